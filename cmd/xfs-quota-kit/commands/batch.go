@@ -0,0 +1,481 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/xfs-quota-kit/pkg/xfs"
+)
+
+// defaultBatchWorkers quota batch 在每个文件系统上默认的并发数
+const defaultBatchWorkers = 4
+
+// batchRequest 一组批量设置，格式与 POST /api/v1/quotas/batch 的请求体相同
+type batchRequest struct {
+	Type   string                     `json:"type"`
+	Path   string                     `json:"path,omitempty"`
+	Name   string                     `json:"name,omitempty"` // xfs.filesystems 中的名称
+	Mode   *xfs.BatchMode             `json:"mode,omitempty"`
+	Quotas map[uint32]xfs.QuotaLimits `json:"quotas"`
+}
+
+// UnmarshalJSON 没有 fields 的限制只设置其中给出的键，缺少的限制保持不变，与 quota set 和 quota import 一致
+func (r *batchRequest) UnmarshalJSON(data []byte) error {
+	type plain batchRequest
+	var raw struct {
+		plain
+		Quotas map[uint32]json.RawMessage `json:"quotas"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*r = batchRequest(raw.plain)
+	r.Quotas = make(map[uint32]xfs.QuotaLimits, len(raw.Quotas))
+	for id, value := range raw.Quotas {
+		var limits xfs.QuotaLimits
+		if err := json.Unmarshal(value, &limits); err != nil {
+			return fmt.Errorf("quota %d: %w", id, err)
+		}
+		if limits.Fields == 0 {
+			var keys map[string]json.RawMessage
+			if err := json.Unmarshal(value, &keys); err != nil {
+				return fmt.Errorf("quota %d: %w", id, err)
+			}
+			for key := range keys {
+				var field xfs.LimitFields
+				if field.UnmarshalText([]byte(key)) == nil {
+					limits.Fields |= field
+				}
+			}
+			if limits.Fields == 0 {
+				return fmt.Errorf("quota %d: no limits given; use 0 to remove a limit", id)
+			}
+		}
+		r.Quotas[id] = limits
+	}
+	return nil
+}
+
+// batchRun 一组批量设置的执行结果
+type batchRun struct {
+	Type       string           `json:"type"`
+	Name       string           `json:"name,omitempty"`
+	Filesystem string           `json:"filesystem"`
+	Error      string           `json:"error,omitempty"`
+	Result     *xfs.BatchResult `json:"result,omitempty"` // 无法开始时（如不是XFS）为nil
+
+	request batchRequest
+	err     error
+}
+
+// batchReport quota batch 的结果文件，Retry 为未设置成功的ID，可通过 --retry 重新执行
+type batchReport struct {
+	GeneratedAt time.Time      `json:"generated_at"`
+	Total       int            `json:"total"`
+	Applied     int            `json:"applied"`
+	Failed      int            `json:"failed"`
+	RolledBack  int            `json:"rolled_back"`
+	Pending     int            `json:"pending"`
+	Batches     []batchRun     `json:"batches"`
+	Retry       []batchRequest `json:"retry"`
+}
+
+func newQuotaBatchCommand() *cobra.Command {
+	var filesystems filesystemSelector
+	var batch batchFlags
+	var file, retry string
+
+	cmd := &cobra.Command{
+		Use:   "batch [path]",
+		Short: "Set quota limits for many IDs from a file",
+		Long: `Set quota limits for many IDs at once. The file (-f) has the same format as
+the body of POST /api/v1/quotas/batch:
+
+  {"type": "user", "path": "/mnt/xfs", "mode": "all-or-nothing",
+   "quotas": {"1001": {"block_hard": "2GiB", "inode_hard": 200000}}}
+
+Only the limits listed for an ID are changed; limits that are left out keep
+their current value and 0 removes a limit. An optional "fields" entry such as
+"block_soft,block_hard" names the limits to change explicitly.
+
+The path in the file is used unless a path, --fs or --all-filesystems is given.
+Filesystems are processed in parallel, each with --workers concurrent updates
+and at most --rate updates per second.
+
+In continue-on-error mode every ID is attempted. In all-or-nothing mode the
+first failure stops the batch on that filesystem and the IDs already changed
+are restored to their previous limits.
+
+--result writes a JSON file with the outcome of every ID; its "retry" list
+holds the IDs that were not applied and can be run again with --retry.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var requests []batchRequest
+			var err error
+			switch {
+			case file != "" && retry != "":
+				return fmt.Errorf("specify either --file or --retry, not both")
+			case retry != "":
+				if len(args) > 0 || filesystems.selected() {
+					return fmt.Errorf("--retry uses the filesystems from the result file")
+				}
+				requests, err = loadBatchRetry(retry)
+			case file != "":
+				requests, err = loadBatchFile(cmd, file, &filesystems, args)
+			default:
+				return fmt.Errorf("--file or --retry is required")
+			}
+			if err != nil {
+				return err
+			}
+
+			return batch.run(cmd, requests)
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "", "JSON file with the limits to set")
+	cmd.Flags().StringVar(&retry, "retry", "", "retry the IDs that were not applied in a previous --result file")
+	batch.addFlags(cmd)
+	filesystems.addFlags(cmd)
+
+	return cmd
+}
+
+// batchFlags quota batch 和 quota import 共用的执行参数
+type batchFlags struct {
+	opts       xfs.BatchOptions
+	mode       string
+	resultFile string
+	format     string
+	quiet      bool
+}
+
+// addFlags 注册执行参数
+func (b *batchFlags) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&b.resultFile, "result", "o", "", "write the outcome of every ID as JSON to this file")
+	cmd.Flags().StringVarP(&b.mode, "mode", "m", "continue-on-error", "failure handling (continue-on-error, all-or-nothing)")
+	cmd.Flags().IntVarP(&b.opts.Workers, "workers", "w", defaultBatchWorkers, "concurrent updates per filesystem")
+	cmd.Flags().Float64Var(&b.opts.Rate, "rate", 0, "maximum updates per second per filesystem (0 for no limit)")
+	cmd.Flags().StringVar(&b.format, "format", "table", "output format (table, json)")
+	cmd.Flags().BoolVarP(&b.quiet, "quiet", "q", false, "do not show progress")
+}
+
+// run 执行批量设置，显示进度并输出结果，指定 --mode 时覆盖请求中的模式
+func (b *batchFlags) run(cmd *cobra.Command, requests []batchRequest) error {
+	if cmd.Flags().Changed("mode") {
+		var mode xfs.BatchMode
+		if err := mode.UnmarshalText([]byte(b.mode)); err != nil {
+			return err
+		}
+		for i := range requests {
+			requests[i].Mode = &mode
+		}
+	}
+
+	showProgress := !b.quiet && b.format != "json" && isTerminal(os.Stderr)
+	report, err := runBatches(cmd, newQuotaManager(cmd), requests, b.opts, showProgress)
+
+	if b.resultFile != "" {
+		if werr := writeBatchReport(b.resultFile, report); werr != nil {
+			return werr
+		}
+	}
+
+	switch b.format {
+	case "json":
+		data, jerr := json.MarshalIndent(report, "", "  ")
+		if jerr != nil {
+			return jerr
+		}
+		fmt.Println(string(data))
+	default:
+		printBatchReport(report)
+		if b.resultFile != "" {
+			fmt.Printf("Results written to %s\n", b.resultFile)
+		}
+	}
+	return err
+}
+
+// loadBatchFile 读取批量设置文件，有路径参数或 --fs/--all-filesystems 时对每个文件系统执行一次
+func loadBatchFile(cmd *cobra.Command, file string, filesystems *filesystemSelector, args []string) ([]batchRequest, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var request batchRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	if _, err := parseQuotaType(request.Type); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	if len(args) == 0 && !filesystems.selected() {
+		if request.Path == "" {
+			return nil, fmt.Errorf("%s has no path; specify a path, --fs or --all-filesystems", file)
+		}
+		return []batchRequest{request}, nil
+	}
+
+	targets, err := filesystems.resolve(cmd, args)
+	if err != nil {
+		return nil, err
+	}
+	requests := make([]batchRequest, len(targets))
+	for i, target := range targets {
+		requests[i] = request
+		requests[i].Path = target.Path
+		requests[i].Name = target.Name
+	}
+	return requests, nil
+}
+
+// loadBatchRetry 读取之前的结果文件中需要重试的ID
+func loadBatchRetry(file string) ([]batchRequest, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var report batchReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	if len(report.Retry) == 0 {
+		return nil, fmt.Errorf("%s has nothing to retry", file)
+	}
+	return report.Retry, nil
+}
+
+// runBatches 执行批量设置，不同设备并行执行，同一设备上的多组（包括子目录和绑定挂载）依次执行，
+// 共用 --workers 和 --rate 的限制
+func runBatches(cmd *cobra.Command, manager xfs.ContextQuotaManager, requests []batchRequest, opts xfs.BatchOptions, showProgress bool) (*batchReport, error) {
+	progress := &batchProgress{enabled: showProgress}
+	for _, request := range requests {
+		progress.total += len(request.Quotas)
+	}
+
+	runs := make([]batchRun, len(requests))
+	byDevice := make(map[string][]int)
+	var devices []string
+	for i, request := range requests {
+		runs[i] = batchRun{Type: request.Type, Name: request.Name, Filesystem: request.Path, request: request}
+		device := batchDevice(request.Path)
+		if _, ok := byDevice[device]; !ok {
+			devices = append(devices, device)
+		}
+		byDevice[device] = append(byDevice[device], i)
+	}
+
+	var wg sync.WaitGroup
+	for _, device := range devices {
+		wg.Add(1)
+		go func(indexes []int) {
+			defer wg.Done()
+			for _, i := range indexes {
+				runBatch(cmd, manager, &runs[i], opts, progress)
+			}
+		}(byDevice[device])
+	}
+	wg.Wait()
+	progress.finish()
+
+	report := newBatchReport(runs)
+
+	failures := &filesystemsError{total: len(runs)}
+	for _, run := range runs {
+		if run.err != nil {
+			failures.add(filesystemTarget{Name: run.Name, Path: run.Filesystem}, run.err)
+		}
+	}
+	if len(runs) == 1 && runs[0].err != nil {
+		return report, fmt.Errorf("failed to set batch quotas: %w", runs[0].err)
+	}
+	return report, failures.err()
+}
+
+// batchDevice 获取路径所在设备的标识，路径无法访问时使用路径本身，错误由 SetBatchQuotas 报告
+func batchDevice(path string) string {
+	var stat syscall.Stat_t
+	if err := syscall.Stat(path, &stat); err != nil {
+		return filepath.Clean(path)
+	}
+	return fmt.Sprintf("dev:%d", stat.Dev)
+}
+
+// runBatch 执行一组批量设置
+func runBatch(cmd *cobra.Command, manager xfs.ContextQuotaManager, run *batchRun, opts xfs.BatchOptions, progress *batchProgress) {
+	qType, err := parseQuotaType(run.request.Type)
+	if err != nil {
+		run.err = err
+		run.Error = err.Error()
+		progress.skip(len(run.request.Quotas))
+		return
+	}
+	if run.request.Mode != nil {
+		opts.Mode = *run.request.Mode
+	}
+	// Progress 的调用不会同时发生
+	reported := 0
+	opts.Progress = func(item *xfs.BatchItem, done, total int) {
+		reported++
+		progress.add(item.Status == xfs.BatchFailed)
+	}
+
+	result, err := manager.SetBatchQuotas(cmd.Context(), qType, run.Filesystem, run.request.Quotas, opts)
+	run.Result = result
+	if err != nil {
+		run.err = err
+		run.Error = err.Error()
+	}
+	// 读取原有限制失败、失败后停止或被取消而未处理的ID
+	progress.skip(len(run.request.Quotas) - reported)
+}
+
+// newBatchReport 汇总各组的结果，并将未设置成功的ID按文件系统和类型整理为重试列表
+func newBatchReport(runs []batchRun) *batchReport {
+	report := &batchReport{GeneratedAt: time.Now(), Batches: runs, Retry: []batchRequest{}}
+	for _, run := range runs {
+		report.Total += len(run.request.Quotas)
+
+		retry := run.request
+		retry.Quotas = make(map[uint32]xfs.QuotaLimits)
+		if run.Result == nil {
+			report.Failed += len(run.request.Quotas)
+			retry.Quotas = run.request.Quotas
+		} else {
+			for _, item := range run.Result.Items {
+				switch item.Status {
+				case xfs.BatchApplied:
+					report.Applied++
+					continue
+				case xfs.BatchFailed, xfs.BatchRollbackFailed:
+					report.Failed++
+				case xfs.BatchRolledBack:
+					report.RolledBack++
+				case xfs.BatchPending:
+					report.Pending++
+				}
+				retry.Quotas[item.ID] = item.Limits
+			}
+		}
+		if len(retry.Quotas) > 0 {
+			report.Retry = append(report.Retry, retry)
+		}
+	}
+	return report
+}
+
+// writeBatchReport 写入结果文件
+func writeBatchReport(path string, report *batchReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write result file: %w", err)
+	}
+	return nil
+}
+
+// maxBatchFailuresShown 摘要中最多列出的失败ID数，完整列表见结果文件
+const maxBatchFailuresShown = 20
+
+func printBatchReport(report *batchReport) {
+	fmt.Printf("Batch: %d IDs, %d applied, %d failed, %d rolled back, %d not attempted\n",
+		report.Total, report.Applied, report.Failed, report.RolledBack, report.Pending)
+
+	shown := 0
+	for _, run := range report.Batches {
+		target := filesystemTarget{Name: run.Name, Path: run.Filesystem}
+		if run.Result == nil {
+			if run.Error != "" {
+				fmt.Printf("  %s: %s\n", target, run.Error)
+			}
+			continue
+		}
+		for _, item := range run.Result.Items {
+			if item.Error == "" {
+				continue
+			}
+			if shown < maxBatchFailuresShown {
+				fmt.Printf("  %s on %s: %s: %s\n", formatTarget(item.Type, item.ID, item.Name), target, item.Status, item.Error)
+			}
+			shown++
+		}
+	}
+	if shown > maxBatchFailuresShown {
+		fmt.Printf("  ... and %d more (use --result for the full list)\n", shown-maxBatchFailuresShown)
+	}
+}
+
+// batchProgress 在终端上显示所有文件系统合计的进度条
+type batchProgress struct {
+	enabled bool
+	total   int
+
+	mu       sync.Mutex
+	done     int
+	failed   int
+	lastDraw time.Time
+}
+
+// add 记录处理完一个ID
+func (p *batchProgress) add(failed bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	if failed {
+		p.failed++
+	}
+	p.draw(false)
+}
+
+// skip 记录未设置的ID
+func (p *batchProgress) skip(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done += n
+	p.failed += n
+	p.draw(false)
+}
+
+func (p *batchProgress) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.enabled && !p.lastDraw.IsZero() {
+		p.draw(true)
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+// draw 刷新进度条，最多每100ms一次，调用方需持有锁
+func (p *batchProgress) draw(force bool) {
+	if !p.enabled || p.total == 0 {
+		return
+	}
+	now := time.Now()
+	if !force && now.Sub(p.lastDraw) < 100*time.Millisecond {
+		return
+	}
+	p.lastDraw = now
+
+	const width = 30
+	filled := p.done * width / p.total
+	fmt.Fprintf(os.Stderr, "\r[%s%s] %d/%d (%d failed)",
+		strings.Repeat("=", filled), strings.Repeat(" ", width-filled), p.done, p.total, p.failed)
+}
+
+// isTerminal 检查文件是否为终端
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
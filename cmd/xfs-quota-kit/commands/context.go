@@ -4,12 +4,14 @@ import (
 	"context"
 
 	"github.com/xfs-quota-kit/pkg/config"
+	"github.com/xfs-quota-kit/pkg/xfs"
 )
 
 type contextKey string
 
 const (
-	configKey contextKey = "config"
+	configKey  contextKey = "config"
+	managerKey contextKey = "manager"
 )
 
 // WithConfig 将配置添加到context
@@ -24,3 +26,9 @@ func GetConfig(ctx context.Context) *config.Config {
 	}
 	return nil
 }
+
+// WithQuotaManager 将配额管理器添加到context，命令将使用它而不是根据配置创建
+// 主要用于测试，例如传入 xfstest.FakeQuotaManager
+func WithQuotaManager(ctx context.Context, manager xfs.ContextQuotaManager) context.Context {
+	return context.WithValue(ctx, managerKey, manager)
+}
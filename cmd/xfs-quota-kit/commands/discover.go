@@ -0,0 +1,170 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/xfs-quota-kit/pkg/config"
+	"github.com/xfs-quota-kit/pkg/xfs"
+	"gopkg.in/yaml.v3"
+)
+
+// NewFilesystemsCommand 创建文件系统命令
+func NewFilesystemsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "filesystems",
+		Short: "Discover XFS filesystems",
+		Long:  `Discover mounted XFS filesystems and maintain xfs.filesystems in the configuration.`,
+	}
+
+	cmd.AddCommand(
+		newFilesystemsDiscoverCommand(),
+	)
+
+	return cmd
+}
+
+func newFilesystemsDiscoverCommand() *cobra.Command {
+	var format string
+	var writeConfig string
+
+	cmd := &cobra.Command{
+		Use:   "discover",
+		Short: "List mounted XFS filesystems and their quota state",
+		Long: `Scan the mount table for XFS filesystems and show the device, mount options,
+which quota types are accounted and enforced, and whether project quotas can be
+used on this kernel. Bind mounts and further mounts of the same device are
+skipped.
+
+With --write-config the discovered filesystems are merged into xfs.filesystems
+of the given configuration file, which is created if it does not exist.
+Entries that are already configured keep their name, enabled flag and project
+ID ranges; other settings and comments in the file are kept, although the
+file is reformatted. New filesystems are enabled when quota accounting is on.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			manager := newQuotaManager(cmd)
+
+			discovered, err := manager.DiscoverFilesystems(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("failed to discover filesystems: %w", err)
+			}
+
+			filesystems := make([]config.FilesystemInfo, len(discovered))
+			for i, fs := range discovered {
+				filesystems[i] = discoveredFilesystemInfo(fs)
+			}
+
+			switch format {
+			case "json":
+				data, err := json.MarshalIndent(discovered, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+			case "yaml":
+				// 可直接粘贴到配置文件中的 xfs.filesystems
+				enc := yaml.NewEncoder(os.Stdout)
+				enc.SetIndent(2)
+				if err := enc.Encode(map[string]interface{}{
+					"xfs": map[string]interface{}{"filesystems": filesystems},
+				}); err != nil {
+					return err
+				}
+				enc.Close()
+			default:
+				printDiscoveredFilesystems(discovered)
+			}
+
+			if writeConfig != "" {
+				merged, err := config.UpdateFilesystems(writeConfig, filesystems)
+				if err != nil {
+					return fmt.Errorf("failed to write config: %w", err)
+				}
+				if format != "json" && format != "yaml" {
+					fmt.Printf("\nWrote %d filesystems to %s\n", len(merged), writeConfig)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table, json, yaml)")
+	cmd.Flags().StringVarP(&writeConfig, "write-config", "w", "", "merge the discovered filesystems into xfs.filesystems of this config file")
+
+	return cmd
+}
+
+// discoveredFilesystemInfo 将发现的文件系统转换为配置中的条目
+func discoveredFilesystemInfo(fs xfs.DiscoveredFilesystem) config.FilesystemInfo {
+	info := config.FilesystemInfo{
+		Name:       filesystemName(fs.MountPoint),
+		MountPoint: fs.MountPoint,
+		Device:     fs.Device,
+		Enabled:    fs.Quota.Any(),
+	}
+	if fs.Quota.Any() {
+		info.Options = fs.Quota.String()
+	}
+	return info
+}
+
+// filesystemName 根据挂载点生成文件系统名称，如 /data/my volume 为 data-my-volume
+func filesystemName(mountPoint string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.':
+			return r
+		default:
+			return '-'
+		}
+	}, strings.Trim(mountPoint, "/"))
+	if name == "" {
+		return "root"
+	}
+	return name
+}
+
+func printDiscoveredFilesystems(filesystems []xfs.DiscoveredFilesystem) {
+	if len(filesystems) == 0 {
+		fmt.Println("No XFS filesystems mounted")
+		return
+	}
+
+	fmt.Printf("%-24s %-28s %-10s %-10s %-10s %-8s\n", "Mount Point", "Device", "User", "Group", "Project", "Prjquota")
+	fmt.Println(strings.Repeat("-", 95))
+	for _, fs := range filesystems {
+		supported := "no"
+		if fs.ProjectQuota {
+			supported = "yes"
+		}
+		fmt.Printf("%-24s %-28s %-10s %-10s %-10s %-8s\n",
+			fs.MountPoint, fs.Device,
+			quotaMode(fs.Quota, xfs.UserQuota),
+			quotaMode(fs.Quota, xfs.GroupQuota),
+			quotaMode(fs.Quota, xfs.ProjectQuota),
+			supported)
+	}
+
+	for _, fs := range filesystems {
+		if fs.StateError != "" {
+			fmt.Printf("\n! %s: quota state taken from mount options: %s\n", fs.MountPoint, fs.StateError)
+		}
+	}
+}
+
+// quotaMode 配额类型的状态：enforced、accounting 或 off
+func quotaMode(opts xfs.QuotaMountOptions, quotaType xfs.QuotaType) string {
+	switch {
+	case opts.Enforced(quotaType):
+		return "enforced"
+	case opts.Accounting(quotaType):
+		return "accounting"
+	default:
+		return "off"
+	}
+}
@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func newQuotaEnforceCommand() *cobra.Command {
+	var quotaType string
+	var filesystems filesystemSelector
+
+	cmd := &cobra.Command{
+		Use:   "enforce [on|off] [path]",
+		Short: "Turn quota enforcement on or off",
+		Long: `Turn quota limit enforcement on or off at runtime.
+
+Quota accounting itself can only be enabled at mount time, so the filesystem
+must already be mounted with the matching accounting option (for example
+pquota or pqnoenforce for project quotas).`,
+		Args:      cobra.RangeArgs(1, 2),
+		ValidArgs: []string{"on", "off"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var enforce bool
+			switch args[0] {
+			case "on":
+				enforce = true
+			case "off":
+				enforce = false
+			default:
+				return fmt.Errorf("invalid argument %q (expected on or off)", args[0])
+			}
+			manager := newQuotaManager(cmd)
+
+			targets, err := filesystems.resolve(cmd, args[1:])
+			if err != nil {
+				return err
+			}
+			qType, err := parseQuotaType(quotaType)
+			if err != nil {
+				return err
+			}
+
+			return forEachFilesystem(targets, func(fs filesystemTarget) error {
+				if err := manager.SetEnforcement(cmd.Context(), qType, fs.Path, enforce); err != nil {
+					return fmt.Errorf("failed to turn %s quota enforcement %s: %w", qType, args[0], err)
+				}
+
+				fmt.Printf("%s quota enforcement turned %s\n", qType, args[0])
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&quotaType, "type", "t", "user", "quota type (user, group, project)")
+	filesystems.addFlags(cmd)
+
+	return cmd
+}
+
+func newQuotaPurgeCommand() *cobra.Command {
+	var quotaType string
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "purge [path]",
+		Short: "Free the disk space used by quota records",
+		Long: `Remove the quota inode of the given type and free the space it uses.
+All limits of that type are lost.
+
+Quota accounting for the type must be off, i.e. the filesystem must be mounted
+without the corresponding quota option.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := args[0]
+			manager := newQuotaManager(cmd)
+
+			qType, err := parseQuotaType(quotaType)
+			if err != nil {
+				return err
+			}
+
+			if !yes {
+				return fmt.Errorf("purging removes all %s quota limits; rerun with --yes to confirm", qType)
+			}
+
+			if err := manager.PurgeQuota(cmd.Context(), qType, path); err != nil {
+				return fmt.Errorf("failed to purge %s quotas: %w", qType, err)
+			}
+
+			fmt.Printf("%s quota records purged\n", qType)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&quotaType, "type", "t", "user", "quota type (user, group, project)")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "confirm removal of all limits")
+
+	return cmd
+}
@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/xfs-quota-kit/pkg/config"
+	"github.com/xfs-quota-kit/pkg/xfs"
+)
+
+func newQuotaEnsureCommand() *cobra.Command {
+	var quotaType string
+	var opts xfs.EnsureOptions
+	var format string
+	var filesystems filesystemSelector
+
+	cmd := &cobra.Command{
+		Use:   "ensure [path...]",
+		Short: "Apply the configured default limits to users and groups without a quota",
+		Long: `Apply xfs.default_limits from the configuration to every user and group that
+has no explicit quota yet. IDs that already have any limit set are left alone,
+so the command can run repeatedly from cron or a login hook.
+
+Users and groups are taken from /etc/passwd and /etc/group and from the quota
+records on the filesystem, which also covers directory-service accounts that
+have already written files. Without a path, all enabled filesystems from the
+configuration are processed. When xfs.auto_create is false only --dry-run
+is carried out.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := GetConfig(cmd.Context())
+			if cfg == nil {
+				return fmt.Errorf("no configuration loaded")
+			}
+			if !cfg.XFS.AutoCreate && !opts.DryRun {
+				fmt.Println("Default limits are disabled (xfs.auto_create is false), nothing to do.")
+				return nil
+			}
+
+			// 没有指定路径时与 --all-filesystems 相同
+			targets := enabledFilesystems(cfg.XFS.Filesystems)
+			if len(args) > 0 || filesystems.selected() {
+				var err error
+				if targets, err = filesystems.resolve(cmd, args); err != nil {
+					return err
+				}
+			}
+			if len(targets) == 0 {
+				return fmt.Errorf("no filesystem given and none enabled in xfs.filesystems")
+			}
+
+			opts.Limits = defaultLimits(cfg.XFS.DefaultLimits)
+			switch quotaType {
+			case "all":
+			case "user", "u":
+				delete(opts.Limits, xfs.GroupQuota)
+			case "group", "g":
+				delete(opts.Limits, xfs.UserQuota)
+			default:
+				return fmt.Errorf("invalid quota type: %s (expected user, group or all)", quotaType)
+			}
+
+			manager := newQuotaManager(cmd)
+			var results []*xfs.EnsureResult
+			var errs []error
+			for _, fs := range targets {
+				result, err := manager.EnsureDefaultLimits(cmd.Context(), fs.Path, opts)
+				if result != nil {
+					results = append(results, result)
+				}
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", fs, err))
+				}
+			}
+
+			switch format {
+			case "json":
+				data, err := json.MarshalIndent(results, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+			default:
+				for _, result := range results {
+					printEnsureResult(result)
+				}
+			}
+
+			if len(errs) > 0 {
+				return fmt.Errorf("failed to apply default limits: %w", errors.Join(errs...))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&quotaType, "type", "t", "all", "quota type (user, group, all)")
+	cmd.Flags().BoolVarP(&opts.DryRun, "dry-run", "n", false, "only list the users and groups that would get the default limits")
+	cmd.Flags().Uint32Var(&opts.MinID, "min-id", xfs.DefaultEnsureMinID, "lowest user/group ID to process")
+	cmd.Flags().Uint32Var(&opts.MaxID, "max-id", xfs.DefaultEnsureMaxID, "highest user/group ID to process")
+	cmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table, json)")
+	filesystems.addFlags(cmd)
+
+	return cmd
+}
+
+// defaultLimits 将配置中的默认限制转换为各配额类型的限制
+func defaultLimits(limits config.DefaultLimits) map[xfs.QuotaType]xfs.QuotaLimits {
+	return map[xfs.QuotaType]xfs.QuotaLimits{
+		xfs.UserQuota: {
+			BlockSoft: limits.UserBlockSoft,
+			BlockHard: limits.UserBlockHard,
+			InodeSoft: limits.UserInodeSoft,
+			InodeHard: limits.UserInodeHard,
+		},
+		xfs.GroupQuota: {
+			BlockSoft: limits.GroupBlockSoft,
+			BlockHard: limits.GroupBlockHard,
+			InodeSoft: limits.GroupInodeSoft,
+			InodeHard: limits.GroupInodeHard,
+		},
+	}
+}
+
+func printEnsureResult(result *xfs.EnsureResult) {
+	verb := "Set"
+	if result.DryRun {
+		verb = "Would set"
+	}
+
+	fmt.Printf("%s:\n", result.Path)
+	for _, action := range result.Applied {
+		fmt.Printf("  %s default limits for %s: block %s/%s, inode %d/%d\n",
+			verb, formatTarget(action.Type, action.ID, action.Name),
+			action.Limits.BlockSoft, action.Limits.BlockHard,
+			action.Limits.InodeSoft, action.Limits.InodeHard)
+	}
+	fmt.Printf("  %d without a quota, %d already have limits\n", len(result.Applied), result.Existing)
+}
@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"context"
+	"errors"
+
+	"github.com/xfs-quota-kit/pkg/xfs"
+)
+
+// 退出码，不同类别的错误使用不同的退出码便于脚本判断
+const (
+	ExitOK              = 0
+	ExitError           = 1   // 其他错误
+	ExitNotXFS          = 3   // 路径不在XFS文件系统上
+	ExitQuotaNotEnabled = 4   // 配额统计未开启
+	ExitNoDquot         = 5   // ID没有配额记录
+	ExitPermission      = 6   // 权限不足
+	ExitNotSupported    = 7   // 内核不支持
+	ExitInvalid         = 8   // 参数无效
+	ExitInterrupted     = 130 // 被信号中断
+)
+
+// errorClass 错误类别及对应的退出码和提示
+type errorClass struct {
+	err  error
+	code int
+	hint string
+}
+
+var errorClasses = []errorClass{
+	{xfs.ErrNotXFS, ExitNotXFS,
+		"the path must be on an XFS filesystem; check it with 'xfs-quota-kit report filesystem <path>'"},
+	{xfs.ErrQuotaNotEnabled, ExitQuotaNotEnabled,
+		"quota accounting can only be enabled at mount time; unmount and mount again with uquota, gquota or pquota (XFS ignores quota options on remount)"},
+	{xfs.ErrNoDquot, ExitNoDquot,
+		"the ID has no quota record yet; set a limit first with 'xfs-quota-kit quota set'"},
+	{xfs.ErrPermission, ExitPermission,
+		"quota changes require root or CAP_SYS_ADMIN; rerun with sudo"},
+	{xfs.ErrNotSupported, ExitNotSupported,
+		"the kernel does not support this quota command; try --backend xfs_quota"},
+	{xfs.ErrInvalid, ExitInvalid,
+		"check the quota type, ID and limits; the filesystem may not be mounted with this quota type"},
+	{context.Canceled, ExitInterrupted, ""},
+}
+
+// ExitCode 获取错误对应的退出码
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	for _, class := range errorClasses {
+		if errors.Is(err, class.err) {
+			return class.code
+		}
+	}
+	return ExitError
+}
+
+// ErrorHint 获取错误对应的处理建议，没有建议时返回空字符串
+func ErrorHint(err error) string {
+	for _, class := range errorClasses {
+		if errors.Is(err, class.err) {
+			return class.hint
+		}
+	}
+	return ""
+}
@@ -0,0 +1,170 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/xfs-quota-kit/pkg/config"
+)
+
+// filesystemTarget 命令要操作的文件系统
+type filesystemTarget struct {
+	Name string // xfs.filesystems 中的名称，直接指定路径时为空
+	Path string
+}
+
+func (t filesystemTarget) String() string {
+	if t.Name == "" {
+		return t.Path
+	}
+	return fmt.Sprintf("%s (%s)", t.Name, t.Path)
+}
+
+// filesystemSelector 通过路径参数、--fs 或 --all-filesystems 选择文件系统
+type filesystemSelector struct {
+	names []string
+	all   bool
+}
+
+// addFlags 注册选择文件系统的参数
+func (s *filesystemSelector) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&s.names, "fs", nil, "name or mount point of a filesystem from xfs.filesystems (repeatable)")
+	cmd.Flags().BoolVar(&s.all, "all-filesystems", false, "operate on all enabled filesystems from xfs.filesystems")
+	cmd.MarkFlagsMutuallyExclusive("fs", "all-filesystems")
+}
+
+// selected 是否通过参数选择了配置中的文件系统
+func (s *filesystemSelector) selected() bool {
+	return len(s.names) > 0 || s.all
+}
+
+// resolve 获取要操作的文件系统，paths 为命令行中的路径参数
+func (s *filesystemSelector) resolve(cmd *cobra.Command, paths []string) ([]filesystemTarget, error) {
+	switch {
+	case len(paths) > 0 && s.selected():
+		return nil, fmt.Errorf("specify either a path or --fs/--all-filesystems, not both")
+	case len(paths) > 0:
+		targets := make([]filesystemTarget, len(paths))
+		for i, path := range paths {
+			targets[i] = filesystemTarget{Path: path}
+		}
+		return targets, nil
+	case !s.selected():
+		return nil, fmt.Errorf("a path, --fs or --all-filesystems is required")
+	}
+
+	var filesystems []config.FilesystemInfo
+	if cfg := GetConfig(cmd.Context()); cfg != nil {
+		filesystems = cfg.XFS.Filesystems
+	}
+
+	if s.all {
+		targets := enabledFilesystems(filesystems)
+		if len(targets) == 0 {
+			return nil, fmt.Errorf("no enabled filesystems in xfs.filesystems")
+		}
+		return targets, nil
+	}
+
+	var targets []filesystemTarget
+	for _, name := range s.names {
+		fs, ok := findFilesystem(filesystems, name)
+		if !ok {
+			return nil, fmt.Errorf("unknown filesystem %q (configured: %s)", name, filesystemNames(filesystems))
+		}
+		targets = append(targets, filesystemTarget{Name: fs.Name, Path: fs.MountPoint})
+	}
+	return targets, nil
+}
+
+// enabledFilesystems 获取配置中启用的文件系统
+func enabledFilesystems(filesystems []config.FilesystemInfo) []filesystemTarget {
+	var targets []filesystemTarget
+	for _, fs := range filesystems {
+		if fs.Enabled {
+			targets = append(targets, filesystemTarget{Name: fs.Name, Path: fs.MountPoint})
+		}
+	}
+	return targets
+}
+
+// findFilesystem 按名称或挂载点查找配置中的文件系统
+func findFilesystem(filesystems []config.FilesystemInfo, nameOrPath string) (config.FilesystemInfo, bool) {
+	for _, fs := range filesystems {
+		if fs.Name == nameOrPath {
+			return fs, true
+		}
+	}
+	for _, fs := range filesystems {
+		if filepath.Clean(fs.MountPoint) == filepath.Clean(nameOrPath) {
+			return fs, true
+		}
+	}
+	return config.FilesystemInfo{}, false
+}
+
+func filesystemNames(filesystems []config.FilesystemInfo) string {
+	if len(filesystems) == 0 {
+		return "none"
+	}
+	names := make([]string, len(filesystems))
+	for i, fs := range filesystems {
+		names[i] = fs.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// forEachFilesystem 依次对每个文件系统执行fn，有多个文件系统时按文件系统分组输出，
+// 某个文件系统失败（如未挂载）时记录错误并继续处理其余的文件系统
+func forEachFilesystem(targets []filesystemTarget, fn func(target filesystemTarget) error) error {
+	if len(targets) == 1 {
+		return fn(targets[0])
+	}
+
+	failures := &filesystemsError{total: len(targets)}
+	for i, target := range targets {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("==> %s <==\n", target)
+		if err := fn(target); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			failures.add(target, err)
+		}
+	}
+	return failures.err()
+}
+
+// filesystemsError 部分文件系统失败时返回的错误，保留各文件系统的错误用于判断退出码
+type filesystemsError struct {
+	total   int
+	targets []filesystemTarget
+	errs    []error
+}
+
+func (e *filesystemsError) add(target filesystemTarget, err error) {
+	e.targets = append(e.targets, target)
+	e.errs = append(e.errs, err)
+}
+
+// err 没有失败时返回nil
+func (e *filesystemsError) err() error {
+	if len(e.errs) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *filesystemsError) Error() string {
+	names := make([]string, len(e.targets))
+	for i, target := range e.targets {
+		names[i] = target.String()
+	}
+	return fmt.Sprintf("failed on %d of %d filesystems: %s", len(e.errs), e.total, strings.Join(names, ", "))
+}
+
+func (e *filesystemsError) Unwrap() []error {
+	return e.errs
+}
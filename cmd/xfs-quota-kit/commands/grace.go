@@ -0,0 +1,273 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/xfs-quota-kit/pkg/xfs"
+)
+
+func newQuotaGraceCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "grace",
+		Short: "Manage quota grace periods",
+		Long: `Show and change the filesystem-wide default grace periods, and extend or
+reset the grace timer of an individual user, group, or project.`,
+	}
+
+	cmd.AddCommand(
+		newQuotaGraceGetCommand(),
+		newQuotaGraceSetCommand(),
+		newQuotaGraceExtendCommand(),
+		newQuotaGraceResetCommand(),
+	)
+
+	return cmd
+}
+
+func newQuotaGraceGetCommand() *cobra.Command {
+	var quotaType string
+	var filesystems filesystemSelector
+
+	cmd := &cobra.Command{
+		Use:   "get [path]",
+		Short: "Show default grace periods",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			manager := newQuotaManager(cmd)
+
+			targets, err := filesystems.resolve(cmd, args)
+			if err != nil {
+				return err
+			}
+			qType, err := parseQuotaType(quotaType)
+			if err != nil {
+				return err
+			}
+
+			return forEachFilesystem(targets, func(fs filesystemTarget) error {
+				grace, err := manager.GetGraceTimes(cmd.Context(), qType, fs.Path)
+				if err != nil {
+					return fmt.Errorf("failed to get grace periods: %w", err)
+				}
+
+				fmt.Printf("Default %s grace periods on %s:\n", qType, fs.Path)
+				fmt.Printf("  Block: %s\n", formatGrace(grace.Block))
+				fmt.Printf("  Inode: %s\n", formatGrace(grace.Inode))
+				fmt.Printf("  Realtime Block: %s\n", formatGrace(grace.RTBlock))
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&quotaType, "type", "t", "user", "quota type (user, group, project)")
+	filesystems.addFlags(cmd)
+
+	return cmd
+}
+
+func newQuotaGraceSetCommand() *cobra.Command {
+	var quotaType string
+	var filesystems filesystemSelector
+	var block, inode, rtBlock string
+
+	cmd := &cobra.Command{
+		Use:   "set [path]",
+		Short: "Set default grace periods",
+		Long:  `Set the filesystem-wide default grace periods (e.g. --block 7d --inode 1d12h).`,
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			manager := newQuotaManager(cmd)
+
+			targets, err := filesystems.resolve(cmd, args)
+			if err != nil {
+				return err
+			}
+			qType, err := parseQuotaType(quotaType)
+			if err != nil {
+				return err
+			}
+
+			var grace xfs.GraceTimes
+			if grace.Block, err = parseOptionalGrace(block); err != nil {
+				return fmt.Errorf("invalid block grace period: %w", err)
+			}
+			if grace.Inode, err = parseOptionalGrace(inode); err != nil {
+				return fmt.Errorf("invalid inode grace period: %w", err)
+			}
+			if grace.RTBlock, err = parseOptionalGrace(rtBlock); err != nil {
+				return fmt.Errorf("invalid realtime block grace period: %w", err)
+			}
+
+			return forEachFilesystem(targets, func(fs filesystemTarget) error {
+				if err := manager.SetGraceTimes(cmd.Context(), qType, fs.Path, grace); err != nil {
+					return fmt.Errorf("failed to set grace periods: %w", err)
+				}
+
+				fmt.Printf("Default %s grace periods updated on %s\n", qType, fs.Path)
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&quotaType, "type", "t", "user", "quota type (user, group, project)")
+	cmd.Flags().StringVar(&block, "block", "", "block grace period (e.g., 7d, 12h)")
+	cmd.Flags().StringVar(&inode, "inode", "", "inode grace period (e.g., 7d, 12h)")
+	cmd.Flags().StringVar(&rtBlock, "rt-block", "", "realtime block grace period (e.g., 7d, 12h)")
+	filesystems.addFlags(cmd)
+
+	return cmd
+}
+
+func newQuotaGraceExtendCommand() *cobra.Command {
+	var target quotaTarget
+	var filesystems filesystemSelector
+	var block, inode, rtBlock string
+
+	cmd := &cobra.Command{
+		Use:   "extend [path]",
+		Short: "Extend the grace timer of an ID",
+		Long:  `Set the grace timer of a user, group, or project to expire the given time from now.`,
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			manager := newQuotaManager(cmd)
+
+			targets, err := filesystems.resolve(cmd, args)
+			if err != nil {
+				return err
+			}
+			qType, id, name, err := target.resolve(cmd)
+			if err != nil {
+				return err
+			}
+
+			now := time.Now()
+			var timers xfs.GraceTimers
+			if d, err := parseOptionalGrace(block); err != nil {
+				return fmt.Errorf("invalid block grace period: %w", err)
+			} else if d > 0 {
+				timers.Block = now.Add(d)
+			}
+			if d, err := parseOptionalGrace(inode); err != nil {
+				return fmt.Errorf("invalid inode grace period: %w", err)
+			} else if d > 0 {
+				timers.Inode = now.Add(d)
+			}
+			if d, err := parseOptionalGrace(rtBlock); err != nil {
+				return fmt.Errorf("invalid realtime block grace period: %w", err)
+			} else if d > 0 {
+				timers.RTBlock = now.Add(d)
+			}
+
+			return forEachFilesystem(targets, func(fs filesystemTarget) error {
+				if err := manager.SetGraceTimers(cmd.Context(), qType, id, fs.Path, timers); err != nil {
+					return fmt.Errorf("failed to extend grace period: %w", err)
+				}
+
+				fmt.Printf("Grace period extended for %s\n", formatTarget(qType, id, name))
+				return nil
+			})
+		},
+	}
+
+	target.addFlags(cmd)
+	cmd.Flags().StringVar(&block, "block", "", "block grace period from now (e.g., 3d)")
+	cmd.Flags().StringVar(&inode, "inode", "", "inode grace period from now (e.g., 3d)")
+	cmd.Flags().StringVar(&rtBlock, "rt-block", "", "realtime block grace period from now (e.g., 3d)")
+	filesystems.addFlags(cmd)
+
+	return cmd
+}
+
+func newQuotaGraceResetCommand() *cobra.Command {
+	var target quotaTarget
+	var filesystems filesystemSelector
+
+	cmd := &cobra.Command{
+		Use:   "reset [path]",
+		Short: "Restart the grace timer of an ID",
+		Long:  `Restart the grace timer of a user, group, or project that is over a soft limit using the default grace period.`,
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			manager := newQuotaManager(cmd)
+
+			targets, err := filesystems.resolve(cmd, args)
+			if err != nil {
+				return err
+			}
+			qType, id, name, err := target.resolve(cmd)
+			if err != nil {
+				return err
+			}
+
+			return forEachFilesystem(targets, func(fs filesystemTarget) error {
+				if err := manager.ResetGrace(cmd.Context(), qType, id, fs.Path); err != nil {
+					return fmt.Errorf("failed to reset grace period: %w", err)
+				}
+
+				fmt.Printf("Grace period reset for %s\n", formatTarget(qType, id, name))
+				return nil
+			})
+		},
+	}
+
+	target.addFlags(cmd)
+	filesystems.addFlags(cmd)
+
+	return cmd
+}
+
+// parseOptionalGrace 解析可选的宽限时间，空字符串返回0
+func parseOptionalGrace(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return parseGraceDuration(s)
+}
+
+// parseGraceDuration 解析宽限时间，在 time.ParseDuration 的基础上支持天（d）和纯秒数
+func parseGraceDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if secs, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return time.Duration(secs) * time.Second, nil
+	}
+
+	var days time.Duration
+	if i := strings.Index(s, "d"); i >= 0 {
+		n, err := strconv.ParseUint(s[:i], 10, 16)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration: %s", s)
+		}
+		days = time.Duration(n) * 24 * time.Hour
+		s = s[i+1:]
+	}
+
+	var rest time.Duration
+	if s != "" {
+		var err error
+		if rest, err = time.ParseDuration(s); err != nil {
+			return 0, fmt.Errorf("invalid duration: %s", s)
+		}
+	}
+
+	d := days + rest
+	if d <= 0 {
+		return 0, fmt.Errorf("duration must be positive")
+	}
+	return d, nil
+}
+
+// formatGraceRemaining 格式化宽限剩余时间
+func formatGraceRemaining(d time.Duration) string {
+	switch {
+	case d == 0:
+		return "-"
+	case d < 0:
+		return "expired"
+	default:
+		return formatGrace(d)
+	}
+}
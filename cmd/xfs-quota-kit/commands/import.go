@@ -0,0 +1,273 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/xfs-quota-kit/pkg/config"
+	"github.com/xfs-quota-kit/pkg/xfs"
+)
+
+// importRow 导入文件中的一行在某个文件系统上的限制
+type importRow struct {
+	xfs.LimitRecord
+	target filesystemTarget
+}
+
+// importPreview --dry-run 时一行的预览
+type importPreview struct {
+	Line       int              `json:"line"`
+	Type       string           `json:"type"`
+	ID         uint32           `json:"id"`
+	Name       string           `json:"name,omitempty"`
+	Filesystem string           `json:"filesystem"`
+	Previous   *xfs.QuotaLimits `json:"previous,omitempty"` // 没有dquot或无法读取时为空
+	Limits     xfs.QuotaLimits  `json:"limits"`             // 导入后的全部限制，无法读取时只有文件中给出的限制
+	Change     string           `json:"change"`             // new、update、unchanged 或 error
+	Error      string           `json:"error,omitempty"`
+}
+
+func newQuotaImportCommand() *cobra.Command {
+	var filesystems filesystemSelector
+	var batch batchFlags
+	var file, inputFormat string
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "import [path]",
+		Short: "Import quota limits from a CSV or YAML file",
+		Long: `Set quota limits from a CSV or YAML file, one row per user, group or project.
+
+Columns (CSV header) or keys (YAML):
+  type           user, group or project
+  name           user/group/project name or numeric ID (or use an id column)
+  filesystem     name or mount point from xfs.filesystems, or an absolute path
+  block_soft, block_hard, rt_block_soft, rt_block_hard
+                 sizes with a unit, e.g. 500MiB, 2GiB, 1TB
+  inode_soft, inode_hard
+
+Missing columns and empty cells leave the current limit unchanged; use 0 to
+remove a limit. Rows without a filesystem use the path argument or
+--fs/--all-filesystems. Lines starting with # are comments in CSV files.
+
+  type,name,filesystem,block_soft,block_hard,inode_hard
+  user,alice,home,9GiB,10GiB,100000
+
+A YAML file is a list (or a "quotas" list) of mappings with the same keys.
+
+Every row is checked before anything is changed and all errors are reported
+with their line numbers. --dry-run shows the current and new limits of every
+row without changing them. The rows are applied like 'quota batch', so
+--mode, --workers, --rate and --result work the same way.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if file == "" {
+				return fmt.Errorf("--file is required")
+			}
+			format := inputFormat
+			if format == "" {
+				format = strings.TrimPrefix(filepath.Ext(file), ".")
+			}
+
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			records, err := xfs.ParseLimits(f, format, newNameResolver(cmd))
+			f.Close()
+
+			errs := &xfs.ImportError{}
+			if err != nil && !errors.As(err, &errs) {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+
+			var defaults []filesystemTarget
+			if len(args) > 0 || filesystems.selected() {
+				if defaults, err = filesystems.resolve(cmd, args); err != nil {
+					return err
+				}
+			}
+
+			rows := importRows(cmd, records, defaults, errs)
+			if err := errs.Err(); err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+			if len(rows) == 0 {
+				return fmt.Errorf("%s: no quotas to import", file)
+			}
+
+			if dryRun {
+				return previewImport(cmd, rows, batch.format)
+			}
+			return batch.run(cmd, importRequests(rows))
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "", "CSV or YAML file with the limits")
+	cmd.Flags().StringVar(&inputFormat, "input-format", "", "file format (csv, yaml); detected from the file extension by default")
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "show the changes without applying them")
+	batch.addFlags(cmd)
+	filesystems.addFlags(cmd)
+
+	return cmd
+}
+
+// importRows 确定每行要设置的文件系统，并检查重复的行，错误记录到errs中
+func importRows(cmd *cobra.Command, records []xfs.LimitRecord, defaults []filesystemTarget, errs *xfs.ImportError) []importRow {
+	var configured []config.FilesystemInfo
+	if cfg := GetConfig(cmd.Context()); cfg != nil {
+		configured = cfg.XFS.Filesystems
+	}
+
+	type rowKey struct {
+		quotaType xfs.QuotaType
+		id        uint32
+		path      string
+	}
+	seen := make(map[rowKey]int)
+
+	var rows []importRow
+	for _, record := range records {
+		targets := defaults
+		switch {
+		case record.Filesystem != "":
+			fs, ok := findFilesystem(configured, record.Filesystem)
+			switch {
+			case ok:
+				targets = []filesystemTarget{{Name: fs.Name, Path: fs.MountPoint}}
+			case filepath.IsAbs(record.Filesystem):
+				targets = []filesystemTarget{{Path: record.Filesystem}}
+			default:
+				errs.Add(record.Line, fmt.Errorf("unknown filesystem %q (configured: %s)", record.Filesystem, filesystemNames(configured)))
+				continue
+			}
+		case len(defaults) == 0:
+			errs.Add(record.Line, fmt.Errorf("no filesystem; add a filesystem column or specify a path, --fs or --all-filesystems"))
+			continue
+		}
+
+		for _, target := range targets {
+			key := rowKey{record.Type, record.ID, filepath.Clean(target.Path)}
+			if line, ok := seen[key]; ok {
+				errs.Add(record.Line, fmt.Errorf("%s on %s is already set on line %d",
+					formatTarget(record.Type, record.ID, record.Name), target, line))
+				continue
+			}
+			seen[key] = record.Line
+			rows = append(rows, importRow{LimitRecord: record, target: target})
+		}
+	}
+	return rows
+}
+
+// importRequests 按文件系统和配额类型将行分组为批量设置
+func importRequests(rows []importRow) []batchRequest {
+	var requests []batchRequest
+	index := make(map[string]int)
+	for _, row := range rows {
+		key := row.Type.String() + "\x00" + row.target.Path
+		i, ok := index[key]
+		if !ok {
+			i = len(requests)
+			index[key] = i
+			requests = append(requests, batchRequest{
+				Type:   row.Type.String(),
+				Path:   row.target.Path,
+				Name:   row.target.Name,
+				Quotas: make(map[uint32]xfs.QuotaLimits),
+			})
+		}
+		requests[i].Quotas[row.ID] = row.Limits
+	}
+	return requests
+}
+
+// previewImport 显示每行当前的限制和将要设置的限制，不做修改
+func previewImport(cmd *cobra.Command, rows []importRow, format string) error {
+	manager := newQuotaManager(cmd)
+
+	previews := make([]importPreview, len(rows))
+	var firstErr error
+	failed := 0
+	for i, row := range rows {
+		preview := importPreview{
+			Line:       row.Line,
+			Type:       row.Type.String(),
+			ID:         row.ID,
+			Name:       row.Name,
+			Filesystem: row.target.String(),
+			Limits:     row.Limits,
+		}
+
+		quota, err := manager.GetQuota(cmd.Context(), row.Type, row.ID, row.target.Path)
+		switch {
+		case err == nil:
+			previous := quota.Limits()
+			preview.Previous = &previous
+			preview.Limits = row.Limits.Merge(previous)
+			preview.Change = "update"
+			if preview.Limits == previous {
+				preview.Change = "unchanged"
+			}
+		case errors.Is(err, xfs.ErrNoDquot):
+			preview.Limits = row.Limits.Merge(xfs.QuotaLimits{})
+			preview.Change = "new"
+		default:
+			preview.Change = "error"
+			preview.Error = err.Error()
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed++
+		}
+		previews[i] = preview
+	}
+
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(previews, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	default:
+		printImportPreview(previews)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d rows cannot be checked: %w", failed, len(rows), firstErr)
+	}
+	return nil
+}
+
+func printImportPreview(previews []importPreview) {
+	fmt.Printf("%-6s %-28s %-20s %-12s %-12s %-10s %-10s %-12s %-12s %-10s\n",
+		"Line", "Target", "Filesystem", "Block Soft", "Block Hard", "Inode Soft", "Inode Hard", "RT Soft", "RT Hard", "Change")
+	fmt.Println(strings.Repeat("-", 140))
+
+	counts := make(map[string]int)
+	for _, p := range previews {
+		quotaType, _ := xfs.ParseQuotaType(p.Type)
+		fmt.Printf("%-6d %-28s %-20s %-12s %-12s %-10d %-10d %-12s %-12s %-10s\n",
+			p.Line, formatTarget(quotaType, p.ID, p.Name), p.Filesystem,
+			p.Limits.BlockSoft, p.Limits.BlockHard, p.Limits.InodeSoft, p.Limits.InodeHard,
+			p.Limits.RTBlockSoft, p.Limits.RTBlockHard, p.Change)
+		if p.Previous != nil && p.Change == "update" {
+			fmt.Printf("%-6s %-28s %-20s %-12s %-12s %-10d %-10d %-12s %-12s\n",
+				"", "  (current)", "", p.Previous.BlockSoft, p.Previous.BlockHard, p.Previous.InodeSoft, p.Previous.InodeHard,
+				p.Previous.RTBlockSoft, p.Previous.RTBlockHard)
+		}
+		if p.Error != "" {
+			fmt.Printf("%-6s   %s\n", "", p.Error)
+		}
+		counts[p.Change]++
+	}
+
+	fmt.Printf("\n%d rows: %d new, %d updated, %d unchanged, %d errors (dry run, nothing changed)\n",
+		len(previews), counts["new"], counts["update"], counts["unchanged"], counts["error"])
+}
@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/xfs-quota-kit/pkg/config"
+	"github.com/xfs-quota-kit/pkg/xfs"
+)
+
+// newQuotaManager 根据配置创建配额管理器
+// 命令通过 cmd.Context() 调用，Ctrl-C 可以中断长时间的遍历和批量操作
+func newQuotaManager(cmd *cobra.Command) xfs.ContextQuotaManager {
+	if manager, ok := cmd.Context().Value(managerKey).(xfs.ContextQuotaManager); ok {
+		return manager
+	}
+
+	var opts []xfs.Option
+	if cfg := GetConfig(cmd.Context()); cfg != nil {
+		opts = append(opts, xfs.WithProjectFiles(cfg.XFS.ProjectsFile, cfg.XFS.ProjidFile))
+
+		// 后端名称已在配置校验时检查
+		if backend, err := xfs.NewBackend(cfg.XFS.Backend, cfg.XFS.XFSQuotaPath); err == nil {
+			opts = append(opts, xfs.WithBackend(backend))
+		}
+		opts = append(opts, xfs.WithNameResolver(newNameResolver(cmd)))
+		opts = append(opts, xfs.WithProjectIDAllocator(newProjectIDAllocator(cfg)))
+	}
+	return xfs.NewContextQuotaManager(opts...)
+}
+
+// newProjectIDAllocator 根据配置创建项目ID分配器，范围和隔离期已在配置校验时检查
+func newProjectIDAllocator(cfg *config.Config) *xfs.ProjectIDAllocator {
+	allocator := &xfs.ProjectIDAllocator{
+		Ranges:           parseProjectIDRanges(cfg.XFS.ProjectIDs.Ranges),
+		FilesystemRanges: make(map[string][]xfs.ProjectIDRange),
+		FreedFile:        cfg.XFS.ProjectIDs.FreedFile,
+	}
+	allocator.Quarantine, _ = time.ParseDuration(cfg.XFS.ProjectIDs.Quarantine)
+	for _, fs := range cfg.XFS.Filesystems {
+		if ranges := parseProjectIDRanges(fs.ProjectIDRanges); len(ranges) > 0 {
+			allocator.FilesystemRanges[filepath.Clean(fs.MountPoint)] = ranges
+		}
+	}
+	return allocator
+}
+
+func parseProjectIDRanges(values []string) []xfs.ProjectIDRange {
+	var ranges []xfs.ProjectIDRange
+	for _, value := range values {
+		if r, err := xfs.ParseProjectIDRange(value); err == nil {
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges
+}
+
+// newNameResolver 根据配置创建名称解析器
+func newNameResolver(cmd *cobra.Command) xfs.NameResolver {
+	projects := xfs.NewProjectStore("", "")
+	resolver := xfs.NameResolverFiles
+	if cfg := GetConfig(cmd.Context()); cfg != nil {
+		projects = xfs.NewProjectStore(cfg.XFS.ProjectsFile, cfg.XFS.ProjidFile)
+		if cfg.XFS.NameResolver != "" {
+			resolver = cfg.XFS.NameResolver
+		}
+	}
+
+	if resolver == xfs.NameResolverSystem {
+		return xfs.NewSystemResolver(projects)
+	}
+	return xfs.NewFileResolver(xfs.DefaultPasswdFile, xfs.DefaultGroupFile, projects)
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/xfs-quota-kit/pkg/xfs"
 )
 
 // NewMonitorCommand 创建监控命令
@@ -26,33 +27,60 @@ func NewMonitorCommand() *cobra.Command {
 func newMonitorStartCommand() *cobra.Command {
 	var interval string
 	var threshold int
+	var filesystems filesystemSelector
 
 	cmd := &cobra.Command{
 		Use:   "start [path]",
 		Short: "Start monitoring",
-		Long:  `Start monitoring quota usage for the specified filesystem.`,
-		Args:  cobra.ExactArgs(1),
+		Long: `Start monitoring quota usage for the specified filesystem.
+
+With --fs or --all-filesystems every selected filesystem is checked on each
+interval; a filesystem that is unavailable is reported and checked again on
+the next interval.`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			path := args[0]
+			manager := newQuotaManager(cmd)
+
+			targets, err := filesystems.resolve(cmd, args)
+			if err != nil {
+				return err
+			}
 
 			duration, err := time.ParseDuration(interval)
 			if err != nil {
 				return fmt.Errorf("invalid interval: %w", err)
 			}
 
-			fmt.Printf("Starting quota monitoring for %s\n", path)
+			for _, fs := range targets {
+				fmt.Printf("Starting quota monitoring for %s\n", fs)
+			}
 			fmt.Printf("Interval: %s\n", interval)
 			fmt.Printf("Threshold: %d%%\n", threshold)
 
-			// 这里实现实际的监控逻辑
 			ticker := time.NewTicker(duration)
 			defer ticker.Stop()
 
 			for {
 				select {
+				case <-cmd.Context().Done():
+					return nil
 				case <-ticker.C:
-					fmt.Printf("[%s] Checking quotas...\n", time.Now().Format("15:04:05"))
-					// 实际的检查逻辑
+					now := time.Now().Format("15:04:05")
+					for _, fs := range targets {
+						alerts := 0
+						report, err := manager.StreamReport(cmd.Context(), fs.Path, func(quota *xfs.QuotaInfo) error {
+							if quotaAlert(quota, float64(threshold)) {
+								alerts++
+							}
+							return nil
+						})
+						if err != nil {
+							fmt.Printf("[%s] %s: check failed: %v\n", now, fs, err)
+							continue
+						}
+						fmt.Printf("[%s] %s: %d quotas, %d over quota, %d above %d%%\n",
+							now, fs, report.TotalQuotas, report.OverQuotas, alerts, threshold)
+					}
 				}
 			}
 		},
@@ -60,10 +88,18 @@ func newMonitorStartCommand() *cobra.Command {
 
 	cmd.Flags().StringVarP(&interval, "interval", "i", "5m", "monitoring interval")
 	cmd.Flags().IntVarP(&threshold, "threshold", "t", 80, "alert threshold percentage")
+	filesystems.addFlags(cmd)
 
 	return cmd
 }
 
+// quotaAlert 检查块、inode或实时块的用量是否超限或达到阈值
+func quotaAlert(quota *xfs.QuotaInfo, threshold float64) bool {
+	return quota.IsBlockExceeded() || quota.IsInodeExceeded() || quota.IsRTExceeded() ||
+		quota.BlockUsagePercent() >= threshold || quota.InodeUsagePercent() >= threshold ||
+		quota.RTUsagePercent() >= threshold
+}
+
 func newMonitorStatusCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "status",
@@ -19,23 +19,31 @@ func NewProjectCommand() *cobra.Command {
 		newProjectCreateCommand(),
 		newProjectRemoveCommand(),
 		newProjectListCommand(),
+		newProjectSetupCommand(),
+		newProjectClearCommand(),
 	)
 
 	return cmd
 }
 
 func newProjectCreateCommand() *cobra.Command {
+	var id uint32
+
 	cmd := &cobra.Command{
 		Use:   "create [name] [path]",
 		Short: "Create a new project",
-		Long:  `Create a new XFS project quota for the specified directory.`,
-		Args:  cobra.ExactArgs(2),
+		Long: `Create a new XFS project quota for the specified directory.
+
+Without --id the first free ID in the configured range is allocated, skipping
+IDs that are listed in the project files, still have quota usage or limits on
+the filesystem, or were released within the quarantine period.`,
+		Args: cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			name := args[0]
 			path := args[1]
-			manager := xfs.NewQuotaManager()
+			manager := newQuotaManager(cmd)
 
-			project, err := manager.CreateProject(name, path)
+			project, err := manager.CreateProject(cmd.Context(), name, path, xfs.CreateProjectOptions{ID: id})
 			if err != nil {
 				return fmt.Errorf("failed to create project: %w", err)
 			}
@@ -48,6 +56,8 @@ func newProjectCreateCommand() *cobra.Command {
 		},
 	}
 
+	cmd.Flags().Uint32Var(&id, "id", 0, "project ID (allocated automatically when 0)")
+
 	return cmd
 }
 
@@ -59,9 +69,9 @@ func newProjectRemoveCommand() *cobra.Command {
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			name := args[0]
-			manager := xfs.NewQuotaManager()
+			manager := newQuotaManager(cmd)
 
-			err := manager.RemoveProject(name)
+			err := manager.RemoveProject(cmd.Context(), name)
 			if err != nil {
 				return fmt.Errorf("failed to remove project: %w", err)
 			}
@@ -80,9 +90,9 @@ func newProjectListCommand() *cobra.Command {
 		Short: "List all projects",
 		Long:  `List all XFS project quotas.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			manager := xfs.NewQuotaManager()
+			manager := newQuotaManager(cmd)
 
-			projects, err := manager.GetProjects()
+			projects, err := manager.GetProjects(cmd.Context())
 			if err != nil {
 				return fmt.Errorf("failed to list projects: %w", err)
 			}
@@ -104,3 +114,79 @@ func newProjectListCommand() *cobra.Command {
 
 	return cmd
 }
+
+func newProjectSetupCommand() *cobra.Command {
+	var quiet bool
+
+	cmd := &cobra.Command{
+		Use:   "setup [name]",
+		Short: "Apply the project ID to the project directories",
+		Long: `Set the project ID and the PROJINHERIT flag on every directory and file
+below the project's paths (equivalent to xfs_quota -x -c 'project -s').
+Mount points below the project directory are not crossed.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			manager := newQuotaManager(cmd)
+
+			result, err := manager.SetupProject(cmd.Context(), name, projectTreeOptions(quiet))
+			if err != nil {
+				return fmt.Errorf("failed to set up project: %w", err)
+			}
+
+			printProjectTreeResult("set up", name, result)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "do not show progress")
+
+	return cmd
+}
+
+func newProjectClearCommand() *cobra.Command {
+	var quiet bool
+
+	cmd := &cobra.Command{
+		Use:   "clear [name]",
+		Short: "Clear the project ID from the project directories",
+		Long: `Reset the project ID to 0 and remove the PROJINHERIT flag on every directory
+and file below the project's paths (equivalent to xfs_quota -x -c 'project -C').`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			manager := newQuotaManager(cmd)
+
+			result, err := manager.ClearProject(cmd.Context(), name, projectTreeOptions(quiet))
+			if err != nil {
+				return fmt.Errorf("failed to clear project: %w", err)
+			}
+
+			printProjectTreeResult("cleared", name, result)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "do not show progress")
+
+	return cmd
+}
+
+// projectTreeOptions 构造目录树处理选项，每处理1000个文件输出一次进度
+func projectTreeOptions(quiet bool) xfs.ProjectTreeOptions {
+	var opts xfs.ProjectTreeOptions
+	if !quiet {
+		opts.Progress = func(path string, processed uint64) {
+			if processed%1000 == 0 {
+				fmt.Printf("  %d files processed (%s)\n", processed, path)
+			}
+		}
+	}
+	return opts
+}
+
+func printProjectTreeResult(action string, name string, result *xfs.ProjectTreeResult) {
+	fmt.Printf("Project '%s' %s successfully\n", name, action)
+	fmt.Printf("  Processed: %d\n", result.Processed)
+	fmt.Printf("  Skipped: %d\n", result.Skipped)
+}
@@ -1,9 +1,10 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
-	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/xfs-quota-kit/pkg/xfs"
@@ -22,138 +23,161 @@ func NewQuotaCommand() *cobra.Command {
 		newQuotaSetCommand(),
 		newQuotaRemoveCommand(),
 		newQuotaListCommand(),
+		newQuotaGraceCommand(),
+		newQuotaEnforceCommand(),
+		newQuotaPurgeCommand(),
+		newQuotaVerifyCommand(),
+		newQuotaEnsureCommand(),
+		newQuotaBatchCommand(),
+		newQuotaImportCommand(),
 	)
 
 	return cmd
 }
 
 func newQuotaGetCommand() *cobra.Command {
-	var quotaType string
-	var id uint32
+	var target quotaTarget
+	var filesystems filesystemSelector
 
 	cmd := &cobra.Command{
 		Use:   "get [path]",
 		Short: "Get quota information",
 		Long:  `Get quota information for a user, group, or project.`,
-		Args:  cobra.ExactArgs(1),
+		Args:  cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			path := args[0]
-			manager := xfs.NewQuotaManager()
+			manager := newQuotaManager(cmd)
 
-			qType, err := parseQuotaType(quotaType)
+			targets, err := filesystems.resolve(cmd, args)
 			if err != nil {
 				return err
 			}
-
-			quota, err := manager.GetQuota(qType, id, path)
+			qType, id, _, err := target.resolve(cmd)
 			if err != nil {
-				return fmt.Errorf("failed to get quota: %w", err)
+				return err
 			}
 
-			printQuotaInfo(quota)
-			return nil
+			return forEachFilesystem(targets, func(fs filesystemTarget) error {
+				quota, err := manager.GetQuota(cmd.Context(), qType, id, fs.Path)
+				if err != nil {
+					return fmt.Errorf("failed to get quota: %w", err)
+				}
+
+				printQuotaInfo(quota)
+				return nil
+			})
 		},
 	}
 
-	cmd.Flags().StringVarP(&quotaType, "type", "t", "user", "quota type (user, group, project)")
-	cmd.Flags().Uint32VarP(&id, "id", "i", 0, "user/group/project ID")
-	cmd.MarkFlagRequired("id")
+	target.addFlags(cmd)
+	filesystems.addFlags(cmd)
 
 	return cmd
 }
 
 func newQuotaSetCommand() *cobra.Command {
-	var quotaType string
-	var id uint32
-	var blockSoft, blockHard string
-	var inodeSoft, inodeHard uint64
+	var target quotaTarget
+	var filesystems filesystemSelector
+	var limits xfs.QuotaLimits
 
 	cmd := &cobra.Command{
 		Use:   "set [path]",
 		Short: "Set quota limits",
-		Long:  `Set quota limits for a user, group, or project.`,
-		Args:  cobra.ExactArgs(1),
-		RunE: func(cmd *cobra.Command, args []string) error {
-			path := args[0]
-			manager := xfs.NewQuotaManager()
+		Long: `Set quota limits for a user, group, or project.
 
-			qType, err := parseQuotaType(quotaType)
-			if err != nil {
-				return err
-			}
+Block limits need a unit: KiB, MiB, GiB, TiB (or K, M, G, T) are powers of
+1024, KB, MB, GB, TB are powers of 1000, B is bytes and BB is 512-byte basic
+blocks. A bare number other than 0 is rejected.
 
-			limits := xfs.QuotaLimits{
-				InodeSoft: inodeSoft,
-				InodeHard: inodeHard,
-			}
+Only the limits given on the command line are changed; use 0 to remove a limit.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			manager := newQuotaManager(cmd)
 
-			// 解析块大小
-			if blockSoft != "" {
-				limits.BlockSoft, err = parseSize(blockSoft)
-				if err != nil {
-					return fmt.Errorf("invalid block soft limit: %w", err)
+			for flag, field := range quotaLimitFlags {
+				if cmd.Flags().Changed(flag) {
+					limits.Fields |= field
 				}
 			}
-
-			if blockHard != "" {
-				limits.BlockHard, err = parseSize(blockHard)
-				if err != nil {
-					return fmt.Errorf("invalid block hard limit: %w", err)
-				}
+			if limits.Fields == 0 {
+				return fmt.Errorf("specify at least one limit (--block-soft, --block-hard, --inode-soft, --inode-hard, --rt-soft, --rt-hard)")
 			}
 
-			err = manager.SetQuota(qType, id, path, limits)
+			targets, err := filesystems.resolve(cmd, args)
 			if err != nil {
-				return fmt.Errorf("failed to set quota: %w", err)
+				return err
 			}
+			qType, id, name, err := target.resolve(cmd)
+			if err != nil {
+				return err
+			}
+
+			return forEachFilesystem(targets, func(fs filesystemTarget) error {
+				if err := manager.SetQuota(cmd.Context(), qType, id, fs.Path, limits); err != nil {
+					return fmt.Errorf("failed to set quota: %w", err)
+				}
 
-			fmt.Printf("Quota set successfully for %s ID %d\n", qType, id)
-			return nil
+				fmt.Printf("Quota set successfully for %s\n", formatTarget(qType, id, name))
+				return nil
+			})
 		},
 	}
 
-	cmd.Flags().StringVarP(&quotaType, "type", "t", "user", "quota type (user, group, project)")
-	cmd.Flags().Uint32VarP(&id, "id", "i", 0, "user/group/project ID")
-	cmd.Flags().StringVar(&blockSoft, "block-soft", "", "block soft limit (e.g., 1GB, 500MB)")
-	cmd.Flags().StringVar(&blockHard, "block-hard", "", "block hard limit (e.g., 2GB, 1000MB)")
-	cmd.Flags().Uint64Var(&inodeSoft, "inode-soft", 0, "inode soft limit")
-	cmd.Flags().Uint64Var(&inodeHard, "inode-hard", 0, "inode hard limit")
-	cmd.MarkFlagRequired("id")
+	target.addFlags(cmd)
+	filesystems.addFlags(cmd)
+	cmd.Flags().Var(&limits.BlockSoft, "block-soft", "block soft limit (e.g., 1GiB, 500MiB)")
+	cmd.Flags().Var(&limits.BlockHard, "block-hard", "block hard limit (e.g., 2GiB, 1000MiB)")
+	cmd.Flags().Uint64Var(&limits.InodeSoft, "inode-soft", 0, "inode soft limit")
+	cmd.Flags().Uint64Var(&limits.InodeHard, "inode-hard", 0, "inode hard limit")
+	cmd.Flags().Var(&limits.RTBlockSoft, "rt-soft", "realtime block soft limit (e.g., 1GiB, 500MiB)")
+	cmd.Flags().Var(&limits.RTBlockHard, "rt-hard", "realtime block hard limit (e.g., 2GiB, 1000MiB)")
 
 	return cmd
 }
 
+// quotaLimitFlags quota set 的限制参数对应的字段
+var quotaLimitFlags = map[string]xfs.LimitFields{
+	"block-soft": xfs.LimitBlockSoft,
+	"block-hard": xfs.LimitBlockHard,
+	"inode-soft": xfs.LimitInodeSoft,
+	"inode-hard": xfs.LimitInodeHard,
+	"rt-soft":    xfs.LimitRTBlockSoft,
+	"rt-hard":    xfs.LimitRTBlockHard,
+}
+
 func newQuotaRemoveCommand() *cobra.Command {
-	var quotaType string
-	var id uint32
+	var target quotaTarget
+	var filesystems filesystemSelector
 
 	cmd := &cobra.Command{
 		Use:   "remove [path]",
 		Short: "Remove quota limits",
 		Long:  `Remove quota limits for a user, group, or project.`,
-		Args:  cobra.ExactArgs(1),
+		Args:  cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			path := args[0]
-			manager := xfs.NewQuotaManager()
+			manager := newQuotaManager(cmd)
 
-			qType, err := parseQuotaType(quotaType)
+			targets, err := filesystems.resolve(cmd, args)
 			if err != nil {
 				return err
 			}
-
-			err = manager.RemoveQuota(qType, id, path)
+			qType, id, name, err := target.resolve(cmd)
 			if err != nil {
-				return fmt.Errorf("failed to remove quota: %w", err)
+				return err
 			}
 
-			fmt.Printf("Quota removed successfully for %s ID %d\n", qType, id)
-			return nil
+			return forEachFilesystem(targets, func(fs filesystemTarget) error {
+				if err := manager.RemoveQuota(cmd.Context(), qType, id, fs.Path); err != nil {
+					return fmt.Errorf("failed to remove quota: %w", err)
+				}
+
+				fmt.Printf("Quota removed successfully for %s\n", formatTarget(qType, id, name))
+				return nil
+			})
 		},
 	}
 
-	cmd.Flags().StringVarP(&quotaType, "type", "t", "user", "quota type (user, group, project)")
-	cmd.Flags().Uint32VarP(&id, "id", "i", 0, "user/group/project ID")
-	cmd.MarkFlagRequired("id")
+	target.addFlags(cmd)
+	filesystems.addFlags(cmd)
 
 	return cmd
 }
@@ -161,102 +185,97 @@ func newQuotaRemoveCommand() *cobra.Command {
 func newQuotaListCommand() *cobra.Command {
 	var quotaType string
 	var format string
+	var filesystems filesystemSelector
 
 	cmd := &cobra.Command{
 		Use:   "list [path]",
 		Short: "List all quotas",
-		Long:  `List all quotas for a specific type on a filesystem.`,
-		Args:  cobra.ExactArgs(1),
+		Long: `List all quotas for a specific type on a filesystem.
+
+With several filesystems the quotas are grouped by filesystem; the JSON output
+is then an array of {"name", "filesystem", "quotas", "error"} objects.`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			path := args[0]
-			manager := xfs.NewQuotaManager()
+			manager := newQuotaManager(cmd)
 
+			targets, err := filesystems.resolve(cmd, args)
+			if err != nil {
+				return err
+			}
 			qType, err := parseQuotaType(quotaType)
 			if err != nil {
 				return err
 			}
 
-			quotas, err := manager.GetAllQuotas(qType, path)
-			if err != nil {
-				return fmt.Errorf("failed to list quotas: %w", err)
+			list := func(fs filesystemTarget, printer quotaPrinter) error {
+				err := manager.WalkQuotas(cmd.Context(), qType, fs.Path, func(quota *xfs.QuotaInfo) error {
+					printer.Print(quota)
+					return nil
+				})
+				printer.Close(err)
+				if err != nil {
+					return fmt.Errorf("failed to list quotas: %w", err)
+				}
+				return nil
 			}
 
-			switch format {
-			case "table":
-				printQuotasTable(quotas)
-			case "json":
-				printQuotasJSON(quotas)
-			default:
-				printQuotasTable(quotas)
+			if format != "json" || len(targets) == 1 {
+				return forEachFilesystem(targets, func(fs filesystemTarget) error {
+					return list(fs, newQuotaPrinter(format))
+				})
 			}
 
-			return nil
+			// 多个文件系统的JSON输出按文件系统分组，失败的文件系统带有 error 字段
+			failures := &filesystemsError{total: len(targets)}
+			fmt.Println("[")
+			for i, fs := range targets {
+				if i > 0 {
+					fmt.Println(",")
+				}
+				fmt.Printf("{\n  \"name\": %s,\n  \"filesystem\": %s,\n  \"quotas\": ", jsonString(fs.Name), jsonString(fs.Path))
+				err := list(fs, &jsonQuotaPrinter{suffix: ","})
+				if err != nil {
+					failures.add(fs, err)
+					fmt.Printf("  \"error\": %s\n}", jsonString(err.Error()))
+				} else {
+					fmt.Printf("  \"error\": null\n}")
+				}
+			}
+			fmt.Println("\n]")
+			return failures.err()
 		},
 	}
 
 	cmd.Flags().StringVarP(&quotaType, "type", "t", "user", "quota type (user, group, project)")
 	cmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table, json)")
+	filesystems.addFlags(cmd)
 
 	return cmd
 }
 
 // 辅助函数
 func parseQuotaType(typeStr string) (xfs.QuotaType, error) {
-	switch strings.ToLower(typeStr) {
-	case "user", "u":
-		return xfs.UserQuota, nil
-	case "group", "g":
-		return xfs.GroupQuota, nil
-	case "project", "p":
-		return xfs.ProjectQuota, nil
-	default:
-		return 0, fmt.Errorf("invalid quota type: %s", typeStr)
-	}
-}
-
-func parseSize(sizeStr string) (uint64, error) {
-	sizeStr = strings.ToUpper(strings.TrimSpace(sizeStr))
-
-	var multiplier uint64 = 1
-	var numStr string
-
-	if strings.HasSuffix(sizeStr, "KB") {
-		multiplier = 1
-		numStr = sizeStr[:len(sizeStr)-2]
-	} else if strings.HasSuffix(sizeStr, "MB") {
-		multiplier = 1024
-		numStr = sizeStr[:len(sizeStr)-2]
-	} else if strings.HasSuffix(sizeStr, "GB") {
-		multiplier = 1024 * 1024
-		numStr = sizeStr[:len(sizeStr)-2]
-	} else if strings.HasSuffix(sizeStr, "TB") {
-		multiplier = 1024 * 1024 * 1024
-		numStr = sizeStr[:len(sizeStr)-2]
-	} else {
-		numStr = sizeStr
-	}
-
-	num, err := strconv.ParseFloat(numStr, 64)
-	if err != nil {
-		return 0, err
-	}
-
-	return uint64(num * float64(multiplier)), nil
+	return xfs.ParseQuotaType(typeStr)
 }
 
 func printQuotaInfo(quota *xfs.QuotaInfo) {
+	now := time.Now()
 	fmt.Printf("Quota Information:\n")
 	fmt.Printf("  ID: %d\n", quota.ID)
+	if quota.Name != "" {
+		fmt.Printf("  Name: %s\n", quota.Name)
+	}
 	fmt.Printf("  Type: %s\n", quota.Type)
 	fmt.Printf("  Path: %s\n", quota.Path)
 	fmt.Printf("  Device: %s\n", quota.Device)
 	fmt.Printf("\nBlock Usage:\n")
-	fmt.Printf("  Used: %s\n", xfs.FormatSize(quota.BlockUsed*1024))
-	fmt.Printf("  Soft Limit: %s\n", xfs.FormatSize(quota.BlockSoft*1024))
-	fmt.Printf("  Hard Limit: %s\n", xfs.FormatSize(quota.BlockHard*1024))
+	fmt.Printf("  Used: %s\n", quota.BlockUsed.Human())
+	fmt.Printf("  Soft Limit: %s\n", quota.BlockSoft.Human())
+	fmt.Printf("  Hard Limit: %s\n", quota.BlockHard.Human())
 	if quota.BlockHard > 0 {
 		fmt.Printf("  Usage: %.1f%%\n", quota.BlockUsagePercent())
 	}
+	fmt.Printf("  Grace Remaining: %s\n", formatGraceRemaining(quota.BlockGraceRemaining(now)))
 	fmt.Printf("\nInode Usage:\n")
 	fmt.Printf("  Used: %d\n", quota.InodeUsed)
 	fmt.Printf("  Soft Limit: %d\n", quota.InodeSoft)
@@ -264,57 +283,131 @@ func printQuotaInfo(quota *xfs.QuotaInfo) {
 	if quota.InodeHard > 0 {
 		fmt.Printf("  Usage: %.1f%%\n", quota.InodeUsagePercent())
 	}
+	fmt.Printf("  Grace Remaining: %s\n", formatGraceRemaining(quota.InodeGraceRemaining(now)))
+	if quota.RTBlockUsed > 0 || quota.RTBlockSoft > 0 || quota.RTBlockHard > 0 {
+		fmt.Printf("\nRealtime Block Usage:\n")
+		fmt.Printf("  Used: %s\n", quota.RTBlockUsed.Human())
+		fmt.Printf("  Soft Limit: %s\n", quota.RTBlockSoft.Human())
+		fmt.Printf("  Hard Limit: %s\n", quota.RTBlockHard.Human())
+		if quota.RTBlockHard > 0 {
+			fmt.Printf("  Usage: %.1f%%\n", quota.RTUsagePercent())
+		}
+		fmt.Printf("  Grace Remaining: %s\n", formatGraceRemaining(quota.RTBlockGraceRemaining(now)))
+	}
 	fmt.Printf("\nLast Updated: %s\n", quota.LastUpdated.Format("2006-01-02 15:04:05"))
 }
 
-func printQuotasTable(quotas []xfs.QuotaInfo) {
-	if len(quotas) == 0 {
-		fmt.Println("No quotas found.")
-		return
+// quotaPrinter 流式输出配额信息，无需先收集全部配额
+type quotaPrinter interface {
+	Print(quota *xfs.QuotaInfo)
+	// Close 结束输出，err 为遍历的错误，失败时不能输出成空结果
+	Close(err error)
+}
+
+func newQuotaPrinter(format string) quotaPrinter {
+	switch format {
+	case "json":
+		return &jsonQuotaPrinter{}
+	default:
+		return &tableQuotaPrinter{}
 	}
+}
 
-	fmt.Printf("%-8s %-12s %-12s %-12s %-10s %-10s %-10s %-8s\n",
-		"ID", "Block Used", "Block Soft", "Block Hard", "Inode Used", "Inode Soft", "Inode Hard", "Status")
-	fmt.Println(strings.Repeat("-", 90))
+// tableQuotaPrinter 表格输出
+type tableQuotaPrinter struct {
+	count int
+}
 
-	for _, quota := range quotas {
-		status := "OK"
-		if quota.IsBlockExceeded() || quota.IsInodeExceeded() {
-			status = "OVER"
-		} else if quota.BlockUsagePercent() > 80 || quota.InodeUsagePercent() > 80 {
-			status = "WARNING"
+func (p *tableQuotaPrinter) Print(quota *xfs.QuotaInfo) {
+	if p.count == 0 {
+		fmt.Printf("%-8s %-16s %-12s %-12s %-12s %-10s %-10s %-10s %-8s %-10s\n",
+			"ID", "Name", "Block Used", "Block Soft", "Block Hard", "Inode Used", "Inode Soft", "Inode Hard", "Status", "Grace")
+		fmt.Println(strings.Repeat("-", 118))
+	}
+	p.count++
+
+	status := "OK"
+	if quota.IsBlockExceeded() || quota.IsInodeExceeded() || quota.IsRTExceeded() {
+		status = "OVER"
+	} else if quota.BlockUsagePercent() > 80 || quota.InodeUsagePercent() > 80 || quota.RTUsagePercent() > 80 {
+		status = "WARNING"
+	}
+
+	fmt.Printf("%-8d %-16s %-12s %-12s %-12s %-10d %-10d %-10d %-8s %-10s\n",
+		quota.ID,
+		quota.Name,
+		quota.BlockUsed.Human(),
+		quota.BlockSoft.Human(),
+		quota.BlockHard.Human(),
+		quota.InodeUsed,
+		quota.InodeSoft,
+		quota.InodeHard,
+		status,
+		formatGraceRemaining(quotaGraceRemaining(quota)))
+}
+
+// quotaGraceRemaining 获取块、inode和实时块中最早到期的宽限剩余时间
+func quotaGraceRemaining(quota *xfs.QuotaInfo) time.Duration {
+	now := time.Now()
+	var earliest time.Duration
+	for _, remaining := range []time.Duration{
+		quota.BlockGraceRemaining(now),
+		quota.InodeGraceRemaining(now),
+		quota.RTBlockGraceRemaining(now),
+	} {
+		if remaining != 0 && (earliest == 0 || remaining < earliest) {
+			earliest = remaining
 		}
+	}
+	return earliest
+}
+
+func (p *tableQuotaPrinter) Close(err error) {
+	if err == nil && p.count == 0 {
+		fmt.Println("No quotas found.")
+	}
+}
 
-		fmt.Printf("%-8d %-12s %-12s %-12s %-10d %-10d %-10d %-8s\n",
-			quota.ID,
-			xfs.FormatSize(quota.BlockUsed*1024),
-			xfs.FormatSize(quota.BlockSoft*1024),
-			xfs.FormatSize(quota.BlockHard*1024),
-			quota.InodeUsed,
-			quota.InodeSoft,
-			quota.InodeHard,
-			status)
+// jsonString 将字符串编码为JSON，%q 的转义不是有效的JSON
+func jsonString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+// jsonQuotaPrinter JSON数组输出
+type jsonQuotaPrinter struct {
+	count  int
+	suffix string // 数组结束后追加的内容，嵌入其他JSON对象时使用
+}
+
+// Print 用 json.Marshal 输出每个元素，字段与 QuotaInfo 的JSON标签一致
+func (p *jsonQuotaPrinter) Print(quota *xfs.QuotaInfo) {
+	data, err := json.MarshalIndent(quota, "  ", "  ")
+	if err != nil {
+		return
 	}
+	if p.count == 0 {
+		fmt.Println("[")
+	} else {
+		fmt.Println(",")
+	}
+	p.count++
+	fmt.Printf("  %s", data)
 }
 
-func printQuotasJSON(quotas []xfs.QuotaInfo) {
-	// 简化的JSON输出
-	fmt.Println("[")
-	for i, quota := range quotas {
-		fmt.Printf("  {\n")
-		fmt.Printf("    \"id\": %d,\n", quota.ID)
-		fmt.Printf("    \"type\": \"%s\",\n", quota.Type)
-		fmt.Printf("    \"block_used\": %d,\n", quota.BlockUsed)
-		fmt.Printf("    \"block_soft\": %d,\n", quota.BlockSoft)
-		fmt.Printf("    \"block_hard\": %d,\n", quota.BlockHard)
-		fmt.Printf("    \"inode_used\": %d,\n", quota.InodeUsed)
-		fmt.Printf("    \"inode_soft\": %d,\n", quota.InodeSoft)
-		fmt.Printf("    \"inode_hard\": %d\n", quota.InodeHard)
-		if i < len(quotas)-1 {
-			fmt.Printf("  },\n")
-		} else {
-			fmt.Printf("  }\n")
+// Close 嵌入其他JSON对象时总是结束数组，由之后的 error 字段标记失败；
+// 单独输出时失败不结束数组，避免不完整的列表被当作有效结果
+func (p *jsonQuotaPrinter) Close(err error) {
+	if err != nil && p.suffix == "" {
+		if p.count > 0 {
+			fmt.Println()
 		}
+		return
+	}
+	if p.count == 0 {
+		fmt.Println("[]" + p.suffix)
+		return
 	}
-	fmt.Println("]")
+	fmt.Println()
+	fmt.Println("]" + p.suffix)
 }
@@ -1,7 +1,10 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/xfs-quota-kit/pkg/xfs"
@@ -26,97 +29,295 @@ func NewReportCommand() *cobra.Command {
 func newReportGenerateCommand() *cobra.Command {
 	var format string
 	var output string
+	var filesystems filesystemSelector
 
 	cmd := &cobra.Command{
 		Use:   "generate [path]",
 		Short: "Generate quota usage report",
-		Long:  `Generate a comprehensive quota usage report for the specified filesystem.`,
-		Args:  cobra.ExactArgs(1),
+		Long: `Generate a comprehensive quota usage report for the specified filesystem.
+
+With --fs or --all-filesystems the reports are grouped by filesystem and
+followed by an overall summary. Filesystems that cannot be read (for example
+because they are not mounted) are listed as failed and the other filesystems
+are still reported. In the JSON output a failed filesystem is an entry of the
+"filesystems" array with an "error" field.`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			path := args[0]
-			manager := xfs.NewQuotaManager()
+			manager := newQuotaManager(cmd)
 
-			report, err := manager.GenerateReport(path)
+			targets, err := filesystems.resolve(cmd, args)
 			if err != nil {
-				return fmt.Errorf("failed to generate report: %w", err)
+				return err
+			}
+
+			if len(targets) == 1 {
+				if _, err := streamReport(cmd, manager, targets[0], format); err != nil {
+					return fmt.Errorf("failed to generate report: %w", err)
+				}
+				return nil
+			}
+
+			// 多个文件系统：依次输出各文件系统的报告，最后输出汇总
+			multi := &xfs.MultiReport{GeneratedAt: time.Now()}
+			failures := &filesystemsError{total: len(targets)}
+			if format == "json" {
+				fmt.Printf("{\n\"filesystems\": [\n")
+			}
+			for i, fs := range targets {
+				if format == "json" {
+					if i > 0 {
+						fmt.Printf(",\n")
+					}
+				} else {
+					fmt.Printf("==> %s <==\n", fs)
+				}
+
+				report, err := streamReport(cmd, manager, fs, format)
+				if err != nil {
+					err = fmt.Errorf("failed to generate report: %w", err)
+					failures.add(fs, err)
+					// JSON 中错误已输出在该文件系统的 error 字段中
+					if format != "json" {
+						fmt.Printf("Error: %v\n", err)
+						multi.AddError(fs.Name, fs.Path, err)
+					}
+				} else {
+					multi.AddReport(report)
+				}
+				if format != "json" {
+					fmt.Println()
+				}
 			}
 
 			switch format {
-			case "table":
-				printReport(report)
 			case "json":
-				printReportJSON(report)
+				fmt.Printf("],\n")
+				printMultiReportSummaryJSON(multi)
 			default:
-				printReport(report)
+				printMultiReportSummary(multi)
 			}
 
-			return nil
+			return failures.err()
 		},
 	}
 
 	cmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table, json)")
 	cmd.Flags().StringVarP(&output, "output", "o", "", "output file path")
+	filesystems.addFlags(cmd)
 
 	return cmd
 }
 
+// streamReport 流式输出一个文件系统的配额报告，内存占用与配额数量无关
+func streamReport(cmd *cobra.Command, manager xfs.ContextQuotaManager, fs filesystemTarget, format string) (*xfs.QuotaReport, error) {
+	var printer quotaPrinter
+	switch format {
+	case "json":
+		fmt.Printf("{\n")
+		if fs.Name != "" {
+			fmt.Printf("  \"name\": %s,\n", jsonString(fs.Name))
+		}
+		fmt.Printf("  \"filesystem\": %s,\n", jsonString(fs.Path))
+		fmt.Printf("  \"quotas\": ")
+		printer = &jsonQuotaPrinter{suffix: ","}
+	default:
+		fmt.Printf("Quota Report for %s\n", fs.Path)
+		fmt.Printf("\nDetailed Quota Information:\n")
+		printer = newQuotaPrinter("table")
+	}
+
+	report, err := manager.StreamReport(cmd.Context(), fs.Path, func(quota *xfs.QuotaInfo) error {
+		printer.Print(quota)
+		return nil
+	})
+	printer.Close(err)
+	if err != nil {
+		if format == "json" {
+			fmt.Printf("  \"error\": %s\n}", jsonString(err.Error()))
+		}
+		return nil, err
+	}
+	report.Name = fs.Name
+
+	switch format {
+	case "json":
+		printReportSummaryJSON(report)
+	default:
+		printReportSummary(report)
+	}
+	return report, nil
+}
+
 func newReportFilesystemCommand() *cobra.Command {
+	var format string
+	var filesystems filesystemSelector
+
 	cmd := &cobra.Command{
 		Use:   "filesystem [path]",
 		Short: "Show filesystem information",
-		Long:  `Show detailed filesystem information and quota status.`,
-		Args:  cobra.ExactArgs(1),
+		Long: `Show detailed filesystem information and quota status.
+
+For XFS filesystems the geometry from XFS_IOC_FSGEOMETRY is included:
+allocation groups, log and realtime sizes and enabled features such as crc,
+reflink and bigtime. Sizes in the JSON output are exact byte counts with a
+unit (e.g. "100GiB").`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			path := args[0]
-			manager := xfs.NewQuotaManager()
+			manager := newQuotaManager(cmd)
 
-			info, err := manager.GetFilesystemInfo(path)
+			targets, err := filesystems.resolve(cmd, args)
 			if err != nil {
-				return fmt.Errorf("failed to get filesystem info: %w", err)
+				return err
 			}
 
-			isXFS, _ := manager.IsXFSFilesystem(path)
+			if format == "json" {
+				// 多个文件系统时输出数组，失败的文件系统不在数组中
+				var infos []*xfs.FilesystemInfo
+				failures := &filesystemsError{total: len(targets)}
+				for _, fs := range targets {
+					info, err := manager.GetFilesystemInfo(cmd.Context(), fs.Path)
+					if err != nil {
+						if len(targets) == 1 {
+							return fmt.Errorf("failed to get filesystem info: %w", err)
+						}
+						failures.add(fs, fmt.Errorf("failed to get filesystem info: %w", err))
+						continue
+					}
+					infos = append(infos, info)
+				}
+
+				var data []byte
+				if len(targets) == 1 {
+					data, err = json.MarshalIndent(infos[0], "", "  ")
+				} else {
+					data, err = json.MarshalIndent(infos, "", "  ")
+				}
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+				return failures.err()
+			}
 
-			fmt.Printf("Filesystem Information:\n")
-			fmt.Printf("  Path: %s\n", path)
-			fmt.Printf("  Type: %s\n", info["type"])
-			fmt.Printf("  XFS: %t\n", isXFS)
-			fmt.Printf("  Block Size: %v\n", info["block_size"])
-			fmt.Printf("  Total Size: %s\n", info["total_size"])
-			fmt.Printf("  Used Size: %s\n", info["used_size"])
-			fmt.Printf("  Free Size: %s\n", info["free_size"])
-			fmt.Printf("  Total Inodes: %v\n", info["total_inodes"])
-			fmt.Printf("  Free Inodes: %v\n", info["free_inodes"])
+			return forEachFilesystem(targets, func(fs filesystemTarget) error {
+				info, err := manager.GetFilesystemInfo(cmd.Context(), fs.Path)
+				if err != nil {
+					return fmt.Errorf("failed to get filesystem info: %w", err)
+				}
 
-			return nil
+				printFilesystemInfo(info)
+				return nil
+			})
 		},
 	}
 
+	cmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table, json)")
+	filesystems.addFlags(cmd)
+
 	return cmd
 }
 
-func printReport(report *xfs.QuotaReport) {
-	fmt.Printf("Quota Report for %s\n", report.Filesystem)
-	fmt.Printf("Generated at: %s\n", report.GeneratedAt.Format("2006-01-02 15:04:05"))
+func printFilesystemInfo(info *xfs.FilesystemInfo) {
+	fmt.Printf("Filesystem Information:\n")
+	fmt.Printf("  Path: %s\n", info.Path)
+	fmt.Printf("  Type: 0x%X\n", info.Type)
+	fmt.Printf("  XFS: %t\n", info.IsXFS)
+	fmt.Printf("  Block Size: %d\n", info.BlockSize)
+	fmt.Printf("  Total Size: %s\n", info.TotalSize.Human())
+	fmt.Printf("  Used Size: %s\n", info.UsedSize().Human())
+	fmt.Printf("  Free Size: %s\n", info.FreeSize.Human())
+	fmt.Printf("  Available Size: %s\n", info.AvailSize.Human())
+	fmt.Printf("  Total Inodes: %d\n", info.TotalInodes)
+	fmt.Printf("  Free Inodes: %d\n", info.FreeInodes)
+
+	if info.GeometryError != "" {
+		fmt.Printf("\nXFS Geometry: unavailable (%s)\n", info.GeometryError)
+	}
+	g := info.Geometry
+	if g == nil {
+		return
+	}
+	log := "internal"
+	if !g.LogInternal {
+		log = "external"
+	}
+	features := "none"
+	if len(g.Features) > 0 {
+		features = strings.Join(g.Features, ", ")
+	}
+
+	fmt.Printf("\nXFS Geometry:\n")
+	fmt.Printf("  UUID: %s\n", g.UUID)
+	fmt.Printf("  Allocation Groups: %d x %s\n", g.AGCount, g.AGSize.Human())
+	fmt.Printf("  Data Size: %s\n", g.DataSize.Human())
+	fmt.Printf("  Sector Size: %d\n", g.SectorSize)
+	fmt.Printf("  Inode Size: %d (max %d%% of space)\n", g.InodeSize, g.InodeMaxPct)
+	fmt.Printf("  Directory Block Size: %d\n", g.DirBlockSize)
+	if g.StripeUnit > 0 {
+		fmt.Printf("  Stripe: unit %s, width %s\n", g.StripeUnit.Human(), g.StripeWidth.Human())
+	}
+	fmt.Printf("  Log: %s, %s\n", g.LogSize.Human(), log)
+	if g.RTSize > 0 {
+		fmt.Printf("  Realtime: %s, extent size %s\n", g.RTSize.Human(), g.RTExtentSize.Human())
+	} else {
+		fmt.Printf("  Realtime: none (extent size %s)\n", g.RTExtentSize.Human())
+	}
+	fmt.Printf("  Features: %s\n", features)
+}
+
+func printReportSummary(report *xfs.QuotaReport) {
 	fmt.Printf("\nSummary:\n")
+	fmt.Printf("  Generated at: %s\n", report.GeneratedAt.Format("2006-01-02 15:04:05"))
 	fmt.Printf("  Total Quotas: %d\n", report.TotalQuotas)
 	fmt.Printf("  Over Quota: %d\n", report.OverQuotas)
 	fmt.Printf("  Warning: %d\n", report.WarningQuotas)
 
-	if len(report.Quotas) > 0 {
-		fmt.Printf("\nDetailed Quota Information:\n")
-		printQuotasTable(report.Quotas)
+	if report.State != nil {
+		fmt.Printf("\nQuota State:\n")
+		for _, qt := range []xfs.QuotaType{xfs.UserQuota, xfs.GroupQuota, xfs.ProjectQuota} {
+			s := report.State.Type(qt)
+			fmt.Printf("  %s: accounting %s, enforcement %s\n", qt, onOff(s.Accounting), onOff(s.Enforced))
+		}
+		printQuotaStateProblems(report.State)
 	}
 }
 
-func printReportJSON(report *xfs.QuotaReport) {
-	fmt.Printf("{\n")
-	fmt.Printf("  \"filesystem\": \"%s\",\n", report.Filesystem)
+func printReportSummaryJSON(report *xfs.QuotaReport) {
 	fmt.Printf("  \"total_quotas\": %d,\n", report.TotalQuotas)
 	fmt.Printf("  \"over_quotas\": %d,\n", report.OverQuotas)
 	fmt.Printf("  \"warning_quotas\": %d,\n", report.WarningQuotas)
-	fmt.Printf("  \"generated_at\": \"%s\",\n", report.GeneratedAt.Format("2006-01-02T15:04:05Z"))
-	fmt.Printf("  \"quotas\": ")
-	printQuotasJSON(report.Quotas)
+	if report.State != nil {
+		if data, err := json.Marshal(report.State); err == nil {
+			fmt.Printf("  \"state\": %s,\n", data)
+		}
+	}
+	fmt.Printf("  \"generated_at\": \"%s\"\n", report.GeneratedAt.Format("2006-01-02T15:04:05Z"))
+	fmt.Printf("}\n")
+}
+
+func printMultiReportSummary(report *xfs.MultiReport) {
+	fmt.Printf("Overall Summary:\n")
+	fmt.Printf("  Generated at: %s\n", report.GeneratedAt.Format("2006-01-02 15:04:05"))
+	for _, r := range report.Filesystems {
+		name := r.Name
+		if name == "" {
+			name = r.Filesystem
+		}
+		fmt.Printf("  %s (%s): %d quotas, %d over quota, %d warning\n", name, r.Filesystem, r.TotalQuotas, r.OverQuotas, r.WarningQuotas)
+	}
+	for _, f := range report.Failed {
+		fmt.Printf("  %s (%s): FAILED: %s\n", f.Name, f.Filesystem, f.Error)
+	}
+	fmt.Printf("  Total Quotas: %d\n", report.TotalQuotas)
+	fmt.Printf("  Over Quota: %d\n", report.OverQuotas)
+	fmt.Printf("  Warning: %d\n", report.WarningQuotas)
+}
+
+// printMultiReportSummaryJSON 输出汇总，失败的文件系统在 filesystems 数组中带有 error 字段
+func printMultiReportSummaryJSON(report *xfs.MultiReport) {
+	fmt.Printf("\"total_quotas\": %d,\n", report.TotalQuotas)
+	fmt.Printf("\"over_quotas\": %d,\n", report.OverQuotas)
+	fmt.Printf("\"warning_quotas\": %d,\n", report.WarningQuotas)
+	fmt.Printf("\"generated_at\": \"%s\"\n", report.GeneratedAt.Format("2006-01-02T15:04:05Z"))
 	fmt.Printf("}\n")
 }
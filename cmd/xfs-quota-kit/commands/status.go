@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/xfs-quota-kit/pkg/xfs"
+)
+
+// NewStatusCommand 创建配额状态命令
+func NewStatusCommand() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "status [path]",
+		Short: "Show quota subsystem state",
+		Long: `Show whether accounting and enforcement are enabled for user, group and
+project quotas, the quota inodes, and the default grace times and warning limits.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := args[0]
+			manager := newQuotaManager(cmd)
+
+			state, err := manager.GetQuotaState(cmd.Context(), path)
+			if err != nil {
+				return fmt.Errorf("failed to get quota state: %w", err)
+			}
+
+			switch format {
+			case "json":
+				data, err := json.MarshalIndent(state, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+			default:
+				printQuotaState(state)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table, json)")
+
+	return cmd
+}
+
+func printQuotaState(state *xfs.QuotaState) {
+	fmt.Printf("Quota State:\n")
+	fmt.Printf("  Path: %s\n", state.Path)
+	fmt.Printf("  Mount Point: %s\n", state.MountPoint)
+	fmt.Printf("  Device: %s\n", state.Device)
+	fmt.Printf("  Incore Dquots: %d\n", state.IncoreDquots)
+	fmt.Println()
+
+	fmt.Printf("%-8s %-10s %-9s %-10s %-10s %-12s %-12s %-12s %-6s\n",
+		"Type", "Accounting", "Enforced", "Inode", "File Size", "Block Grace", "Inode Grace", "RT Grace", "Warns")
+	fmt.Println("----------------------------------------------------------------------------------------------")
+	for _, qt := range []xfs.QuotaType{xfs.UserQuota, xfs.GroupQuota, xfs.ProjectQuota} {
+		s := state.Type(qt)
+		fmt.Printf("%-8s %-10s %-9s %-10d %-10s %-12s %-12s %-12s %d/%d\n",
+			qt,
+			onOff(s.Accounting),
+			onOff(s.Enforced),
+			s.Inode,
+			s.FileSize.Human(),
+			formatGrace(s.BlockGrace),
+			formatGrace(s.InodeGrace),
+			formatGrace(s.RTBlockGrace),
+			s.BlockWarnLimit,
+			s.InodeWarnLimit)
+	}
+
+	printQuotaStateProblems(state)
+}
+
+func printQuotaStateProblems(state *xfs.QuotaState) {
+	problems := state.Problems()
+	if len(problems) == 0 {
+		return
+	}
+
+	fmt.Printf("\nWarnings:\n")
+	for _, problem := range problems {
+		fmt.Printf("  ! %s\n", problem)
+	}
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+// formatGrace 以天/小时/分钟格式化宽限时间，如 7d、1d12h、30m
+func formatGrace(d time.Duration) string {
+	if d <= 0 {
+		return "-"
+	}
+
+	days := int64(d / (24 * time.Hour))
+	hours := int64(d % (24 * time.Hour) / time.Hour)
+	minutes := int64(d % time.Hour / time.Minute)
+
+	var b strings.Builder
+	if days > 0 {
+		fmt.Fprintf(&b, "%dd", days)
+	}
+	if hours > 0 {
+		fmt.Fprintf(&b, "%dh", hours)
+	}
+	if minutes > 0 {
+		fmt.Fprintf(&b, "%dm", minutes)
+	}
+	if b.Len() == 0 {
+		fmt.Fprintf(&b, "%ds", int64(d/time.Second))
+	}
+	return b.String()
+}
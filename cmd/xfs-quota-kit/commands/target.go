@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/xfs-quota-kit/pkg/xfs"
+)
+
+// quotaTarget 配额命令的目标，可以用 --type/--id 或 --user/--group/--project 名称指定
+type quotaTarget struct {
+	quotaType string
+	id        uint32
+	user      string
+	group     string
+	project   string
+}
+
+// addFlags 注册目标相关的参数
+func (t *quotaTarget) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&t.quotaType, "type", "t", "user", "quota type (user, group, project)")
+	cmd.Flags().Uint32VarP(&t.id, "id", "i", 0, "user/group/project ID")
+	cmd.Flags().StringVarP(&t.user, "user", "u", "", "user name (implies --type user)")
+	cmd.Flags().StringVarP(&t.group, "group", "g", "", "group name (implies --type group)")
+	cmd.Flags().StringVarP(&t.project, "project", "p", "", "project name from /etc/projid (implies --type project)")
+	cmd.MarkFlagsMutuallyExclusive("id", "user", "group", "project")
+}
+
+// resolve 获取配额类型和ID，名称通过配置的名称解析器转换为ID
+func (t *quotaTarget) resolve(cmd *cobra.Command) (xfs.QuotaType, uint32, string, error) {
+	qType, err := parseQuotaType(t.quotaType)
+	if err != nil {
+		return 0, 0, "", err
+	}
+
+	var flag, name string
+	var nameType xfs.QuotaType
+	switch {
+	case t.user != "":
+		flag, name, nameType = "user", t.user, xfs.UserQuota
+	case t.group != "":
+		flag, name, nameType = "group", t.group, xfs.GroupQuota
+	case t.project != "":
+		flag, name, nameType = "project", t.project, xfs.ProjectQuota
+	case cmd.Flags().Changed("id"):
+		return qType, t.id, "", nil
+	default:
+		return 0, 0, "", fmt.Errorf("one of --id, --user, --group or --project is required")
+	}
+
+	if cmd.Flags().Changed("type") && qType != nameType {
+		return 0, 0, "", fmt.Errorf("--%s conflicts with --type %s", flag, t.quotaType)
+	}
+	id, err := xfs.ResolveID(newNameResolver(cmd), nameType, name)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	return nameType, id, name, nil
+}
+
+// formatTarget 格式化配额目标用于输出，如 "user alice (ID 1001)"
+func formatTarget(quotaType xfs.QuotaType, id uint32, name string) string {
+	if name == "" || name == fmt.Sprint(id) {
+		return fmt.Sprintf("%s ID %d", quotaType, id)
+	}
+	return fmt.Sprintf("%s %s (ID %d)", quotaType, name, id)
+}
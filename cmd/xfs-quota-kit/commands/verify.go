@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/xfs-quota-kit/pkg/xfs"
+)
+
+func newQuotaVerifyCommand() *cobra.Command {
+	var opts xfs.VerifyOptions
+	var format string
+	var quiet bool
+
+	cmd := &cobra.Command{
+		Use:   "verify [project]",
+		Short: "Verify project quota accounting against the directory tree",
+		Long: `Walk the project's directories, sum blocks and inodes per project ID, user
+and group, and compare the totals with the usage recorded in the dquots.
+
+The project's own usage must match exactly. Users and groups only own part of
+their files inside the project, so their totals must not exceed the recorded
+usage. Files with a different project ID and directories without PROJINHERIT
+are listed. Mount points below the project directories are not crossed; run
+the check while the tree is idle to avoid false mismatches.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			manager := newQuotaManager(cmd)
+
+			if !quiet && format != "json" {
+				opts.Progress = func(path string, scanned uint64) {
+					if scanned%10000 == 0 {
+						fmt.Printf("  %d files scanned (%s)\n", scanned, path)
+					}
+				}
+			}
+
+			result, err := manager.VerifyProject(cmd.Context(), name, opts)
+			if err != nil {
+				return fmt.Errorf("failed to verify project: %w", err)
+			}
+
+			switch format {
+			case "json":
+				data, err := json.MarshalIndent(result, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+			default:
+				printVerifyResult(result)
+			}
+
+			if !result.OK() {
+				return fmt.Errorf("project %s: quota accounting does not match the directory tree", name)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVarP(&opts.Workers, "workers", "w", xfs.DefaultVerifyWorkers, "number of directories scanned in parallel")
+	cmd.Flags().IntVar(&opts.MaxFiles, "max-files", xfs.DefaultVerifyMaxFiles, "maximum number of mismatched files to list (-1 for all)")
+	cmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table, json)")
+	cmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "do not show progress")
+
+	return cmd
+}
+
+func printVerifyResult(result *xfs.VerifyResult) {
+	fmt.Printf("Project '%s' (ID %d)\n", result.Project, result.ProjectID)
+	fmt.Printf("  Paths: %s\n", strings.Join(result.Paths, ", "))
+	fmt.Printf("  Scanned: %d\n", result.Scanned)
+	fmt.Printf("  Skipped: %d\n", result.Skipped)
+
+	fmt.Printf("\n%-8s %-10s %-16s %-14s %-14s %-12s %-12s %-8s\n",
+		"Type", "ID", "Name", "Scanned", "Reported", "Scanned Ino", "Reported Ino", "Status")
+	fmt.Println(strings.Repeat("-", 101))
+	for _, check := range result.Checks {
+		status := "OK"
+		if !check.OK {
+			status = "MISMATCH"
+		}
+		if !check.Exact {
+			status += "*"
+		}
+		fmt.Printf("%-8s %-10d %-16s %-14s %-14s %-12d %-12d %-8s\n",
+			check.Type, check.ID, check.Name,
+			(check.Scanned.Blocks + check.Scanned.RTBlocks).String(), (check.Reported.Blocks + check.Reported.RTBlocks).String(),
+			check.Scanned.Inodes, check.Reported.Inodes, status)
+	}
+	fmt.Printf("* only part of the files were scanned, usage must not exceed the reported usage\n")
+
+	if len(result.MismatchedFiles) > 0 {
+		fmt.Printf("\nMismatched files:\n")
+		for _, file := range result.MismatchedFiles {
+			if file.MissingInherit {
+				fmt.Printf("  %s (project %d, missing PROJINHERIT)\n", file.Path, file.ProjectID)
+			} else {
+				fmt.Printf("  %s (project %d)\n", file.Path, file.ProjectID)
+			}
+		}
+		if result.Truncated {
+			fmt.Printf("  ... more files omitted, use --max-files to list them\n")
+		}
+	}
+}
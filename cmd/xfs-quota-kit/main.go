@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 	"github.com/xfs-quota-kit/cmd/xfs-quota-kit/commands"
@@ -16,14 +19,22 @@ var (
 )
 
 func main() {
-	if err := newRootCommand().Execute(); err != nil {
+	// 收到中断信号时取消context，正在进行的配额操作尽快返回
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := newRootCommand().ExecuteContext(ctx)
+	stop()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		if hint := commands.ErrorHint(err); hint != "" {
+			fmt.Fprintf(os.Stderr, "Hint: %s\n", hint)
+		}
+		os.Exit(commands.ExitCode(err))
 	}
 }
 
 func newRootCommand() *cobra.Command {
 	var configFile string
+	var backend string
 
 	cmd := &cobra.Command{
 		Use:   "xfs-quota-kit",
@@ -48,6 +59,11 @@ Features:
 				return fmt.Errorf("failed to load config: %w", err)
 			}
 
+			// 命令行指定的后端优先于配置文件
+			if cmd.Flags().Changed("backend") {
+				cfg.XFS.Backend = backend
+			}
+
 			// 验证配置
 			if err := cfg.Validate(); err != nil {
 				return fmt.Errorf("invalid config: %w", err)
@@ -63,12 +79,15 @@ Features:
 
 	// 全局标志
 	cmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "config file path")
+	cmd.PersistentFlags().StringVar(&backend, "backend", "", "quota backend (native, xfs_quota)")
 
 	// 添加子命令
 	cmd.AddCommand(
 		commands.NewQuotaCommand(),
 		commands.NewProjectCommand(),
 		commands.NewReportCommand(),
+		commands.NewStatusCommand(),
+		commands.NewFilesystemsCommand(),
 		commands.NewMonitorCommand(),
 		commands.NewServerCommand(),
 		commands.NewCompletionCommand(),
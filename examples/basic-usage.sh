@@ -51,7 +51,7 @@ echo "===================="
 # 设置用户配额（需要 root 权限）
 echo "设置用户 1001 的配额..."
 $BINARY quota set "$XFS_PATH" --type user --id 1001 \
-    --block-hard 2GB --inode-hard 100000 || echo "设置配额失败（可能需要 root 权限或 XFS 文件系统）"
+    --block-hard 2GiB --inode-hard 100000 || echo "设置配额失败（可能需要 root 权限或 XFS 文件系统）"
 
 # 查看用户配额
 echo "查看用户 1001 的配额..."
@@ -2,9 +2,14 @@ package config
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
+	"time"
 
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
+	"github.com/xfs-quota-kit/pkg/utils"
+	"github.com/xfs-quota-kit/pkg/xfs"
 )
 
 // Config 应用配置结构
@@ -59,6 +64,10 @@ type XFSConfig struct {
 	DefaultPath   string           `mapstructure:"default_path"`
 	ProjectsFile  string           `mapstructure:"projects_file"`
 	ProjidFile    string           `mapstructure:"projid_file"`
+	Backend       string           `mapstructure:"backend"`        // native, xfs_quota
+	XFSQuotaPath  string           `mapstructure:"xfs_quota_path"` // xfs_quota 可执行文件路径
+	NameResolver  string           `mapstructure:"name_resolver"`  // files, system
+	ProjectIDs    ProjectIDConfig  `mapstructure:"project_ids"`
 	DefaultLimits DefaultLimits    `mapstructure:"default_limits"`
 	AutoCreate    bool             `mapstructure:"auto_create"`
 	BackupEnabled bool             `mapstructure:"backup_enabled"`
@@ -66,25 +75,34 @@ type XFSConfig struct {
 	Filesystems   []FilesystemInfo `mapstructure:"filesystems"`
 }
 
-// DefaultLimits 默认配额限制
+// ProjectIDConfig 项目ID分配配置
+type ProjectIDConfig struct {
+	Ranges     []string `mapstructure:"ranges"`     // e.g., "1000-9999"
+	Quarantine string   `mapstructure:"quarantine"` // 释放的ID多久内不再分配，e.g., "720h"
+	FreedFile  string   `mapstructure:"freed_file"` // 记录ID释放时间的文件
+}
+
+// DefaultLimits 默认配额限制，块限制必须带单位
 type DefaultLimits struct {
-	UserBlockSoft  string `mapstructure:"user_block_soft"` // e.g., "1GB"
-	UserBlockHard  string `mapstructure:"user_block_hard"` // e.g., "2GB"
-	UserInodeSoft  uint64 `mapstructure:"user_inode_soft"`
-	UserInodeHard  uint64 `mapstructure:"user_inode_hard"`
-	GroupBlockSoft string `mapstructure:"group_block_soft"`
-	GroupBlockHard string `mapstructure:"group_block_hard"`
-	GroupInodeSoft uint64 `mapstructure:"group_inode_soft"`
-	GroupInodeHard uint64 `mapstructure:"group_inode_hard"`
+	UserBlockSoft  utils.Size `mapstructure:"user_block_soft"` // e.g., "1GiB"
+	UserBlockHard  utils.Size `mapstructure:"user_block_hard"` // e.g., "2GiB"
+	UserInodeSoft  uint64     `mapstructure:"user_inode_soft"`
+	UserInodeHard  uint64     `mapstructure:"user_inode_hard"`
+	GroupBlockSoft utils.Size `mapstructure:"group_block_soft"`
+	GroupBlockHard utils.Size `mapstructure:"group_block_hard"`
+	GroupInodeSoft uint64     `mapstructure:"group_inode_soft"`
+	GroupInodeHard uint64     `mapstructure:"group_inode_hard"`
 }
 
 // FilesystemInfo 文件系统信息
 type FilesystemInfo struct {
-	Name       string `mapstructure:"name"`
-	MountPoint string `mapstructure:"mount_point"`
-	Device     string `mapstructure:"device"`
-	Options    string `mapstructure:"options"`
-	Enabled    bool   `mapstructure:"enabled"`
+	Name       string `mapstructure:"name" yaml:"name"`
+	MountPoint string `mapstructure:"mount_point" yaml:"mount_point"`
+	Device     string `mapstructure:"device" yaml:"device"`
+	Options    string `mapstructure:"options" yaml:"options"`
+	Enabled    bool   `mapstructure:"enabled" yaml:"enabled"`
+
+	ProjectIDRanges []string `mapstructure:"project_id_ranges" yaml:"project_id_ranges,omitempty"` // 覆盖 xfs.project_ids.ranges
 }
 
 // MonitorConfig 监控配置
@@ -132,7 +150,11 @@ func Load(configFile string) (*Config, error) {
 	}
 
 	// 解析到结构体
-	if err := viper.Unmarshal(config); err != nil {
+	if err := viper.Unmarshal(config, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		sizeDecodeHook,
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+	))); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
@@ -169,17 +191,22 @@ func setDefaults() {
 	viper.SetDefault("xfs.default_path", "/mnt/xfs")
 	viper.SetDefault("xfs.projects_file", "/etc/projects")
 	viper.SetDefault("xfs.projid_file", "/etc/projid")
+	viper.SetDefault("xfs.backend", "native")
+	viper.SetDefault("xfs.xfs_quota_path", "xfs_quota")
+	viper.SetDefault("xfs.name_resolver", "files")
+	viper.SetDefault("xfs.project_ids.ranges", []string{"1000-4294967294"})
+	viper.SetDefault("xfs.project_ids.freed_file", "/var/lib/xfs-quota-kit/projid.freed")
 	viper.SetDefault("xfs.auto_create", true)
 	viper.SetDefault("xfs.backup_enabled", true)
 	viper.SetDefault("xfs.backup_path", "/var/backups/xfs-quota-kit")
 
 	// 默认配额限制
-	viper.SetDefault("xfs.default_limits.user_block_soft", "1GB")
-	viper.SetDefault("xfs.default_limits.user_block_hard", "2GB")
+	viper.SetDefault("xfs.default_limits.user_block_soft", "1GiB")
+	viper.SetDefault("xfs.default_limits.user_block_hard", "2GiB")
 	viper.SetDefault("xfs.default_limits.user_inode_soft", 100000)
 	viper.SetDefault("xfs.default_limits.user_inode_hard", 200000)
-	viper.SetDefault("xfs.default_limits.group_block_soft", "10GB")
-	viper.SetDefault("xfs.default_limits.group_block_hard", "20GB")
+	viper.SetDefault("xfs.default_limits.group_block_soft", "10GiB")
+	viper.SetDefault("xfs.default_limits.group_block_hard", "20GiB")
 	viper.SetDefault("xfs.default_limits.group_inode_soft", 1000000)
 	viper.SetDefault("xfs.default_limits.group_inode_hard", 2000000)
 
@@ -230,6 +257,32 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("logging output set to file but no file specified")
 	}
 
+	// 验证XFS配置
+	validBackends := []string{"native", "xfs_quota"}
+	if c.XFS.Backend != "" && !contains(validBackends, c.XFS.Backend) {
+		return fmt.Errorf("invalid xfs backend: %s", c.XFS.Backend)
+	}
+
+	validResolvers := []string{"files", "system"}
+	if c.XFS.NameResolver != "" && !contains(validResolvers, c.XFS.NameResolver) {
+		return fmt.Errorf("invalid xfs name resolver: %s", c.XFS.NameResolver)
+	}
+
+	ranges := append([]string{}, c.XFS.ProjectIDs.Ranges...)
+	for _, fs := range c.XFS.Filesystems {
+		ranges = append(ranges, fs.ProjectIDRanges...)
+	}
+	for _, r := range ranges {
+		if _, err := xfs.ParseProjectIDRange(r); err != nil {
+			return err
+		}
+	}
+	if c.XFS.ProjectIDs.Quarantine != "" {
+		if _, err := time.ParseDuration(c.XFS.ProjectIDs.Quarantine); err != nil {
+			return fmt.Errorf("invalid project ID quarantine: %s", c.XFS.ProjectIDs.Quarantine)
+		}
+	}
+
 	return nil
 }
 
@@ -243,6 +296,25 @@ func (c *Config) IsDebugMode() bool {
 	return c.Server.Mode == "debug"
 }
 
+// sizeDecodeHook 将带单位的字符串解析为 utils.Size
+// 没有单位的数字只接受0，避免把KB数或字节数误当作另一种单位
+func sizeDecodeHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if to != reflect.TypeOf(utils.Size(0)) {
+		return data, nil
+	}
+	switch value := data.(type) {
+	case string:
+		return utils.ParseSize(value)
+	case utils.Size:
+		return value, nil
+	default:
+		if reflect.ValueOf(data).IsZero() {
+			return utils.Size(0), nil
+		}
+		return nil, fmt.Errorf("size %v needs a unit (e.g. KiB, MiB, GiB, or B for bytes)", data)
+	}
+}
+
 // 辅助函数
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
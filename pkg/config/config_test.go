@@ -5,8 +5,10 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/xfs-quota-kit/pkg/utils"
 )
 
 func TestConfig_Validate(t *testing.T) {
@@ -143,6 +145,63 @@ func TestConfig_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "logging output set to file but no file specified",
 		},
+		{
+			name: "invalid xfs backend",
+			config: Config{
+				Server: ServerConfig{
+					Port: 8080,
+					Mode: "release",
+				},
+				Logging: LoggingConfig{
+					Level:  "info",
+					Format: "json",
+					Output: "stdout",
+				},
+				XFS: XFSConfig{
+					Backend: "ioctl",
+				},
+			},
+			wantErr: true,
+			errMsg:  "invalid xfs backend",
+		},
+		{
+			name: "invalid name resolver",
+			config: Config{
+				Server: ServerConfig{
+					Port: 8080,
+					Mode: "release",
+				},
+				Logging: LoggingConfig{
+					Level:  "info",
+					Format: "json",
+					Output: "stdout",
+				},
+				XFS: XFSConfig{
+					NameResolver: "ldap",
+				},
+			},
+			wantErr: true,
+			errMsg:  "invalid xfs name resolver",
+		},
+		{
+			name: "invalid project ID range",
+			config: Config{
+				Server: ServerConfig{
+					Port: 8080,
+					Mode: "release",
+				},
+				Logging: LoggingConfig{
+					Level:  "info",
+					Format: "json",
+					Output: "stdout",
+				},
+				XFS: XFSConfig{
+					ProjectIDs: ProjectIDConfig{Ranges: []string{"2000-1000"}},
+				},
+			},
+			wantErr: true,
+			errMsg:  "invalid project ID range",
+		},
 	}
 
 	for _, tt := range tests {
@@ -306,12 +365,43 @@ func TestDefaultLimitsConfig(t *testing.T) {
 	require.NoError(t, err)
 
 	// 验证默认配额限制
-	assert.Equal(t, "1GB", config.XFS.DefaultLimits.UserBlockSoft)
-	assert.Equal(t, "2GB", config.XFS.DefaultLimits.UserBlockHard)
+	assert.Equal(t, 1*utils.GiB, config.XFS.DefaultLimits.UserBlockSoft)
+	assert.Equal(t, 2*utils.GiB, config.XFS.DefaultLimits.UserBlockHard)
 	assert.Equal(t, uint64(100000), config.XFS.DefaultLimits.UserInodeSoft)
 	assert.Equal(t, uint64(200000), config.XFS.DefaultLimits.UserInodeHard)
-	assert.Equal(t, "10GB", config.XFS.DefaultLimits.GroupBlockSoft)
-	assert.Equal(t, "20GB", config.XFS.DefaultLimits.GroupBlockHard)
+	assert.Equal(t, 10*utils.GiB, config.XFS.DefaultLimits.GroupBlockSoft)
+	assert.Equal(t, 20*utils.GiB, config.XFS.DefaultLimits.GroupBlockHard)
+}
+
+func TestDefaultLimitsConfigUnits(t *testing.T) {
+	// Load 使用全局viper，避免无效的配置影响后续测试
+	t.Cleanup(viper.Reset)
+
+	configFile := filepath.Join(t.TempDir(), "limits.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(`
+xfs:
+  default_limits:
+    user_block_soft: "500MiB"
+    user_block_hard: 0
+    group_block_soft: "1.5TB"
+    group_block_hard: "2048BB"
+`), 0644))
+
+	config, err := Load(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, 500*utils.MiB, config.XFS.DefaultLimits.UserBlockSoft)
+	assert.Equal(t, utils.Size(0), config.XFS.DefaultLimits.UserBlockHard)
+	assert.Equal(t, 1500*utils.GB, config.XFS.DefaultLimits.GroupBlockSoft)
+	assert.Equal(t, 1*utils.MiB, config.XFS.DefaultLimits.GroupBlockHard)
+
+	// 没有单位的数字无法确定含义
+	require.NoError(t, os.WriteFile(configFile, []byte(`
+xfs:
+  default_limits:
+    user_block_soft: 1024
+`), 0644))
+	_, err = Load(configFile)
+	assert.Error(t, err)
 }
 
 func TestMonitorConfig(t *testing.T) {
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/xfs-quota-kit/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// MergeFilesystems 将发现的文件系统合并到已有配置中
+// 挂载点相同的条目保留原有的名称、启用状态和项目ID范围，只更新设备和挂载选项；
+// 已配置但未发现的条目（如暂时未挂载）保持不变，新的文件系统追加在后面，重名时加数字后缀
+func MergeFilesystems(existing, discovered []FilesystemInfo) []FilesystemInfo {
+	merged := append([]FilesystemInfo{}, existing...)
+	index := make(map[string]int)
+	names := make(map[string]bool)
+	for i, fs := range merged {
+		index[filepath.Clean(fs.MountPoint)] = i
+		names[fs.Name] = true
+	}
+
+	for _, fs := range discovered {
+		if i, ok := index[filepath.Clean(fs.MountPoint)]; ok {
+			merged[i].Device = fs.Device
+			merged[i].Options = fs.Options
+			continue
+		}
+
+		name := fs.Name
+		for n := 2; names[name]; n++ {
+			name = fs.Name + "-" + strconv.Itoa(n)
+		}
+		fs.Name = name
+		names[name] = true
+		index[filepath.Clean(fs.MountPoint)] = len(merged)
+		merged = append(merged, fs)
+	}
+	return merged
+}
+
+// WriteFilesystems 将 xfs.filesystems 写入配置文件，文件中的其他内容和注释保持不变
+// 文件不存在时创建只包含 xfs.filesystems 的配置文件
+func WriteFilesystems(path string, filesystems []FilesystemInfo) error {
+	doc, xfs, err := readConfigDocument(path)
+	if err != nil {
+		return err
+	}
+	return writeFilesystems(path, doc, xfs, filesystems)
+}
+
+// UpdateFilesystems 将发现的文件系统合并到配置文件的 xfs.filesystems 中，返回合并后的列表
+// 合并规则见 MergeFilesystems
+func UpdateFilesystems(path string, discovered []FilesystemInfo) ([]FilesystemInfo, error) {
+	doc, xfs, err := readConfigDocument(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing []FilesystemInfo
+	for i := 0; i+1 < len(xfs.Content); i += 2 {
+		if xfs.Content[i].Value == "filesystems" {
+			if err := xfs.Content[i+1].Decode(&existing); err != nil {
+				return nil, fmt.Errorf("config file %s: invalid xfs.filesystems: %w", path, err)
+			}
+		}
+	}
+
+	merged := MergeFilesystems(existing, discovered)
+	if err := writeFilesystems(path, doc, xfs, merged); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// readConfigDocument 读取配置文件的YAML文档和其中的 xfs 映射，文件不存在时返回空文档
+func readConfigDocument(path string) (*yaml.Node, *yaml.Node, error) {
+	doc := &yaml.Node{}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := yaml.Unmarshal(data, doc); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	case errors.Is(err, os.ErrNotExist):
+	default:
+		return nil, nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if doc.Kind == 0 {
+		doc = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("config file %s is not a YAML mapping", path)
+	}
+
+	xfs, err := mappingValue(root, "xfs")
+	if err != nil {
+		return nil, nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	return doc, xfs, nil
+}
+
+// writeFilesystems 替换文档中的 xfs.filesystems 并写回配置文件
+func writeFilesystems(path string, doc, xfs *yaml.Node, filesystems []FilesystemInfo) error {
+	var value yaml.Node
+	if err := value.Encode(filesystems); err != nil {
+		return err
+	}
+	setMappingValue(xfs, "filesystems", &value)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(path, buf.Bytes(), 0644)
+}
+
+// mappingValue 获取映射中作为映射的键值，不存在或为空时改为空映射
+func mappingValue(mapping *yaml.Node, key string) (*yaml.Node, error) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != key {
+			continue
+		}
+		value := mapping.Content[i+1]
+		switch {
+		case value.Kind == yaml.MappingNode:
+		case value.Kind == yaml.ScalarNode && value.Tag == "!!null":
+			value.Kind, value.Tag, value.Value = yaml.MappingNode, "", ""
+		default:
+			return nil, fmt.Errorf("%s is not a mapping", key)
+		}
+		return value, nil
+	}
+
+	value := &yaml.Node{Kind: yaml.MappingNode}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+	return value, nil
+}
+
+// setMappingValue 替换映射中的键值，保留键上的注释
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+}
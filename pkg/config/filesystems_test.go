@@ -0,0 +1,103 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeFilesystems(t *testing.T) {
+	existing := []FilesystemInfo{
+		{Name: "home", MountPoint: "/home", Device: "/dev/sdb1", Enabled: false, ProjectIDRanges: []string{"5000-5999"}},
+		{Name: "archive", MountPoint: "/archive", Device: "/dev/sdd1", Enabled: true},
+	}
+	discovered := []FilesystemInfo{
+		{Name: "home", MountPoint: "/home/", Device: "/dev/sdc1", Options: "pquota", Enabled: true},
+		{Name: "data", MountPoint: "/data", Device: "/dev/sde1", Options: "uquota", Enabled: true},
+		{Name: "home", MountPoint: "/srv/home", Device: "/dev/sdf1", Enabled: true},
+	}
+
+	merged := MergeFilesystems(existing, discovered)
+	assert.Equal(t, []FilesystemInfo{
+		{Name: "home", MountPoint: "/home", Device: "/dev/sdc1", Options: "pquota", Enabled: false, ProjectIDRanges: []string{"5000-5999"}},
+		{Name: "archive", MountPoint: "/archive", Device: "/dev/sdd1", Enabled: true},
+		{Name: "data", MountPoint: "/data", Device: "/dev/sde1", Options: "uquota", Enabled: true},
+		{Name: "home-2", MountPoint: "/srv/home", Device: "/dev/sdf1", Enabled: true},
+	}, merged)
+}
+
+func TestWriteFilesystems(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(`# 服务器配置
+server:
+  port: 9000
+
+xfs:
+  backend: "native" # 配额后端
+  filesystems:
+    - name: "old"
+      mount_point: "/old"
+`), 0600))
+
+	filesystems := []FilesystemInfo{
+		{Name: "home", MountPoint: "/home", Device: "/dev/sdb1", Options: "uquota,pquota", Enabled: true},
+		{Name: "data", MountPoint: "/data", Device: "/dev/sdc1", ProjectIDRanges: []string{"5000-5999"}},
+	}
+	require.NoError(t, WriteFilesystems(configFile, filesystems))
+
+	data, err := os.ReadFile(configFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# 服务器配置")
+	assert.Contains(t, string(data), "# 配额后端")
+	assert.NotContains(t, string(data), "/old")
+
+	info, err := os.Stat(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	config, err := Load(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, 9000, config.Server.Port)
+	assert.Equal(t, "native", config.XFS.Backend)
+	assert.Equal(t, filesystems, config.XFS.Filesystems)
+}
+
+func TestWriteFilesystems_NewFile(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	filesystems := []FilesystemInfo{{Name: "root", MountPoint: "/", Device: "/dev/sda1", Enabled: true}}
+	require.NoError(t, WriteFilesystems(configFile, filesystems))
+
+	config, err := Load(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, filesystems, config.XFS.Filesystems)
+
+	require.NoError(t, os.WriteFile(configFile, []byte("xfs: native\n"), 0644))
+	assert.Error(t, WriteFilesystems(configFile, filesystems))
+}
+
+func TestUpdateFilesystems(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(`xfs:
+  filesystems:
+    - name: "home"
+      mount_point: "/home"
+      enabled: false
+`), 0644))
+
+	merged, err := UpdateFilesystems(configFile, []FilesystemInfo{
+		{Name: "home", MountPoint: "/home", Device: "/dev/sdb1", Options: "pquota", Enabled: true},
+		{Name: "data", MountPoint: "/data", Device: "/dev/sdc1", Enabled: true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []FilesystemInfo{
+		{Name: "home", MountPoint: "/home", Device: "/dev/sdb1", Options: "pquota"},
+		{Name: "data", MountPoint: "/data", Device: "/dev/sdc1", Enabled: true},
+	}, merged)
+
+	config, err := Load(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, merged, config.XFS.Filesystems)
+}
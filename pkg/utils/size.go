@@ -1,62 +1,199 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
-	"strconv"
+	"math"
+	"math/big"
 	"strings"
 )
 
-// ParseSize 解析大小字符串为字节数
-func ParseSize(sizeStr string) (uint64, error) {
-	sizeStr = strings.ToUpper(strings.TrimSpace(sizeStr))
-
-	var multiplier uint64 = 1
-	var numStr string
-
-	switch {
-	case strings.HasSuffix(sizeStr, "TB"):
-		multiplier = 1024 * 1024 * 1024 * 1024
-		numStr = sizeStr[:len(sizeStr)-2]
-	case strings.HasSuffix(sizeStr, "GB"):
-		multiplier = 1024 * 1024 * 1024
-		numStr = sizeStr[:len(sizeStr)-2]
-	case strings.HasSuffix(sizeStr, "MB"):
-		multiplier = 1024 * 1024
-		numStr = sizeStr[:len(sizeStr)-2]
-	case strings.HasSuffix(sizeStr, "KB"):
-		multiplier = 1024
-		numStr = sizeStr[:len(sizeStr)-2]
-	case strings.HasSuffix(sizeStr, "B"):
-		multiplier = 1
-		numStr = sizeStr[:len(sizeStr)-1]
-	default:
-		numStr = sizeStr
-	}
-
-	num, err := strconv.ParseFloat(numStr, 64)
-	if err != nil {
-		return 0, fmt.Errorf("invalid size format: %s", sizeStr)
+// Size 以字节为单位的大小
+// 文本形式必须带单位，只有0可以省略：
+//   - IEC: KiB、MiB、GiB、TiB、PiB、EiB，以及 xfs_quota 风格的 K、M、G、T、P、E，按1024进位
+//   - SI: KB、MB、GB、TB、PB、EB，按1000进位
+//   - B 为字节，BB 为512字节的文件系统基本块
+//
+// 单位不区分大小写，数值可以带小数，但结果必须是整数字节
+type Size uint64
+
+// 大小单位
+const (
+	Byte       Size = 1
+	BasicBlock Size = 512 // 文件系统基本块，fs_disk_quota 的块单位
+
+	KiB = 1024 * Byte
+	MiB = 1024 * KiB
+	GiB = 1024 * MiB
+	TiB = 1024 * GiB
+	PiB = 1024 * TiB
+	EiB = 1024 * PiB
+
+	KB = 1000 * Byte
+	MB = 1000 * KB
+	GB = 1000 * MB
+	TB = 1000 * GB
+	PB = 1000 * TB
+	EB = 1000 * PB
+)
+
+// sizeUnits 单位后缀（小写）与倍数
+var sizeUnits = map[string]Size{
+	"b":  Byte,
+	"bb": BasicBlock,
+	"k":  KiB, "kib": KiB, "kb": KB,
+	"m": MiB, "mib": MiB, "mb": MB,
+	"g": GiB, "gib": GiB, "gb": GB,
+	"t": TiB, "tib": TiB, "tb": TB,
+	"p": PiB, "pib": PiB, "pb": PB,
+	"e": EiB, "eib": EiB, "eb": EB,
+}
+
+// iecUnits 格式化使用的单位，从大到小
+var iecUnits = []struct {
+	size Size
+	name string
+}{
+	{EiB, "EiB"}, {PiB, "PiB"}, {TiB, "TiB"}, {GiB, "GiB"}, {MiB, "MiB"}, {KiB, "KiB"},
+}
+
+// ParseSize 解析带单位的大小，如 "2GiB"、"1.5 TB"、"2048BB"
+func ParseSize(s string) (Size, error) {
+	str := strings.TrimSpace(s)
+	end := 0
+	for end < len(str) && (str[end] >= '0' && str[end] <= '9' || str[end] == '.') {
+		end++
+	}
+	numStr, unitStr := str[:end], strings.ToLower(strings.TrimSpace(str[end:]))
+
+	if numStr == "" {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	num, ok := new(big.Rat).SetString(numStr)
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q", s)
 	}
 
-	if num < 0 {
-		return 0, fmt.Errorf("size cannot be negative: %s", sizeStr)
+	if unitStr == "" {
+		if num.Sign() == 0 {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("size %q needs a unit (e.g. KiB, MiB, GiB, or B for bytes)", s)
+	}
+	unit, ok := sizeUnits[unitStr]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q: unknown unit %q", s, str[end:])
 	}
 
-	return uint64(num * float64(multiplier)), nil
+	bytes := num.Mul(num, new(big.Rat).SetUint64(uint64(unit)))
+	if !bytes.IsInt() {
+		return 0, fmt.Errorf("invalid size %q: not a whole number of bytes", s)
+	}
+	if !bytes.Num().IsUint64() {
+		return 0, fmt.Errorf("invalid size %q: larger than %d bytes", s, uint64(math.MaxUint64))
+	}
+	return Size(bytes.Num().Uint64()), nil
 }
 
-// FormatSize 格式化字节数为可读格式
-func FormatSize(bytes uint64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
+// SizeFromKiB 将KiB数转换为大小
+func SizeFromKiB(kib uint64) Size {
+	return Size(kib) * KiB
+}
+
+// SizeFromBasicBlocks 将512字节基本块数转换为大小
+func SizeFromBasicBlocks(bb uint64) Size {
+	return Size(bb) * BasicBlock
+}
+
+// KiB 获取KiB数，不足1KiB的部分舍去
+func (s Size) KiB() uint64 {
+	return uint64(s / KiB)
+}
+
+// BasicBlocks 获取512字节基本块数，不足一块的部分向上取整
+func (s Size) BasicBlocks() uint64 {
+	return uint64(s/BasicBlock) + boolToUint64(s%BasicBlock != 0)
+}
+
+func boolToUint64(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// String 格式化为可以被 ParseSize 精确解析的形式，使用能整除的最大IEC单位，如 "1536MiB"
+func (s Size) String() string {
+	if s == 0 {
+		return "0"
+	}
+	for _, unit := range iecUnits {
+		if s%unit.size == 0 {
+			return fmt.Sprintf("%d%s", s/unit.size, unit.name)
+		}
+	}
+	return fmt.Sprintf("%dB", uint64(s))
+}
+
+// Human 格式化为便于阅读的近似值，如 "1.5 GiB"
+func (s Size) Human() string {
+	if s < KiB {
+		return fmt.Sprintf("%d B", uint64(s))
+	}
+	for _, unit := range iecUnits {
+		if s >= unit.size {
+			return fmt.Sprintf("%.1f %s", float64(s)/float64(unit.size), unit.name)
+		}
 	}
+	return fmt.Sprintf("%d B", uint64(s))
+}
+
+// FormatSize 格式化字节数为可读格式，如 "1.5 GiB"
+//
+// Deprecated: 使用 Size(bytes).Human()
+func FormatSize(bytes uint64) string {
+	return Size(bytes).Human()
+}
 
-	div, exp := uint64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
+// Set 实现 pflag.Value
+func (s *Size) Set(value string) error {
+	size, err := ParseSize(value)
+	if err != nil {
+		return err
 	}
+	*s = size
+	return nil
+}
+
+// Type 实现 pflag.Value
+func (s *Size) Type() string {
+	return "size"
+}
+
+// MarshalText 实现 encoding.TextMarshaler
+func (s Size) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler
+func (s *Size) UnmarshalText(text []byte) error {
+	return s.Set(string(text))
+}
 
-	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+// MarshalJSON 以带单位的字符串编码，如 "2GiB"
+func (s Size) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON 解析带单位的字符串；数字没有单位，只接受0
+func (s *Size) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		var num json.Number
+		if json.Unmarshal(data, &num) != nil {
+			return fmt.Errorf("invalid size %s", data)
+		}
+		str = num.String()
+	}
+	return s.Set(str)
 }
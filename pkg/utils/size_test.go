@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Size
+	}{
+		{"0", 0},
+		{"512B", 512},
+		{"1KiB", KiB},
+		{"1k", KiB},
+		{"1KB", 1000},
+		{"1.5GiB", 1536 * MiB},
+		{"2 GB", 2 * GB},
+		{"2048BB", 1 * MiB},
+		{"1.5TB", 1500 * GB},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			size, err := ParseSize(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, size)
+		})
+	}
+}
+
+func TestParseSize_Errors(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"1024",   // 没有单位
+		"GiB",    // 没有数值
+		"-1GiB",  // 负数
+		"1XB",    // 未知单位
+		"0.1B",   // 不足一字节
+		"16EiB",  // 溢出
+		"1.2.3M", // 非法数值
+	} {
+		t.Run(input, func(t *testing.T) {
+			_, err := ParseSize(input)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSize_String(t *testing.T) {
+	tests := []struct {
+		size     Size
+		expected string
+	}{
+		{0, "0"},
+		{100, "100B"},
+		{KiB, "1KiB"},
+		{1536 * MiB, "1536MiB"},
+		{2 * GiB, "2GiB"},
+		{GB, "1000000000B"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.size.String())
+
+			parsed, err := ParseSize(tt.size.String())
+			require.NoError(t, err)
+			assert.Equal(t, tt.size, parsed)
+		})
+	}
+
+	assert.Equal(t, "1.5 GiB", (1536 * MiB).Human())
+	assert.Equal(t, "100 B", Size(100).Human())
+	assert.Equal(t, "1.5 GiB", FormatSize(1536*1024*1024))
+}
+
+func TestSize_BasicBlocks(t *testing.T) {
+	assert.Equal(t, uint64(2), SizeFromBasicBlocks(2).BasicBlocks())
+	assert.Equal(t, uint64(3), Size(1025).BasicBlocks())
+	assert.Equal(t, uint64(1), Size(1025).KiB())
+	assert.Equal(t, 4*KiB, SizeFromKiB(4))
+}
+
+func TestSize_JSON(t *testing.T) {
+	var limits struct {
+		Soft Size `json:"soft"`
+		Hard Size `json:"hard"`
+	}
+	limits.Soft, limits.Hard = 1536*MiB, 0
+
+	data, err := json.Marshal(limits)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"soft":"1536MiB","hard":"0"}`, string(data))
+
+	require.NoError(t, json.Unmarshal([]byte(`{"soft":"1GB","hard":0}`), &limits))
+	assert.Equal(t, GB, limits.Soft)
+	assert.Equal(t, Size(0), limits.Hard)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"soft":1024}`), &limits))
+	assert.Error(t, json.Unmarshal([]byte(`{"soft":true}`), &limits))
+}
@@ -0,0 +1,236 @@
+package xfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultProjectIDRange 默认的项目ID分配范围
+var DefaultProjectIDRange = ProjectIDRange{Min: 1000, Max: math.MaxUint32 - 1}
+
+// ErrProjectIDInUse 项目ID已被占用
+var ErrProjectIDInUse = errors.New("project ID is already in use")
+
+// ProjectIDRange 项目ID范围（闭区间）
+type ProjectIDRange struct {
+	Min uint32
+	Max uint32
+}
+
+// ParseProjectIDRange 解析 "1000-1999" 形式的范围，单个数字表示只包含该ID
+func ParseProjectIDRange(s string) (ProjectIDRange, error) {
+	minStr, maxStr, found := strings.Cut(strings.TrimSpace(s), "-")
+	if !found {
+		maxStr = minStr
+	}
+
+	min, err := strconv.ParseUint(strings.TrimSpace(minStr), 10, 32)
+	if err != nil {
+		return ProjectIDRange{}, fmt.Errorf("invalid project ID range %q", s)
+	}
+	max, err := strconv.ParseUint(strings.TrimSpace(maxStr), 10, 32)
+	if err != nil {
+		return ProjectIDRange{}, fmt.Errorf("invalid project ID range %q", s)
+	}
+	if min == 0 || min > max {
+		return ProjectIDRange{}, fmt.Errorf("invalid project ID range %q (must be within 1-%d and ascending)", s, uint32(math.MaxUint32))
+	}
+	return ProjectIDRange{Min: uint32(min), Max: uint32(max)}, nil
+}
+
+// Contains 检查ID是否在范围内
+func (r ProjectIDRange) Contains(id uint32) bool {
+	return id >= r.Min && id <= r.Max
+}
+
+func (r ProjectIDRange) String() string {
+	if r.Min == r.Max {
+		return strconv.FormatUint(uint64(r.Min), 10)
+	}
+	return fmt.Sprintf("%d-%d", r.Min, r.Max)
+}
+
+// ProjectIDAllocator 项目ID分配器
+// 跳过 /etc/projid 中已有的ID、文件系统上仍有用量或限制的dquot，以及隔离期内释放的ID
+type ProjectIDAllocator struct {
+	Ranges           []ProjectIDRange            // 默认分配范围，为空时使用 DefaultProjectIDRange
+	FilesystemRanges map[string][]ProjectIDRange // 按挂载点指定的分配范围
+	Quarantine       time.Duration               // 释放的ID在此期间内不会再分配，0表示立即可以重用
+	FreedFile        string                      // 记录ID释放时间的文件 (id:unix时间)，Quarantine>0时使用
+
+	now func() time.Time
+}
+
+// rangesFor 获取挂载点对应的分配范围
+func (a *ProjectIDAllocator) rangesFor(mountPoint string) []ProjectIDRange {
+	if ranges, ok := a.FilesystemRanges[mountPoint]; ok && len(ranges) > 0 {
+		return ranges
+	}
+	if len(a.Ranges) > 0 {
+		return a.Ranges
+	}
+	return []ProjectIDRange{DefaultProjectIDRange}
+}
+
+func (a *ProjectIDAllocator) timeNow() time.Time {
+	if a.now != nil {
+		return a.now()
+	}
+	return time.Now()
+}
+
+// quarantineEnabled 是否启用释放ID的隔离期
+func (a *ProjectIDAllocator) quarantineEnabled() bool {
+	return a.Quarantine > 0 && a.FreedFile != ""
+}
+
+// Choose 选择项目ID：requested不为0时检查其是否冲突，否则在范围内分配第一个空闲ID
+// used 为已占用的ID及原因，通常来自项目文件和文件系统上的dquot
+func (a *ProjectIDAllocator) Choose(mountPoint string, requested uint32, used map[uint32]string) (uint32, error) {
+	if a.quarantineEnabled() {
+		freed, err := a.quarantined()
+		if err != nil {
+			return 0, err
+		}
+		for id, until := range freed {
+			if _, exists := used[id]; !exists {
+				used[id] = fmt.Sprintf("was released recently and is quarantined until %s", until.Format(time.RFC3339))
+			}
+		}
+	}
+
+	if requested != 0 {
+		if reason, exists := used[requested]; exists {
+			return 0, fmt.Errorf("%w: ID %d %s", ErrProjectIDInUse, requested, reason)
+		}
+		return requested, nil
+	}
+
+	ranges := a.rangesFor(mountPoint)
+	for _, r := range ranges {
+		for id := r.Min; ; id++ {
+			if _, exists := used[id]; !exists {
+				return id, nil
+			}
+			if id == r.Max {
+				break
+			}
+		}
+	}
+
+	names := make([]string, len(ranges))
+	for i, r := range ranges {
+		names[i] = r.String()
+	}
+	return 0, fmt.Errorf("no free project ID in range %s for %s", strings.Join(names, ","), mountPoint)
+}
+
+// quarantined 读取仍在隔离期内的ID及其到期时间
+func (a *ProjectIDAllocator) quarantined() (map[uint32]time.Time, error) {
+	file, err := ReadProjectFile(a.FreedFile)
+	if err != nil {
+		return nil, err
+	}
+
+	now := a.timeNow()
+	freed := make(map[uint32]time.Time)
+	for _, entry := range file.Entries() {
+		id, err := parseProjectID(entry.Key)
+		if err != nil {
+			continue
+		}
+		at, err := strconv.ParseInt(entry.Value, 10, 64)
+		if err != nil {
+			continue
+		}
+		if until := time.Unix(at, 0).Add(a.Quarantine); until.After(now) {
+			freed[id] = until
+		}
+	}
+	return freed, nil
+}
+
+// Release 记录ID的释放时间并清理已过隔离期的记录，未启用隔离期时不做任何操作
+func (a *ProjectIDAllocator) Release(id uint32) error {
+	if !a.quarantineEnabled() {
+		return nil
+	}
+
+	file, err := ReadProjectFile(a.FreedFile)
+	if err != nil {
+		return err
+	}
+
+	now := a.timeNow()
+	file.RemoveIf(func(entry ProjectFileEntry) bool {
+		entryID, err := parseProjectID(entry.Key)
+		if err != nil || entryID == id {
+			return err == nil
+		}
+		at, err := strconv.ParseInt(entry.Value, 10, 64)
+		return err == nil && !time.Unix(at, 0).Add(a.Quarantine).After(now)
+	})
+	file.Append(strconv.FormatUint(uint64(id), 10), strconv.FormatInt(now.Unix(), 10))
+
+	if err := os.MkdirAll(filepath.Dir(a.FreedFile), 0755); err != nil {
+		return err
+	}
+	return file.Write()
+}
+
+// usedProjectIDs 收集已占用的项目ID：项目文件中的条目和挂载点上仍有用量或限制的dquot
+func (q *quotaManager) usedProjectIDs(ctx context.Context, files *ProjectFiles, mount *MountInfo) (map[uint32]string, error) {
+	used := map[uint32]string{0: "is reserved for the default limits"}
+	for _, project := range files.List() {
+		if project.Name != "" {
+			used[project.ID] = fmt.Sprintf("is used by project %q in %s", project.Name, files.Projid.Path)
+		} else {
+			used[project.ID] = fmt.Sprintf("is listed in %s", files.Projects.Path)
+		}
+	}
+
+	err := q.backend.WalkDquots(ctx, ProjectQuota, mount, func(dq *FsDiskQuota) error {
+		if _, exists := used[dq.ID]; !exists && !dquotEmpty(dq) {
+			used[dq.ID] = fmt.Sprintf("still has quota usage or limits on %s", mount.MountPoint)
+		}
+		return nil
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, syscall.ESRCH), errors.Is(err, syscall.EINVAL), errors.Is(err, syscall.ENOSYS):
+		// 项目配额统计未开启或内核不支持枚举，只能依据项目文件判断
+	default:
+		return nil, &QuotaError{Op: "list", Path: mount.MountPoint, Err: err}
+	}
+	return used, nil
+}
+
+// dquotEmpty 检查dquot是否既没有用量也没有限制
+func dquotEmpty(dq *FsDiskQuota) bool {
+	return dq.BCount == 0 && dq.ICount == 0 && dq.RTBCount == 0 &&
+		dq.BlkSoftLimit == 0 && dq.BlkHardLimit == 0 &&
+		dq.InoSoftLimit == 0 && dq.InoHardLimit == 0 &&
+		dq.RTBSoftLimit == 0 && dq.RTBHardLimit == 0
+}
+
+// existingParent 获取路径本身或其最近的已存在的上级目录
+func existingParent(path string) string {
+	for {
+		if _, err := os.Lstat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,116 @@
+package xfs
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProjectIDRange(t *testing.T) {
+	r, err := ParseProjectIDRange("1000-1999")
+	require.NoError(t, err)
+	assert.Equal(t, ProjectIDRange{Min: 1000, Max: 1999}, r)
+	assert.True(t, r.Contains(1999))
+	assert.False(t, r.Contains(2000))
+	assert.Equal(t, "1000-1999", r.String())
+
+	r, err = ParseProjectIDRange("42")
+	require.NoError(t, err)
+	assert.Equal(t, "42", r.String())
+
+	for _, invalid := range []string{"", "0-10", "20-10", "a-b", "1-99999999999"} {
+		_, err := ParseProjectIDRange(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestProjectIDAllocator_Choose(t *testing.T) {
+	a := &ProjectIDAllocator{
+		Ranges:           []ProjectIDRange{{Min: 10, Max: 12}},
+		FilesystemRanges: map[string][]ProjectIDRange{"/srv": {{Min: 500, Max: 500}, {Min: 600, Max: 601}}},
+	}
+
+	id, err := a.Choose("/mnt/xfs", 0, map[uint32]string{10: "is used", 12: "is used"})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(11), id)
+
+	id, err = a.Choose("/srv", 0, map[uint32]string{500: "is used"})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(600), id)
+
+	_, err = a.Choose("/mnt/xfs", 0, map[uint32]string{10: "", 11: "", 12: ""})
+	assert.ErrorContains(t, err, "no free project ID in range 10-12")
+
+	// 显式指定的ID可以在范围之外，但不能冲突
+	id, err = a.Choose("/mnt/xfs", 99, map[uint32]string{})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(99), id)
+	_, err = a.Choose("/mnt/xfs", 11, map[uint32]string{11: `is used by project "web" in /etc/projid`})
+	assert.True(t, errors.Is(err, ErrProjectIDInUse))
+	assert.Contains(t, err.Error(), `ID 11 is used by project "web"`)
+
+	// 默认范围从1000开始
+	id, err = (&ProjectIDAllocator{}).Choose("/mnt/xfs", 0, map[uint32]string{1000: ""})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1001), id)
+}
+
+func TestProjectIDAllocator_Quarantine(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	a := &ProjectIDAllocator{
+		Ranges:     []ProjectIDRange{{Min: 10, Max: 20}},
+		Quarantine: 24 * time.Hour,
+		FreedFile:  filepath.Join(t.TempDir(), "state", "projid.freed"),
+		now:        func() time.Time { return now },
+	}
+
+	require.NoError(t, a.Release(10))
+	id, err := a.Choose("/mnt/xfs", 0, map[uint32]string{})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(11), id)
+
+	_, err = a.Choose("/mnt/xfs", 10, map[uint32]string{})
+	assert.True(t, errors.Is(err, ErrProjectIDInUse))
+	assert.Contains(t, err.Error(), "quarantined until")
+
+	// 隔离期过后可以重用，过期记录在下次释放时清理
+	now = now.Add(25 * time.Hour)
+	id, err = a.Choose("/mnt/xfs", 0, map[uint32]string{})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(10), id)
+
+	require.NoError(t, a.Release(11))
+	file, err := ReadProjectFile(a.FreedFile)
+	require.NoError(t, err)
+	require.Len(t, file.Entries(), 1)
+	assert.Equal(t, "11", file.Entries()[0].Key)
+}
+
+func TestQuotaManager_UsedProjectIDs(t *testing.T) {
+	fake := &fakeXFSQuota{stdout: map[string]string{
+		"report -p -N -n -b -i -r": `#0                  0          0          0     00 [--------]          3          0          0     00 [--------]          0          0          0     00 [--------]
+#1000               0          0          0     00 [--------]          0          0          0     00 [--------]          0          0          0     00 [--------]
+#1001               0          0       1024     00 [--------]          0          0          0     00 [--------]          0          0          0     00 [--------]
+#1002               8          0          0     00 [--------]          2          0          0     00 [--------]          0          0          0     00 [--------]
+`,
+	}}
+	q := NewContextQuotaManager(WithBackend(newFakeXFSQuotaBackend(fake, time.Now()))).(*quotaManager)
+
+	files := &ProjectFiles{
+		Projects: &ProjectFile{Path: "/etc/projects"},
+		Projid:   &ProjectFile{Path: "/etc/projid"},
+	}
+	require.NoError(t, files.Add(ProjectInfo{ID: 1002, Name: "web"}))
+
+	used, err := q.usedProjectIDs(context.Background(), files, testMount)
+	require.NoError(t, err)
+	assert.Len(t, used, 3)
+	assert.Contains(t, used[1001], "still has quota usage or limits on /mnt/xfs")
+	assert.Contains(t, used[1002], `project "web"`)
+	assert.NotContains(t, used, uint32(1000))
+}
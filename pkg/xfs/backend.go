@@ -0,0 +1,146 @@
+package xfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// BackendNative 直接调用 quotactl(2) 的后端
+	BackendNative = "native"
+	// BackendXFSQuota 调用 xfs_quota 命令行工具的后端
+	BackendXFSQuota = "xfs_quota"
+)
+
+// Backend 配额管理器与内核交互的底层实现
+// 所有块数量使用 fs_disk_quota 的单位（512字节基本块），
+// context取消后应尽快返回 ctx.Err()
+type Backend interface {
+	// Name 后端名称
+	Name() string
+
+	// GetDquot 获取单个ID的dquot，ID不存在时返回 ENOENT
+	GetDquot(ctx context.Context, quotaType QuotaType, id uint32, mount *MountInfo) (*FsDiskQuota, error)
+	// SetDquot 按 dq.FieldMask 设置限制、计时器和警告次数
+	SetDquot(ctx context.Context, quotaType QuotaType, id uint32, mount *MountInfo, dq *FsDiskQuota) error
+	// WalkDquots 按ID升序遍历所有存在的dquot
+	// 不支持枚举时在调用fn之前返回 EINVAL 或 ENOSYS
+	WalkDquots(ctx context.Context, quotaType QuotaType, mount *MountInfo, fn func(dq *FsDiskQuota) error) error
+
+	// GetStatv 获取配额子系统状态
+	GetStatv(ctx context.Context, quotaType QuotaType, mount *MountInfo) (*FsQuotaStatv, error)
+	// SetEnforcement 开启或关闭配额限制
+	SetEnforcement(ctx context.Context, quotaType QuotaType, mount *MountInfo, enforce bool) error
+	// RemoveQuotaFile 释放配额inode
+	RemoveQuotaFile(ctx context.Context, quotaType QuotaType, mount *MountInfo) error
+}
+
+// NewBackend 按名称创建后端，name为空时使用原生后端
+// xfsQuotaPath 为 xfs_quota 可执行文件路径，仅 xfs_quota 后端使用
+func NewBackend(name, xfsQuotaPath string) (Backend, error) {
+	switch name {
+	case "", BackendNative:
+		return NewNativeBackend(), nil
+	case BackendXFSQuota:
+		return NewXFSQuotaBackend(xfsQuotaPath), nil
+	default:
+		return nil, fmt.Errorf("unknown quota backend %q (expected %s or %s)", name, BackendNative, BackendXFSQuota)
+	}
+}
+
+// nativeBackend 通过 quotactl(2) 系统调用实现
+type nativeBackend struct{}
+
+// NewNativeBackend 创建原生 quotactl 后端
+func NewNativeBackend() Backend {
+	return nativeBackend{}
+}
+
+func (nativeBackend) Name() string {
+	return BackendNative
+}
+
+func (nativeBackend) GetDquot(ctx context.Context, quotaType QuotaType, id uint32, mount *MountInfo) (*FsDiskQuota, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var dq FsDiskQuota
+	if err := quotactl(makeQuotaCmd(Q_XGETQUOTA, quotaType), mount.Device(), id, unsafe.Pointer(&dq)); err != nil {
+		return nil, err
+	}
+	dq.ID = id
+	return &dq, nil
+}
+
+func (nativeBackend) SetDquot(ctx context.Context, quotaType QuotaType, id uint32, mount *MountInfo, dq *FsDiskQuota) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	dq.ID = id
+	return quotactl(makeQuotaCmd(Q_XSETQLIM, quotaType), mount.Device(), id, unsafe.Pointer(dq))
+}
+
+func (nativeBackend) WalkDquots(ctx context.Context, quotaType QuotaType, mount *MountInfo, fn func(dq *FsDiskQuota) error) error {
+	cmd := makeQuotaCmd(Q_XGETNEXTQUOTA, quotaType)
+	device := mount.Device()
+
+	id := uint32(0)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var dq FsDiskQuota
+		if err := quotactl(cmd, device, id, unsafe.Pointer(&dq)); err != nil {
+			if errors.Is(err, syscall.ENOENT) {
+				// 没有更多dquot
+				return nil
+			}
+			return err
+		}
+
+		if err := fn(&dq); err != nil {
+			return err
+		}
+
+		if dq.ID == math.MaxUint32 {
+			return nil
+		}
+		id = dq.ID + 1
+	}
+}
+
+func (nativeBackend) GetStatv(ctx context.Context, quotaType QuotaType, mount *MountInfo) (*FsQuotaStatv, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	statv := FsQuotaStatv{Version: FS_QSTATV_VERSION1}
+	if err := quotactl(makeQuotaCmd(Q_XGETQSTATV, quotaType), mount.Device(), 0, unsafe.Pointer(&statv)); err != nil {
+		return nil, err
+	}
+	return &statv, nil
+}
+
+func (nativeBackend) SetEnforcement(ctx context.Context, quotaType QuotaType, mount *MountInfo, enforce bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	_, flags := quotaTypeStateFlags(quotaType)
+	cmd := Q_XQUOTAOFF
+	if enforce {
+		cmd = Q_XQUOTAON
+	}
+	return quotactl(makeQuotaCmd(cmd, quotaType), mount.Device(), 0, unsafe.Pointer(&flags))
+}
+
+func (nativeBackend) RemoveQuotaFile(ctx context.Context, quotaType QuotaType, mount *MountInfo) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	flags := uint32(quotaTypeFlag(quotaType))
+	return quotactl(makeQuotaCmd(Q_XQUOTARM, quotaType), mount.Device(), 0, unsafe.Pointer(&flags))
+}
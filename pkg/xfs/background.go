@@ -0,0 +1,118 @@
+package xfs
+
+import "context"
+
+// BackgroundQuotaManager 将 ContextQuotaManager 包装为 QuotaManager，
+// 所有调用使用 context.Background()
+func BackgroundQuotaManager(m ContextQuotaManager) QuotaManager {
+	return backgroundManager{m: m}
+}
+
+// backgroundManager 不带context的配额管理器包装
+type backgroundManager struct {
+	m ContextQuotaManager
+}
+
+func (b backgroundManager) GetQuota(quotaType QuotaType, id uint32, path string) (*QuotaInfo, error) {
+	return b.m.GetQuota(context.Background(), quotaType, id, path)
+}
+
+func (b backgroundManager) SetQuota(quotaType QuotaType, id uint32, path string, limits QuotaLimits) error {
+	return b.m.SetQuota(context.Background(), quotaType, id, path, limits)
+}
+
+func (b backgroundManager) RemoveQuota(quotaType QuotaType, id uint32, path string) error {
+	return b.m.RemoveQuota(context.Background(), quotaType, id, path)
+}
+
+func (b backgroundManager) GetGraceTimes(quotaType QuotaType, path string) (*GraceTimes, error) {
+	return b.m.GetGraceTimes(context.Background(), quotaType, path)
+}
+
+func (b backgroundManager) SetGraceTimes(quotaType QuotaType, path string, grace GraceTimes) error {
+	return b.m.SetGraceTimes(context.Background(), quotaType, path, grace)
+}
+
+func (b backgroundManager) SetGraceTimers(quotaType QuotaType, id uint32, path string, timers GraceTimers) error {
+	return b.m.SetGraceTimers(context.Background(), quotaType, id, path, timers)
+}
+
+func (b backgroundManager) ResetGrace(quotaType QuotaType, id uint32, path string) error {
+	return b.m.ResetGrace(context.Background(), quotaType, id, path)
+}
+
+func (b backgroundManager) GetAllQuotas(quotaType QuotaType, path string) ([]QuotaInfo, error) {
+	return b.m.GetAllQuotas(context.Background(), quotaType, path)
+}
+
+func (b backgroundManager) SetBatchQuotas(quotaType QuotaType, path string, quotas map[uint32]QuotaLimits, opts BatchOptions) (*BatchResult, error) {
+	return b.m.SetBatchQuotas(context.Background(), quotaType, path, quotas, opts)
+}
+
+func (b backgroundManager) WalkQuotas(quotaType QuotaType, path string, fn QuotaWalkFunc) error {
+	return b.m.WalkQuotas(context.Background(), quotaType, path, fn)
+}
+
+func (b backgroundManager) EnsureDefaultLimits(path string, opts EnsureOptions) (*EnsureResult, error) {
+	return b.m.EnsureDefaultLimits(context.Background(), path, opts)
+}
+
+func (b backgroundManager) CreateProject(name string, path string, opts CreateProjectOptions) (*ProjectInfo, error) {
+	return b.m.CreateProject(context.Background(), name, path, opts)
+}
+
+func (b backgroundManager) RemoveProject(name string) error {
+	return b.m.RemoveProject(context.Background(), name)
+}
+
+func (b backgroundManager) GetProjects() ([]ProjectInfo, error) {
+	return b.m.GetProjects(context.Background())
+}
+
+func (b backgroundManager) SetupProject(name string, opts ProjectTreeOptions) (*ProjectTreeResult, error) {
+	return b.m.SetupProject(context.Background(), name, opts)
+}
+
+func (b backgroundManager) ClearProject(name string, opts ProjectTreeOptions) (*ProjectTreeResult, error) {
+	return b.m.ClearProject(context.Background(), name, opts)
+}
+
+func (b backgroundManager) VerifyProject(name string, opts VerifyOptions) (*VerifyResult, error) {
+	return b.m.VerifyProject(context.Background(), name, opts)
+}
+
+func (b backgroundManager) GenerateReport(path string) (*QuotaReport, error) {
+	return b.m.GenerateReport(context.Background(), path)
+}
+
+func (b backgroundManager) StreamReport(path string, fn QuotaWalkFunc) (*QuotaReport, error) {
+	return b.m.StreamReport(context.Background(), path, fn)
+}
+
+func (b backgroundManager) CheckQuotaStatus(path string) error {
+	return b.m.CheckQuotaStatus(context.Background(), path)
+}
+
+func (b backgroundManager) GetQuotaState(path string) (*QuotaState, error) {
+	return b.m.GetQuotaState(context.Background(), path)
+}
+
+func (b backgroundManager) SetEnforcement(quotaType QuotaType, path string, enforce bool) error {
+	return b.m.SetEnforcement(context.Background(), quotaType, path, enforce)
+}
+
+func (b backgroundManager) PurgeQuota(quotaType QuotaType, path string) error {
+	return b.m.PurgeQuota(context.Background(), quotaType, path)
+}
+
+func (b backgroundManager) IsXFSFilesystem(path string) (bool, error) {
+	return b.m.IsXFSFilesystem(context.Background(), path)
+}
+
+func (b backgroundManager) GetFilesystemInfo(path string) (*FilesystemInfo, error) {
+	return b.m.GetFilesystemInfo(context.Background(), path)
+}
+
+func (b backgroundManager) DiscoverFilesystems() ([]DiscoveredFilesystem, error) {
+	return b.m.DiscoverFilesystems(context.Background())
+}
@@ -0,0 +1,338 @@
+package xfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/xfs-quota-kit/pkg/utils"
+)
+
+// BatchMode 批量设置中某个ID失败时的处理方式
+type BatchMode int
+
+const (
+	BatchContinueOnError BatchMode = iota // 记录失败后继续处理其余ID
+	BatchAllOrNothing                     // 停止处理并恢复已修改的ID
+)
+
+func (m BatchMode) String() string {
+	switch m {
+	case BatchContinueOnError:
+		return "continue-on-error"
+	case BatchAllOrNothing:
+		return "all-or-nothing"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText 以名称形式输出到JSON
+func (m BatchMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText 解析 continue-on-error 或 all-or-nothing
+func (m *BatchMode) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "continue-on-error":
+		*m = BatchContinueOnError
+	case "all-or-nothing":
+		*m = BatchAllOrNothing
+	default:
+		return fmt.Errorf("invalid batch mode %q (continue-on-error, all-or-nothing)", text)
+	}
+	return nil
+}
+
+// BatchOptions 批量设置的选项
+type BatchOptions struct {
+	Mode    BatchMode
+	Workers int     // 同一文件系统上并发设置的goroutine数，0表示逐个设置
+	Rate    float64 // 每秒最多设置的ID数，0表示不限制
+
+	// Progress 每处理完一个ID后调用，done为已处理的ID数（包括失败的），
+	// 调用来自多个goroutine但不会同时发生，回滚不报告进度
+	Progress func(item *BatchItem, done, total int)
+}
+
+// BatchStatus 单个ID的处理结果
+type BatchStatus string
+
+const (
+	BatchPending        BatchStatus = "pending"         // 未处理（失败后停止或context被取消）
+	BatchApplied        BatchStatus = "applied"         // 已设置新的限制
+	BatchFailed         BatchStatus = "failed"          // 读取原有限制或设置失败，限制未改变
+	BatchRolledBack     BatchStatus = "rolled_back"     // 已设置，因其他ID失败恢复为原有限制
+	BatchRollbackFailed BatchStatus = "rollback_failed" // 已设置，但恢复原有限制失败
+)
+
+// BatchItem 批量设置中单个ID的结果
+type BatchItem struct {
+	Type     QuotaType    `json:"type"`
+	ID       uint32       `json:"id"`
+	Name     string       `json:"name,omitempty"`
+	Previous *QuotaLimits `json:"previous,omitempty"` // 修改前的限制，没有dquot时为全0，读取失败时为nil
+	Limits   QuotaLimits  `json:"limits"`             // 要设置的限制
+	Status   BatchStatus  `json:"status"`
+	Error    string       `json:"error,omitempty"`
+	Err      error        `json:"-"`
+}
+
+// SetError 记录ID的失败状态和错误
+func (i *BatchItem) SetError(status BatchStatus, err error) {
+	i.Status = status
+	i.Err = err
+	i.Error = err.Error()
+}
+
+// BatchResult 批量设置的结果，Items 按ID升序排列
+type BatchResult struct {
+	Type  QuotaType   `json:"type"`
+	Path  string      `json:"path"`
+	Mode  BatchMode   `json:"mode"`
+	Items []BatchItem `json:"items"`
+}
+
+// NewBatchResult 创建批量设置结果，所有ID初始为 BatchPending
+func NewBatchResult(quotaType QuotaType, path string, quotas map[uint32]QuotaLimits, opts BatchOptions) *BatchResult {
+	ids := make([]uint32, 0, len(quotas))
+	for id := range quotas {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	result := &BatchResult{Type: quotaType, Path: path, Mode: opts.Mode, Items: make([]BatchItem, len(ids))}
+	for i, id := range ids {
+		result.Items[i] = BatchItem{Type: quotaType, ID: id, Limits: quotas[id], Status: BatchPending}
+	}
+	return result
+}
+
+// Count 统计处于指定状态的ID数量
+func (r *BatchResult) Count(status BatchStatus) int {
+	n := 0
+	for _, item := range r.Items {
+		if item.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Err 汇总失败的ID，没有失败时返回nil
+func (r *BatchResult) Err() error {
+	var errs []error
+	rolledBack := false
+	for _, item := range r.Items {
+		switch item.Status {
+		case BatchFailed, BatchRollbackFailed:
+			errs = append(errs, item.Err)
+		case BatchRolledBack:
+			rolledBack = true
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &BatchError{
+		Path:       r.Path,
+		Failed:     r.Count(BatchFailed),
+		Total:      len(r.Items),
+		RolledBack: rolledBack && r.Count(BatchRollbackFailed) == 0,
+		Errs:       errs,
+	}
+}
+
+// BatchError 批量设置中部分ID失败
+// 可以通过 errors.Is/errors.As 匹配其中任一ID的错误
+type BatchError struct {
+	Path       string
+	Failed     int     // 设置失败的ID数量
+	Total      int     // 批量中的ID总数
+	RolledBack bool    // 已修改的ID全部恢复为原有限制
+	Errs       []error // 各ID的错误，包括恢复失败的错误
+}
+
+func (e *BatchError) Error() string {
+	msg := fmt.Sprintf("batch %s: %d of %d IDs failed", e.Path, e.Failed, e.Total)
+	if e.RolledBack {
+		msg += ", all changes rolled back"
+	}
+	if len(e.Errs) > 0 {
+		msg += ": " + e.Errs[0].Error()
+	}
+	if len(e.Errs) > 1 {
+		msg += fmt.Sprintf(" (and %d more)", len(e.Errs)-1)
+	}
+	return msg
+}
+
+func (e *BatchError) Unwrap() []error {
+	return e.Errs
+}
+
+// SetBatchQuotas 按ID升序批量设置配额
+// 修改前先读取所有ID原有的限制记录在结果中，BatchAllOrNothing 模式下读取失败时不做任何修改，
+// BatchContinueOnError 模式下读取失败的ID仍会设置，Previous 为nil。
+// 设置和回滚见 ExecuteBatch，有ID失败时返回 *BatchError，结果中包含每个ID的状态
+func (q *quotaManager) SetBatchQuotas(ctx context.Context, quotaType QuotaType, path string, quotas map[uint32]QuotaLimits, opts BatchOptions) (*BatchResult, error) {
+	mount, err := q.findMount(path)
+	if err != nil {
+		return nil, &QuotaError{Op: "batch", Path: path, Err: err}
+	}
+
+	result := NewBatchResult(quotaType, path, quotas, opts)
+	for i := range result.Items {
+		item := &result.Items[i]
+		item.Name, _ = q.names.LookupName(quotaType, item.ID)
+	}
+
+	// 先记录全部原有限制，BatchAllOrNothing 模式下读取失败时无法回滚，不做任何修改
+	failed := false
+	for i := range result.Items {
+		item := &result.Items[i]
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		dq, err := q.backend.GetDquot(ctx, quotaType, item.ID, mount)
+		switch {
+		case err == nil:
+			limits := dquotLimits(dq)
+			item.Previous = &limits
+		case errors.Is(err, syscall.ENOENT):
+			item.Previous = &QuotaLimits{}
+		case opts.Mode == BatchAllOrNothing:
+			item.SetError(BatchFailed, &QuotaError{Op: "batch", Path: path, Err: err})
+			failed = true
+		}
+	}
+	if failed {
+		return result, result.Err()
+	}
+
+	return result, ExecuteBatch(ctx, result, opts, func(ctx context.Context, id uint32, limits QuotaLimits) error {
+		return q.SetQuota(ctx, quotaType, id, path, limits)
+	})
+}
+
+// BatchSetFunc 设置单个ID的限制
+type BatchSetFunc func(ctx context.Context, id uint32, limits QuotaLimits) error
+
+// ExecuteBatch 对结果中仍为 BatchPending 的ID调用set，按 opts 控制并发数和速率
+// BatchAllOrNothing 模式下任一ID失败或context被取消时不再开始新的ID，等待进行中的ID完成后
+// 按相反顺序用 Previous 恢复已修改的ID，Previous 为nil的ID无法恢复。供 QuotaManager 的实现共用，返回值与 SetBatchQuotas 相同
+func ExecuteBatch(ctx context.Context, result *BatchResult, opts BatchOptions, set BatchSetFunc) error {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	// stop 在 BatchAllOrNothing 模式下有ID失败时取消，只用于停止分发，进行中的ID仍使用ctx
+	stop, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var tick <-chan time.Time
+	if opts.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.Rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	var mu sync.Mutex
+	done := len(result.Items) - result.Count(BatchPending)
+	jobs := make(chan *BatchItem)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range jobs {
+				if stop.Err() != nil {
+					continue
+				}
+				err := set(ctx, item.ID, item.Limits)
+				if err != nil && ctx.Err() != nil {
+					continue // 被取消的ID保持 BatchPending
+				}
+
+				mu.Lock()
+				if err != nil {
+					item.SetError(BatchFailed, err)
+					if opts.Mode == BatchAllOrNothing {
+						cancel()
+					}
+				} else {
+					item.Status = BatchApplied
+				}
+				done++
+				if opts.Progress != nil {
+					opts.Progress(item, done, len(result.Items))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for i := range result.Items {
+		item := &result.Items[i]
+		if item.Status != BatchPending {
+			continue
+		}
+		if tick != nil {
+			select {
+			case <-tick:
+			case <-stop.Done():
+				break dispatch
+			}
+		}
+		select {
+		case jobs <- item:
+		case <-stop.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	ctxErr := ctx.Err()
+	if opts.Mode == BatchAllOrNothing && (ctxErr != nil || result.Count(BatchFailed) > 0) {
+		// 回滚不受context取消影响，避免留下部分修改
+		rollbackCtx := context.WithoutCancel(ctx)
+		for i := len(result.Items) - 1; i >= 0; i-- {
+			item := &result.Items[i]
+			if item.Status != BatchApplied {
+				continue
+			}
+			if item.Previous == nil {
+				item.SetError(BatchRollbackFailed, fmt.Errorf("previous limits of %d are unknown", item.ID))
+				continue
+			}
+			if err := set(rollbackCtx, item.ID, *item.Previous); err != nil {
+				item.SetError(BatchRollbackFailed, err)
+				continue
+			}
+			item.Status = BatchRolledBack
+		}
+	}
+
+	return errors.Join(ctxErr, result.Err())
+}
+
+// dquotLimits 获取dquot中的限制
+func dquotLimits(dq *FsDiskQuota) QuotaLimits {
+	return QuotaLimits{
+		BlockSoft:   utils.SizeFromBasicBlocks(dq.BlkSoftLimit),
+		BlockHard:   utils.SizeFromBasicBlocks(dq.BlkHardLimit),
+		InodeSoft:   dq.InoSoftLimit,
+		InodeHard:   dq.InoHardLimit,
+		RTBlockSoft: utils.SizeFromBasicBlocks(dq.RTBSoftLimit),
+		RTBlockHard: utils.SizeFromBasicBlocks(dq.RTBHardLimit),
+	}
+}
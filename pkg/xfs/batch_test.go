@@ -0,0 +1,224 @@
+package xfs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xfs-quota-kit/pkg/utils"
+)
+
+func TestNewBatchResult(t *testing.T) {
+	quotas := map[uint32]QuotaLimits{
+		1002: {BlockHard: utils.GiB},
+		1000: {InodeHard: 100},
+		1001: {},
+	}
+	result := NewBatchResult(UserQuota, "/mnt/xfs", quotas, BatchOptions{Mode: BatchAllOrNothing})
+
+	require.Len(t, result.Items, 3)
+	for i, id := range []uint32{1000, 1001, 1002} {
+		assert.Equal(t, id, result.Items[i].ID)
+		assert.Equal(t, quotas[id], result.Items[i].Limits)
+		assert.Equal(t, BatchPending, result.Items[i].Status)
+	}
+	assert.Equal(t, 3, result.Count(BatchPending))
+	assert.NoError(t, result.Err())
+
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"mode":"all-or-nothing"`)
+
+	var mode BatchMode
+	require.NoError(t, mode.UnmarshalText([]byte("all-or-nothing")))
+	assert.Equal(t, BatchAllOrNothing, mode)
+	require.NoError(t, mode.UnmarshalText([]byte("continue-on-error")))
+	assert.Equal(t, BatchContinueOnError, mode)
+	assert.Error(t, mode.UnmarshalText([]byte("partial")))
+}
+
+func TestBatchResult_Err(t *testing.T) {
+	perm := &QuotaError{Op: "set", Path: "/mnt/xfs", Err: NewSyscallError("quotactl", syscall.EPERM)}
+	result := NewBatchResult(UserQuota, "/mnt/xfs", map[uint32]QuotaLimits{1000: {}, 1001: {}, 1002: {}}, BatchOptions{Mode: BatchAllOrNothing})
+	result.Items[0].Status = BatchRolledBack
+	result.Items[1].SetError(BatchFailed, perm)
+
+	err := result.Err()
+	var batchErr *BatchError
+	require.ErrorAs(t, err, &batchErr)
+	assert.Equal(t, 1, batchErr.Failed)
+	assert.Equal(t, 3, batchErr.Total)
+	assert.True(t, batchErr.RolledBack)
+	assert.ErrorIs(t, err, ErrPermission)
+	assert.Contains(t, err.Error(), "1 of 3 IDs failed, all changes rolled back")
+	assert.Equal(t, perm.Error(), result.Items[1].Error)
+
+	// 恢复失败时不能报告为已回滚
+	result.Items[0].SetError(BatchRollbackFailed, errors.New("io error"))
+	require.ErrorAs(t, result.Err(), &batchErr)
+	assert.False(t, batchErr.RolledBack)
+	assert.Len(t, batchErr.Errs, 2)
+	assert.Contains(t, batchErr.Error(), "(and 1 more)")
+}
+
+func TestExecuteBatch(t *testing.T) {
+	quotas := make(map[uint32]QuotaLimits)
+	for id := uint32(1000); id < 1100; id++ {
+		quotas[id] = QuotaLimits{InodeHard: uint64(id)}
+	}
+
+	var mu sync.Mutex
+	applied := make(map[uint32]QuotaLimits)
+	running, maxRunning := 0, 0
+	set := func(ctx context.Context, id uint32, limits QuotaLimits) error {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		running--
+		applied[id] = limits
+		return nil
+	}
+
+	result := NewBatchResult(UserQuota, "/mnt/xfs", quotas, BatchOptions{})
+	var progress []int
+	opts := BatchOptions{
+		Workers:  4,
+		Progress: func(item *BatchItem, done, total int) { progress = append(progress, done) },
+	}
+	require.NoError(t, ExecuteBatch(context.Background(), result, opts, set))
+	assert.Equal(t, 100, result.Count(BatchApplied))
+	assert.Equal(t, quotas, applied)
+	assert.LessOrEqual(t, maxRunning, 4)
+	require.Len(t, progress, 100)
+	assert.Equal(t, 100, progress[99])
+}
+
+func TestExecuteBatch_AllOrNothing(t *testing.T) {
+	quotas := make(map[uint32]QuotaLimits)
+	for id := uint32(1000); id < 1020; id++ {
+		quotas[id] = QuotaLimits{InodeHard: 100}
+	}
+
+	var mu sync.Mutex
+	current := make(map[uint32]QuotaLimits)
+	set := func(ctx context.Context, id uint32, limits QuotaLimits) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if id == 1005 {
+			return &QuotaError{Op: "set", Path: "/mnt/xfs", Err: NewSyscallError("quotactl", syscall.EPERM)}
+		}
+		current[id] = limits
+		return nil
+	}
+
+	result := NewBatchResult(UserQuota, "/mnt/xfs", quotas, BatchOptions{Mode: BatchAllOrNothing})
+	for i := range result.Items {
+		result.Items[i].Previous = &QuotaLimits{InodeHard: 10}
+	}
+	err := ExecuteBatch(context.Background(), result, BatchOptions{Mode: BatchAllOrNothing, Workers: 3, Rate: 1000}, set)
+	assert.ErrorIs(t, err, ErrPermission)
+
+	assert.Equal(t, 1, result.Count(BatchFailed))
+	assert.Zero(t, result.Count(BatchApplied))
+	for id, limits := range current {
+		assert.Equal(t, QuotaLimits{InodeHard: 10}, limits, "ID %d not rolled back", id)
+	}
+	assert.Equal(t, len(current), result.Count(BatchRolledBack))
+}
+
+func TestExecuteBatch_Rate(t *testing.T) {
+	quotas := make(map[uint32]QuotaLimits)
+	for id := uint32(1000); id < 1010; id++ {
+		quotas[id] = QuotaLimits{InodeHard: 100}
+	}
+	set := func(ctx context.Context, id uint32, limits QuotaLimits) error { return nil }
+
+	// 每秒100个，10个ID至少需要90ms，与并发数无关
+	result := NewBatchResult(UserQuota, "/mnt/xfs", quotas, BatchOptions{})
+	start := time.Now()
+	require.NoError(t, ExecuteBatch(context.Background(), result, BatchOptions{Workers: 4, Rate: 100}, set))
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+	assert.Equal(t, 10, result.Count(BatchApplied))
+}
+
+func TestExecuteBatch_Cancel(t *testing.T) {
+	quotas := make(map[uint32]QuotaLimits)
+	for id := uint32(1000); id < 1020; id++ {
+		quotas[id] = QuotaLimits{InodeHard: 100}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	set := func(ctx context.Context, id uint32, limits QuotaLimits) error {
+		if id == 1005 {
+			cancel()
+			return ctx.Err()
+		}
+		return nil
+	}
+
+	result := NewBatchResult(UserQuota, "/mnt/xfs", quotas, BatchOptions{})
+	reported := 0
+	opts := BatchOptions{Progress: func(item *BatchItem, done, total int) { reported++ }}
+	err := ExecuteBatch(ctx, result, opts, set)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// 被取消的ID和之后的ID保持 BatchPending，不报告进度
+	assert.Equal(t, 5, result.Count(BatchApplied))
+	assert.Equal(t, 15, result.Count(BatchPending))
+	assert.Equal(t, BatchPending, result.Items[5].Status)
+	assert.Equal(t, 5, reported)
+}
+
+func TestQuotaManager_SetBatchQuotasPreReadError(t *testing.T) {
+	fake := &fakeXFSQuota{stderr: map[string]string{
+		"report -u -N -n -b -i -r -L 1001 -U 1001": "xfs_quota: cannot read quota",
+	}}
+	q := NewContextQuotaManager(WithMountInfoPath("testdata/mountinfo"), WithBackend(newFakeXFSQuotaBackend(fake, time.Now())))
+	ctx := context.Background()
+	dir := t.TempDir() // 在 testdata/mountinfo 中属于根文件系统
+	limits := QuotaLimits{BlockHard: utils.MiB, Fields: LimitBlockHard}
+	quotas := map[uint32]QuotaLimits{1000: limits, 1001: limits}
+
+	// 读取原有限制失败的ID仍会设置
+	result, err := q.SetBatchQuotas(ctx, UserQuota, dir, quotas, BatchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Count(BatchApplied))
+	assert.Equal(t, &QuotaLimits{}, result.Items[0].Previous)
+	assert.Nil(t, result.Items[1].Previous)
+	assert.Contains(t, fake.commands, "limit -u bhard=1024k 1001")
+
+	// BatchAllOrNothing 模式下无法回滚，不做任何修改
+	fake.commands = nil
+	result, err = q.SetBatchQuotas(ctx, UserQuota, dir, quotas, BatchOptions{Mode: BatchAllOrNothing})
+	require.Error(t, err)
+	assert.Equal(t, BatchPending, result.Items[0].Status)
+	assert.Equal(t, BatchFailed, result.Items[1].Status)
+	for _, command := range fake.commands {
+		assert.NotContains(t, command, "limit")
+	}
+}
+
+func TestDquotLimits(t *testing.T) {
+	dq := &FsDiskQuota{BlkSoftLimit: 2048, BlkHardLimit: 4096, InoSoftLimit: 10, InoHardLimit: 20, RTBHardLimit: 8, BCount: 100}
+	assert.Equal(t, QuotaLimits{
+		BlockSoft:   utils.MiB,
+		BlockHard:   2 * utils.MiB,
+		InodeSoft:   10,
+		InodeHard:   20,
+		RTBlockHard: 4 * utils.KiB,
+	}, dquotLimits(dq))
+}
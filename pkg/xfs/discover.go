@@ -0,0 +1,88 @@
+package xfs
+
+import (
+	"context"
+)
+
+// DiscoveredFilesystem 从挂载表中发现的XFS文件系统
+type DiscoveredFilesystem struct {
+	MountPoint   string            `json:"mount_point"`           // 挂载点
+	Device       string            `json:"device"`                // 块设备
+	Options      []string          `json:"options"`               // 挂载选项，包括超级块选项
+	Quota        QuotaMountOptions `json:"quota"`                 // 各配额类型的统计和限制状态
+	ProjectQuota bool              `json:"project_quota"`         // 内核能否对该文件系统使用项目配额
+	StateError   string            `json:"state_error,omitempty"` // 无法通过quotactl查询状态时的错误，此时 Quota 取自挂载选项
+}
+
+// DiscoverFilesystems 扫描挂载表中的XFS文件系统及其配额状态
+// 配额状态优先通过 Q_XGETQSTATV 获取，能获取时说明内核支持该文件系统的XFS配额（包括项目配额）
+func (q *quotaManager) DiscoverFilesystems(ctx context.Context) ([]DiscoveredFilesystem, error) {
+	table, err := LoadMountTable(q.mountInfoPath)
+	if err != nil {
+		return nil, &QuotaError{Op: "discover", Path: q.mountInfoPath, Err: err}
+	}
+
+	var filesystems []DiscoveredFilesystem
+	for _, mount := range table.XFSMounts() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		fs := DiscoveredFilesystem{
+			MountPoint: mount.MountPoint,
+			Device:     mount.Device(),
+			Options:    append(append([]string{}, mount.Options...), mount.SuperOptions...),
+			Quota:      mount.QuotaOptions(),
+		}
+
+		state, err := q.GetQuotaState(ctx, mount.MountPoint)
+		if err != nil {
+			fs.StateError = err.Error()
+		} else {
+			fs.Quota = state.MountOptions()
+			fs.ProjectQuota = true
+		}
+		if fs.Quota.ProjectAccounting {
+			fs.ProjectQuota = true
+		}
+
+		filesystems = append(filesystems, fs)
+	}
+	return filesystems, nil
+}
+
+// XFSMounts 获取挂载表中的XFS文件系统，每个设备只返回一次
+// 跳过bind mount、同一设备的其他挂载点以及被其他挂载覆盖的挂载点
+func (t *MountTable) XFSMounts() []MountInfo {
+	var mounts []MountInfo
+	seen := make(map[[2]uint32]bool)
+	for i := range t.Mounts {
+		mount := &t.Mounts[i]
+		if !mount.IsXFS() || mount.IsBindMount() {
+			continue
+		}
+		if top, err := t.FindByMountPoint(mount.MountPoint); err != nil || top != mount {
+			continue
+		}
+
+		dev := [2]uint32{mount.Major, mount.Minor}
+		if seen[dev] {
+			continue
+		}
+		seen[dev] = true
+		mounts = append(mounts, *mount)
+	}
+	return mounts
+}
+
+// MountOptions 将配额状态转换为挂载选项形式
+func (s *QuotaState) MountOptions() QuotaMountOptions {
+	return QuotaMountOptions{
+		UserAccounting:    s.User.Accounting,
+		UserEnforced:      s.User.Enforced,
+		GroupAccounting:   s.Group.Accounting,
+		GroupEnforced:     s.Group.Enforced,
+		ProjectAccounting: s.Project.Accounting,
+		ProjectEnforced:   s.Project.Enforced,
+	}
+}
@@ -0,0 +1,56 @@
+package xfs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMountTable_XFSMounts(t *testing.T) {
+	table := loadFixtureMountTable(t)
+
+	var mountPoints []string
+	for _, mount := range table.XFSMounts() {
+		mountPoints = append(mountPoints, mount.MountPoint)
+	}
+	// /srv/web 是 /dev/sdb1 的bind mount，/home 上的ext4被XFS覆盖
+	assert.Equal(t, []string{"/", "/mnt/xfs", "/mnt/xfs2", "/data/my volume", "/home", "/boot"}, mountPoints)
+}
+
+func TestQuotaManager_DiscoverFilesystems(t *testing.T) {
+	q := NewContextQuotaManager(
+		WithMountInfoPath("testdata/mountinfo"),
+		WithBackend(newFakeXFSQuotaBackend(&fakeXFSQuota{}, time.Now())),
+	)
+
+	filesystems, err := q.DiscoverFilesystems(context.Background())
+	require.NoError(t, err)
+	require.Len(t, filesystems, 6)
+
+	// 测试环境中挂载点不存在，配额状态取自挂载选项
+	fs := filesystems[1]
+	assert.Equal(t, "/mnt/xfs", fs.MountPoint)
+	assert.Equal(t, "/dev/sdb1", fs.Device)
+	assert.Contains(t, fs.Options, "prjquota")
+	assert.NotEmpty(t, fs.StateError)
+	assert.Equal(t, "uquota,pquota", fs.Quota.String())
+	assert.True(t, fs.ProjectQuota)
+
+	assert.Equal(t, "/dev/block/8:1", filesystems[5].Device)
+	assert.False(t, filesystems[5].Quota.Any())
+
+	q = NewContextQuotaManager(WithMountInfoPath("testdata/missing"))
+	_, err = q.DiscoverFilesystems(context.Background())
+	assert.Error(t, err)
+}
+
+func TestQuotaState_MountOptions(t *testing.T) {
+	state := &QuotaState{
+		User:    QuotaTypeState{Accounting: true, Enforced: true},
+		Project: QuotaTypeState{Accounting: true},
+	}
+	assert.Equal(t, "uquota,pqnoenforce", state.MountOptions().String())
+}
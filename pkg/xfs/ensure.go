@@ -0,0 +1,145 @@
+package xfs
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"syscall"
+)
+
+// 应用默认限制时处理的ID范围，与 login.defs 中 UID_MIN/UID_MAX 的默认值一致，
+// 跳过系统账号和 nobody (65534)
+const (
+	DefaultEnsureMinID = 1000
+	DefaultEnsureMaxID = 60000
+)
+
+// EnsureOptions 应用默认限制的选项
+type EnsureOptions struct {
+	Limits map[QuotaType]QuotaLimits // 各配额类型的默认限制，全为0的类型不处理
+	MinID  uint32                    // 只处理 MinID 到 MaxID 之间的ID，为0时使用默认范围
+	MaxID  uint32
+	DryRun bool // 只列出需要应用默认限制的ID，不做修改
+}
+
+// Includes 检查ID是否在处理范围内
+func (o EnsureOptions) Includes(id uint32) bool {
+	min, max := o.MinID, o.MaxID
+	if min == 0 {
+		min = DefaultEnsureMinID
+	}
+	if max == 0 {
+		max = DefaultEnsureMaxID
+	}
+	return id >= min && id <= max
+}
+
+// EnsureAction 对一个ID应用的默认限制
+type EnsureAction struct {
+	Type   QuotaType   `json:"type"`
+	ID     uint32      `json:"id"`
+	Name   string      `json:"name,omitempty"`
+	Limits QuotaLimits `json:"limits"`
+}
+
+// EnsureResult 应用默认限制的结果
+type EnsureResult struct {
+	Path     string         `json:"path"`
+	DryRun   bool           `json:"dry_run"`
+	Applied  []EnsureAction `json:"applied"`  // DryRun时为将要应用的默认限制
+	Existing int            `json:"existing"` // 已有显式限制而跳过的ID数量
+}
+
+// EnsureDefaultLimits 为没有显式限制的用户和组设置默认限制
+// 处理的ID来自 passwd/group 文件以及文件系统上已有的dquot（包括LDAP等来源中已经写过文件的账号），
+// 已设置任一限制的ID不会被修改
+func (q *quotaManager) EnsureDefaultLimits(ctx context.Context, path string, opts EnsureOptions) (*EnsureResult, error) {
+	mount, err := q.findMount(path)
+	if err != nil {
+		return nil, &QuotaError{Op: "ensure", Path: path, Err: err}
+	}
+
+	result := &EnsureResult{Path: path, DryRun: opts.DryRun}
+	for _, quotaType := range []QuotaType{UserQuota, GroupQuota} {
+		limits := opts.Limits[quotaType]
+		if limits == (QuotaLimits{}) {
+			continue
+		}
+
+		ids, err := q.ensureCandidates(ctx, quotaType, mount, opts)
+		if err != nil {
+			return result, &QuotaError{Op: "ensure", Path: path, Err: err}
+		}
+
+		for _, id := range ids {
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+
+			dq, err := q.backend.GetDquot(ctx, quotaType, id, mount)
+			switch {
+			case err == nil && dquotHasLimits(dq):
+				result.Existing++
+				continue
+			case err == nil, errors.Is(err, syscall.ENOENT):
+			default:
+				return result, &QuotaError{Op: "ensure", Path: path, Err: err}
+			}
+
+			if !opts.DryRun {
+				if err := q.SetQuota(ctx, quotaType, id, path, limits); err != nil {
+					return result, err
+				}
+			}
+			action := EnsureAction{Type: quotaType, ID: id, Limits: limits}
+			action.Name, _ = q.names.LookupName(quotaType, id)
+			result.Applied = append(result.Applied, action)
+		}
+	}
+	return result, nil
+}
+
+// ensureCandidates 获取范围内的已知ID和已有dquot的ID，按升序排列
+func (q *quotaManager) ensureCandidates(ctx context.Context, quotaType QuotaType, mount *MountInfo, opts EnsureOptions) ([]uint32, error) {
+	known, err := q.knownIDs(quotaType)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[uint32]bool)
+	for _, id := range known {
+		if opts.Includes(id) {
+			seen[id] = true
+		}
+	}
+
+	err = q.backend.WalkDquots(ctx, quotaType, mount, func(dq *FsDiskQuota) error {
+		if opts.Includes(dq.ID) {
+			seen[dq.ID] = true
+		}
+		return nil
+	})
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		return nil, ctx.Err()
+	case errors.Is(err, syscall.EINVAL), errors.Is(err, syscall.ENOSYS):
+		// 内核不支持枚举dquot，只处理已知ID
+	default:
+		return nil, err
+	}
+
+	ids := make([]uint32, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+// dquotHasLimits 检查dquot是否设置了任一限制
+func dquotHasLimits(dq *FsDiskQuota) bool {
+	return dq.BlkSoftLimit != 0 || dq.BlkHardLimit != 0 ||
+		dq.InoSoftLimit != 0 || dq.InoHardLimit != 0 ||
+		dq.RTBSoftLimit != 0 || dq.RTBHardLimit != 0
+}
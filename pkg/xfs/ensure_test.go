@@ -0,0 +1,50 @@
+package xfs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnsureOptions_Includes(t *testing.T) {
+	opts := EnsureOptions{}
+	assert.False(t, opts.Includes(0))
+	assert.False(t, opts.Includes(999))
+	assert.True(t, opts.Includes(1000))
+	assert.False(t, opts.Includes(65534))
+
+	opts = EnsureOptions{MinID: 500, MaxID: 70000}
+	assert.True(t, opts.Includes(500))
+	assert.True(t, opts.Includes(65534))
+}
+
+func TestQuotaManager_EnsureCandidates(t *testing.T) {
+	// 1500 不在 passwd 中（如LDAP账号），但已经在文件系统上写过文件
+	fake := &fakeXFSQuota{stdout: map[string]string{
+		"report -u -N -n -b -i -r": `#0                  0          0          0     00 [--------]          3          0          0     00 [--------]          0          0          0     00 [--------]
+#1000               8          0          0     00 [--------]          2          0          0     00 [--------]          0          0          0     00 [--------]
+#1500              16          0          0     00 [--------]          1          0          0     00 [--------]          0          0          0     00 [--------]
+#65534              8          0          0     00 [--------]          1          0          0     00 [--------]          0          0          0     00 [--------]
+`,
+	}}
+	passwd := filepath.Join(t.TempDir(), "passwd")
+	require.NoError(t, os.WriteFile(passwd, []byte("root:x:0:0:::\nalice:x:1000:1000:::\nbob:x:1001:1001:::\nnobody:x:65534:65534:::\n"), 0644))
+
+	q := NewContextQuotaManager(WithBackend(newFakeXFSQuotaBackend(fake, time.Now()))).(*quotaManager)
+	q.passwdFile = passwd
+
+	ids, err := q.ensureCandidates(context.Background(), UserQuota, testMount, EnsureOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{1000, 1001, 1500}, ids)
+}
+
+func TestDquotHasLimits(t *testing.T) {
+	assert.False(t, dquotHasLimits(&FsDiskQuota{BCount: 100, ICount: 3}))
+	assert.True(t, dquotHasLimits(&FsDiskQuota{InoHardLimit: 10}))
+	assert.True(t, dquotHasLimits(&FsDiskQuota{RTBSoftLimit: 8}))
+}
@@ -0,0 +1,120 @@
+package xfs
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"syscall"
+)
+
+const (
+	// DefaultPasswdFile 默认用户数据库
+	DefaultPasswdFile = "/etc/passwd"
+	// DefaultGroupFile 默认组数据库
+	DefaultGroupFile = "/etc/group"
+)
+
+// forEachDquot 遍历文件系统上所有存在dquot的ID
+// 优先由后端枚举（Q_XGETNEXTQUOTA），不支持时退回到遍历已知ID
+func (q *quotaManager) forEachDquot(ctx context.Context, quotaType QuotaType, path string, mount *MountInfo, fn func(quota *QuotaInfo) error) error {
+	device := mount.Device()
+
+	var fnErr error
+	visited := false
+	err := q.backend.WalkDquots(ctx, quotaType, mount, func(dq *FsDiskQuota) error {
+		visited = true
+		fnErr = fn(q.quotaInfo(quotaType, path, device, dq))
+		return fnErr
+	})
+	switch {
+	case err == nil:
+		return nil
+	case fnErr != nil:
+		return fnErr
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case !visited && (errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOSYS)):
+		// 4.6之前的内核不支持 Q_XGETNEXTQUOTA
+		return q.forEachKnownID(ctx, quotaType, path, mount, fn)
+	default:
+		return &QuotaError{Op: "list", Path: path, Err: err}
+	}
+}
+
+// forEachKnownID 逐个查询 passwd/group/projid 中已知的ID
+func (q *quotaManager) forEachKnownID(ctx context.Context, quotaType QuotaType, path string, mount *MountInfo, fn func(quota *QuotaInfo) error) error {
+	ids, err := q.knownIDs(quotaType)
+	if err != nil {
+		return &QuotaError{Op: "list", Path: path, Err: err}
+	}
+
+	device := mount.Device()
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		dq, err := q.backend.GetDquot(ctx, quotaType, id, mount)
+		if err != nil {
+			if errors.Is(err, syscall.ENOENT) {
+				continue // 该ID没有dquot
+			}
+			return &QuotaError{Op: "list", Path: path, Err: err}
+		}
+
+		if err := fn(q.quotaInfo(quotaType, path, device, dq)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// knownIDs 获取系统中已知的用户/组/项目ID（包含ID 0），按升序排列
+func (q *quotaManager) knownIDs(quotaType QuotaType) ([]uint32, error) {
+	seen := map[uint32]bool{0: true}
+
+	switch quotaType {
+	case UserQuota, GroupQuota:
+		file := q.passwdFile
+		if quotaType == GroupQuota {
+			file = q.groupFile
+		}
+		ids, err := readIDFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			seen[id] = true
+		}
+	case ProjectQuota:
+		projects, err := q.projects.List()
+		if err != nil {
+			return nil, err
+		}
+		for _, project := range projects {
+			seen[project.ID] = true
+		}
+	}
+
+	ids := make([]uint32, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+// readIDFile 读取 passwd/group 格式文件的第三列（UID/GID）
+func readIDFile(path string) ([]uint32, error) {
+	entries, err := readNameFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint32, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,48 @@
+package xfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadIDFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "passwd")
+	content := "# comment\nroot:x:0:0:root:/root:/bin/bash\nalice:x:1001:1001::/home/alice:/bin/sh\n\nbroken\nbob:x:notanumber:1:::\ncarol:x:70000:100:::\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	ids, err := readIDFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{0, 1001, 70000}, ids)
+
+	_, err = readIDFile(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func TestQuotaManager_KnownIDs(t *testing.T) {
+	dir := t.TempDir()
+	passwd := filepath.Join(dir, "passwd")
+	group := filepath.Join(dir, "group")
+	require.NoError(t, os.WriteFile(passwd, []byte("alice:x:1001:1001:::\nbob:x:1000:1000:::\nalice2:x:1001:1001:::\n"), 0644))
+	require.NoError(t, os.WriteFile(group, []byte("staff:x:50:alice,bob\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "projid"), []byte("web:10\ndb:11\n"), 0644))
+
+	q := NewContextQuotaManager(WithProjectFiles(filepath.Join(dir, "projects"), filepath.Join(dir, "projid"))).(*quotaManager)
+	q.passwdFile = passwd
+	q.groupFile = group
+
+	ids, err := q.knownIDs(UserQuota)
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{0, 1000, 1001}, ids)
+
+	ids, err = q.knownIDs(GroupQuota)
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{0, 50}, ids)
+
+	ids, err = q.knownIDs(ProjectQuota)
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{0, 10, 11}, ids)
+}
@@ -0,0 +1,70 @@
+package xfs
+
+import (
+	"errors"
+	"syscall"
+)
+
+// 配额操作的错误类别，可以通过 errors.Is 判断
+// quotactl 和 ioctl 返回的errno会映射到对应的类别，原始errno仍可用 errors.Is 匹配
+var (
+	// ErrNotXFS 路径不在XFS文件系统上
+	ErrNotXFS = errors.New("not an XFS filesystem")
+	// ErrQuotaNotEnabled 该类型的配额统计未开启 (ESRCH)
+	ErrQuotaNotEnabled = errors.New("quota accounting is not enabled")
+	// ErrNoDquot ID没有配额记录 (ENOENT)
+	ErrNoDquot = errors.New("no quota record for this ID")
+	// ErrPermission 权限不足，需要 CAP_SYS_ADMIN (EPERM/EACCES)
+	ErrPermission = errors.New("permission denied (CAP_SYS_ADMIN required)")
+	// ErrNotSupported 内核或文件系统不支持该命令 (ENOSYS/EOPNOTSUPP/ENOTTY)
+	ErrNotSupported = errors.New("operation not supported by the kernel")
+	// ErrInvalid 参数无效或文件系统不支持该配额类型 (EINVAL)
+	ErrInvalid = errors.New("invalid argument")
+)
+
+// SyscallError quotactl 或 ioctl 系统调用错误
+type SyscallError struct {
+	Syscall string        // 系统调用名称
+	Errno   syscall.Errno // 原始errno
+}
+
+// NewSyscallError 创建系统调用错误
+func NewSyscallError(name string, errno syscall.Errno) error {
+	return &SyscallError{Syscall: name, Errno: errno}
+}
+
+func (e *SyscallError) Error() string {
+	return e.Syscall + ": " + e.Errno.Error()
+}
+
+func (e *SyscallError) Unwrap() error {
+	return e.Errno
+}
+
+// Is 将errno映射到错误类别
+func (e *SyscallError) Is(target error) bool {
+	class := errnoClass(e.Errno)
+	return class != nil && class == target
+}
+
+// errnoClass 获取errno对应的错误类别
+func errnoClass(errno syscall.Errno) error {
+	switch errno {
+	case syscall.ESRCH:
+		return ErrQuotaNotEnabled
+	case syscall.ENOENT:
+		return ErrNoDquot
+	case syscall.EPERM, syscall.EACCES:
+		return ErrPermission
+	case syscall.ENOSYS, syscall.EOPNOTSUPP, syscall.ENOTTY:
+		return ErrNotSupported
+	case syscall.EINVAL:
+		return ErrInvalid
+	}
+	return nil
+}
+
+// isQuotaNotEnabled 检查是否为该类型的配额统计未开启
+func isQuotaNotEnabled(err error) bool {
+	return errors.Is(err, ErrQuotaNotEnabled) || errors.Is(err, syscall.ESRCH)
+}
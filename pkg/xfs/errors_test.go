@@ -0,0 +1,47 @@
+package xfs
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyscallError_Is(t *testing.T) {
+	tests := []struct {
+		errno syscall.Errno
+		class error
+	}{
+		{syscall.ESRCH, ErrQuotaNotEnabled},
+		{syscall.ENOENT, ErrNoDquot},
+		{syscall.EPERM, ErrPermission},
+		{syscall.EACCES, ErrPermission},
+		{syscall.ENOSYS, ErrNotSupported},
+		{syscall.ENOTTY, ErrNotSupported},
+		{syscall.EINVAL, ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		err := error(&QuotaError{Op: "get", Path: "/mnt/xfs", Err: NewSyscallError("quotactl", tt.errno)})
+		assert.True(t, errors.Is(err, tt.class), tt.errno.Error())
+		assert.True(t, errors.Is(err, tt.errno), tt.errno.Error())
+		assert.False(t, errors.Is(err, ErrNotXFS), tt.errno.Error())
+	}
+
+	err := NewSyscallError("ioctl", syscall.EIO)
+	assert.Equal(t, "ioctl: input/output error", err.Error())
+	for _, class := range []error{ErrQuotaNotEnabled, ErrNoDquot, ErrPermission, ErrNotSupported, ErrInvalid} {
+		assert.False(t, errors.Is(err, class))
+	}
+}
+
+func TestQuotaManager_NotXFS(t *testing.T) {
+	manager := NewQuotaManager(WithMountInfoPath("testdata/mountinfo"))
+
+	_, err := manager.GetQuotaState("/proc")
+	assert.ErrorIs(t, err, ErrNotXFS)
+
+	err = manager.SetEnforcement(ProjectQuota, "/", true)
+	assert.ErrorIs(t, err, ErrQuotaNotEnabled)
+}
@@ -0,0 +1,78 @@
+package xfs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFsxattr_Layout(t *testing.T) {
+	assert.Equal(t, uintptr(28), unsafe.Sizeof(Fsxattr{}))
+}
+
+func TestWalkProjectTree(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "a", "b"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a", "file"), []byte("x"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a", "b", "file"), []byte("x"), 0644))
+	require.NoError(t, os.Symlink("/etc/passwd", filepath.Join(root, "link")))
+	require.NoError(t, syscall.Mkfifo(filepath.Join(root, "fifo"), 0644))
+
+	var applied []string
+	var dirs []string
+	var progress uint64
+	result, err := walkProjectTree(context.Background(), root, ProjectTreeOptions{
+		Progress: func(path string, processed uint64) { progress = processed },
+	}, func(path string, isDir bool) error {
+		rel, _ := filepath.Rel(root, path)
+		applied = append(applied, rel)
+		if isDir {
+			dirs = append(dirs, rel)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	sort.Strings(applied)
+	assert.Equal(t, []string{".", "a", "a/b", "a/b/file", "a/file"}, applied)
+	assert.ElementsMatch(t, []string{".", "a", "a/b"}, dirs)
+	assert.Equal(t, uint64(5), result.Processed)
+	assert.Equal(t, uint64(2), result.Skipped)
+	assert.Equal(t, uint64(5), progress)
+}
+
+func TestWalkProjectTree_Errors(t *testing.T) {
+	root := t.TempDir()
+	file := filepath.Join(root, "file")
+	require.NoError(t, os.WriteFile(file, []byte("x"), 0644))
+
+	_, err := walkProjectTree(context.Background(), file, ProjectTreeOptions{}, func(string, bool) error { return nil })
+	assert.Error(t, err)
+
+	_, err = walkProjectTree(context.Background(), filepath.Join(root, "missing"), ProjectTreeOptions{}, func(string, bool) error { return nil })
+	assert.True(t, os.IsNotExist(err))
+
+	result, err := walkProjectTree(context.Background(), root, ProjectTreeOptions{}, func(string, bool) error { return assert.AnError })
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Equal(t, uint64(0), result.Processed)
+}
+
+func TestWalkProjectTree_Cancel(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "a", "b"), 0755))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result, err := walkProjectTree(ctx, root, ProjectTreeOptions{}, func(path string, isDir bool) error {
+		cancel() // 处理第一个条目后取消
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, uint64(1), result.Processed)
+}
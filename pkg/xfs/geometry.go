@@ -0,0 +1,182 @@
+package xfs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"github.com/xfs-quota-kit/pkg/utils"
+)
+
+const (
+	// 文件系统几何信息ioctl命令
+	XFS_IOC_FSGEOMETRY_V4 = 0x8070587c // _IOR('X', 124, struct xfs_fsop_geom_v4)
+	XFS_IOC_FSGEOMETRY    = 0x8100587e // _IOR('X', 126, struct xfs_fsop_geom)，内核5.2起支持
+
+	// xfs_fsop_geom.flags 特性标志
+	XFS_FSOP_GEOM_FLAGS_ATTR     = 1 << 0  // 扩展属性
+	XFS_FSOP_GEOM_FLAGS_NLINK    = 1 << 1  // 32位链接数
+	XFS_FSOP_GEOM_FLAGS_QUOTA    = 1 << 2  // 配额
+	XFS_FSOP_GEOM_FLAGS_IALIGN   = 1 << 3  // inode对齐
+	XFS_FSOP_GEOM_FLAGS_DALIGN   = 1 << 4  // 条带对齐
+	XFS_FSOP_GEOM_FLAGS_SHARED   = 1 << 5  // 只读共享
+	XFS_FSOP_GEOM_FLAGS_EXTFLG   = 1 << 6  // 未写入extent标志
+	XFS_FSOP_GEOM_FLAGS_DIRV2    = 1 << 7  // 第2版目录
+	XFS_FSOP_GEOM_FLAGS_LOGV2    = 1 << 8  // 第2版日志
+	XFS_FSOP_GEOM_FLAGS_SECTOR   = 1 << 9  // 扇区大小不为512字节
+	XFS_FSOP_GEOM_FLAGS_ATTR2    = 1 << 10 // 内联属性改进
+	XFS_FSOP_GEOM_FLAGS_PROJID32 = 1 << 11 // 32位项目ID
+	XFS_FSOP_GEOM_FLAGS_DIRV2CI  = 1 << 12 // 大小写不敏感目录
+	XFS_FSOP_GEOM_FLAGS_LAZYSB   = 1 << 14 // 延迟超级块计数
+	XFS_FSOP_GEOM_FLAGS_V5SB     = 1 << 15 // 第5版超级块（元数据CRC）
+	XFS_FSOP_GEOM_FLAGS_FTYPE    = 1 << 16 // 目录项中的文件类型
+	XFS_FSOP_GEOM_FLAGS_FINOBT   = 1 << 17 // 空闲inode B+树
+	XFS_FSOP_GEOM_FLAGS_SPINODES = 1 << 18 // 稀疏inode块
+	XFS_FSOP_GEOM_FLAGS_RMAPBT   = 1 << 19 // 反向映射B+树
+	XFS_FSOP_GEOM_FLAGS_REFLINK  = 1 << 20 // reflink
+	XFS_FSOP_GEOM_FLAGS_BIGTIME  = 1 << 21 // 2038年以后的时间戳
+	XFS_FSOP_GEOM_FLAGS_INOBTCNT = 1 << 22 // inode B+树块计数
+	XFS_FSOP_GEOM_FLAGS_NREXT64  = 1 << 23 // 64位extent计数
+)
+
+// geometryFeatures 特性标志对应的名称，与 xfs_info 的输出一致
+var geometryFeatures = []struct {
+	flag uint32
+	name string
+}{
+	{XFS_FSOP_GEOM_FLAGS_V5SB, "crc"},
+	{XFS_FSOP_GEOM_FLAGS_FTYPE, "ftype"},
+	{XFS_FSOP_GEOM_FLAGS_FINOBT, "finobt"},
+	{XFS_FSOP_GEOM_FLAGS_SPINODES, "sparse"},
+	{XFS_FSOP_GEOM_FLAGS_RMAPBT, "rmapbt"},
+	{XFS_FSOP_GEOM_FLAGS_REFLINK, "reflink"},
+	{XFS_FSOP_GEOM_FLAGS_BIGTIME, "bigtime"},
+	{XFS_FSOP_GEOM_FLAGS_INOBTCNT, "inobtcount"},
+	{XFS_FSOP_GEOM_FLAGS_NREXT64, "nrext64"},
+	{XFS_FSOP_GEOM_FLAGS_ATTR2, "attr2"},
+	{XFS_FSOP_GEOM_FLAGS_PROJID32, "projid32bit"},
+	{XFS_FSOP_GEOM_FLAGS_LAZYSB, "lazy-count"},
+	{XFS_FSOP_GEOM_FLAGS_LOGV2, "log-v2"},
+	{XFS_FSOP_GEOM_FLAGS_DIRV2CI, "ascii-ci"},
+}
+
+// FsopGeom 对应内核 struct xfs_fsop_geom，总大小为256字节
+// 第4版结构为前112字节（到 LogSunit 为止）
+type FsopGeom struct {
+	BlockSize    uint32   // blocksize 数据块大小（字节）
+	RTExtSize    uint32   // rtextsize 实时extent大小（块）
+	AGBlocks     uint32   // agblocks 每个AG的块数
+	AGCount      uint32   // agcount AG数量
+	LogBlocks    uint32   // logblocks 日志块数
+	SectSize     uint32   // sectsize 数据扇区大小（字节）
+	InodeSize    uint32   // inodesize inode大小（字节）
+	IMaxPct      uint32   // imaxpct inode最多占用空间的百分比
+	DataBlocks   uint64   // datablocks 数据卷块数
+	RTBlocks     uint64   // rtblocks 实时卷块数
+	RTExtents    uint64   // rtextents 实时卷extent数
+	LogStart     uint64   // logstart 日志起始块，外部日志为0
+	UUID         [16]byte // uuid
+	Sunit        uint32   // sunit 条带单元（块）
+	Swidth       uint32   // swidth 条带宽度（块）
+	Version      int32    // version 结构版本
+	Flags        uint32   // flags XFS_FSOP_GEOM_FLAGS_* 特性标志
+	LogSectSize  uint32   // logsectsize 日志扇区大小（字节）
+	RTSectSize   uint32   // rtsectsize 实时卷扇区大小（字节）
+	DirBlockSize uint32   // dirblocksize 目录块大小（字节）
+	LogSunit     uint32   // logsunit 日志条带单元（字节）
+	Sick         uint32   // sick 不健康的元数据
+	Checked      uint32   // checked 已检查的元数据
+	Reserved     [17]uint64
+}
+
+// GetFsGeometry 通过 XFS_IOC_FSGEOMETRY 获取文件系统几何信息
+// 内核不支持第5版结构时退回到第4版，此时 Sick 和 Checked 为0
+func GetFsGeometry(path string) (*FsopGeom, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var geom FsopGeom
+	err = ioctl(file.Fd(), XFS_IOC_FSGEOMETRY, unsafe.Pointer(&geom))
+	if errors.Is(err, syscall.ENOTTY) || errors.Is(err, syscall.EINVAL) {
+		geom = FsopGeom{}
+		err = ioctl(file.Fd(), XFS_IOC_FSGEOMETRY_V4, unsafe.Pointer(&geom))
+	}
+	if err != nil {
+		return nil, &os.PathError{Op: "fsgeometry", Path: path, Err: err}
+	}
+	return &geom, nil
+}
+
+// Geometry XFS文件系统几何信息，大小均以字节为单位
+type Geometry struct {
+	UUID          string     `json:"uuid"`            // 文件系统UUID
+	BlockSize     uint32     `json:"block_size"`      // 数据块大小
+	SectorSize    uint32     `json:"sector_size"`     // 数据扇区大小
+	InodeSize     uint32     `json:"inode_size"`      // inode大小
+	DirBlockSize  uint32     `json:"dir_block_size"`  // 目录块大小
+	AGCount       uint32     `json:"ag_count"`        // AG数量
+	AGSize        utils.Size `json:"ag_size"`         // 每个AG的大小
+	DataSize      utils.Size `json:"data_size"`       // 数据卷大小
+	InodeMaxPct   uint32     `json:"inode_max_pct"`   // inode最多占用空间的百分比
+	StripeUnit    utils.Size `json:"stripe_unit"`     // 条带单元
+	StripeWidth   utils.Size `json:"stripe_width"`    // 条带宽度
+	LogSize       utils.Size `json:"log_size"`        // 日志大小
+	LogInternal   bool       `json:"log_internal"`    // 日志是否位于数据卷内
+	LogSectorSize uint32     `json:"log_sector_size"` // 日志扇区大小
+	LogStripeUnit utils.Size `json:"log_stripe_unit"` // 日志条带单元
+	RTSize        utils.Size `json:"rt_size"`         // 实时卷大小
+	RTExtentSize  utils.Size `json:"rt_extent_size"`  // 实时extent大小
+	RTExtents     uint64     `json:"rt_extents"`      // 实时卷extent数
+	Version       int32      `json:"version"`         // 几何结构版本
+	Flags         uint32     `json:"flags"`           // XFS_FSOP_GEOM_FLAGS_* 特性标志
+	Features      []string   `json:"features"`        // 特性名称，如 crc、reflink、bigtime
+}
+
+// NewGeometry 将 xfs_fsop_geom 转换为以字节为单位的几何信息
+func NewGeometry(geom *FsopGeom) *Geometry {
+	block := utils.Size(geom.BlockSize)
+	g := &Geometry{
+		UUID:          formatUUID(geom.UUID),
+		BlockSize:     geom.BlockSize,
+		SectorSize:    geom.SectSize,
+		InodeSize:     geom.InodeSize,
+		DirBlockSize:  geom.DirBlockSize,
+		AGCount:       geom.AGCount,
+		AGSize:        utils.Size(geom.AGBlocks) * block,
+		DataSize:      utils.Size(geom.DataBlocks) * block,
+		InodeMaxPct:   geom.IMaxPct,
+		StripeUnit:    utils.Size(geom.Sunit) * block,
+		StripeWidth:   utils.Size(geom.Swidth) * block,
+		LogSize:       utils.Size(geom.LogBlocks) * block,
+		LogInternal:   geom.LogStart != 0,
+		LogSectorSize: geom.LogSectSize,
+		LogStripeUnit: utils.Size(geom.LogSunit),
+		RTSize:        utils.Size(geom.RTBlocks) * block,
+		RTExtentSize:  utils.Size(geom.RTExtSize) * block,
+		RTExtents:     geom.RTExtents,
+		Version:       geom.Version,
+		Flags:         geom.Flags,
+		Features:      []string{},
+	}
+	for _, feature := range geometryFeatures {
+		if geom.Flags&feature.flag != 0 {
+			g.Features = append(g.Features, feature.name)
+		}
+	}
+	return g
+}
+
+// Has 检查是否具有指定的特性标志
+func (g *Geometry) Has(flag uint32) bool {
+	return g.Flags&flag != 0
+}
+
+// formatUUID 格式化为 8-4-4-4-12 形式
+func formatUUID(b [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
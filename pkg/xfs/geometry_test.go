@@ -0,0 +1,62 @@
+package xfs
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xfs-quota-kit/pkg/utils"
+)
+
+func TestFsopGeom_Layout(t *testing.T) {
+	var geom FsopGeom
+
+	// 必须与内核 struct xfs_fsop_geom 完全一致，前112字节为第4版结构
+	assert.Equal(t, uintptr(256), unsafe.Sizeof(geom))
+	assert.Equal(t, uintptr(32), unsafe.Offsetof(geom.DataBlocks))
+	assert.Equal(t, uintptr(64), unsafe.Offsetof(geom.UUID))
+	assert.Equal(t, uintptr(88), unsafe.Offsetof(geom.Version))
+	assert.Equal(t, uintptr(112), unsafe.Offsetof(geom.Sick))
+
+	// _IOR('X', nr, size)
+	assert.Equal(t, uintptr(2<<30|256<<16|'X'<<8|126), uintptr(XFS_IOC_FSGEOMETRY))
+	assert.Equal(t, uintptr(2<<30|112<<16|'X'<<8|124), uintptr(XFS_IOC_FSGEOMETRY_V4))
+}
+
+func TestNewGeometry(t *testing.T) {
+	geom := &FsopGeom{
+		BlockSize:   4096,
+		RTExtSize:   1,
+		AGBlocks:    655360,
+		AGCount:     4,
+		LogBlocks:   16384,
+		SectSize:    512,
+		InodeSize:   512,
+		IMaxPct:     25,
+		DataBlocks:  2621440,
+		LogStart:    1310724,
+		UUID:        [16]byte{0x8b, 0x2c, 0x1f, 0x3d, 0x4e, 0x5f, 0x40, 0x61, 0x82, 0x93, 0xa4, 0xb5, 0xc6, 0xd7, 0xe8, 0xf9},
+		Sunit:       16,
+		Swidth:      64,
+		Version:     5,
+		Flags:       XFS_FSOP_GEOM_FLAGS_V5SB | XFS_FSOP_GEOM_FLAGS_REFLINK | XFS_FSOP_GEOM_FLAGS_BIGTIME | XFS_FSOP_GEOM_FLAGS_QUOTA,
+		LogSectSize: 512,
+		LogSunit:    65536,
+	}
+
+	g := NewGeometry(geom)
+	assert.Equal(t, "8b2c1f3d-4e5f-4061-8293-a4b5c6d7e8f9", g.UUID)
+	assert.Equal(t, 10*utils.GiB, g.DataSize)
+	assert.Equal(t, uint32(4), g.AGCount)
+	assert.Equal(t, 2560*utils.MiB, g.AGSize)
+	assert.Equal(t, 64*utils.MiB, g.LogSize)
+	assert.True(t, g.LogInternal)
+	assert.Equal(t, 64*utils.KiB, g.StripeUnit)
+	assert.Equal(t, 256*utils.KiB, g.StripeWidth)
+	assert.Equal(t, 64*utils.KiB, g.LogStripeUnit)
+	assert.Equal(t, 4*utils.KiB, g.RTExtentSize)
+	assert.Equal(t, utils.Size(0), g.RTSize)
+	assert.Equal(t, []string{"crc", "reflink", "bigtime"}, g.Features)
+	assert.True(t, g.Has(XFS_FSOP_GEOM_FLAGS_QUOTA))
+	assert.False(t, g.Has(XFS_FSOP_GEOM_FLAGS_RMAPBT))
+}
@@ -0,0 +1,105 @@
+package xfs
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// GetGraceTimes 获取文件系统的默认宽限时间
+func (q *quotaManager) GetGraceTimes(ctx context.Context, quotaType QuotaType, path string) (*GraceTimes, error) {
+	state, err := q.GetQuotaState(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	typeState := state.Type(quotaType)
+	if typeState == nil {
+		return nil, &QuotaError{Op: "grace", Path: path, Err: fmt.Errorf("invalid quota type %d", quotaType)}
+	}
+
+	return &GraceTimes{
+		Block:   typeState.BlockGrace,
+		Inode:   typeState.InodeGrace,
+		RTBlock: typeState.RTBlockGrace,
+	}, nil
+}
+
+// SetGraceTimes 设置文件系统的默认宽限时间
+// XFS约定ID 0的dquot计时器保存默认宽限时间（以秒为单位的时长）
+func (q *quotaManager) SetGraceTimes(ctx context.Context, quotaType QuotaType, path string, grace GraceTimes) error {
+	dq := FsDiskQuota{
+		Version: FS_DQUOT_VERSION,
+		Flags:   quotaTypeFlag(quotaType),
+	}
+	if grace.Block > 0 {
+		dq.SetBlockTimer(int64(grace.Block / time.Second))
+	}
+	if grace.Inode > 0 {
+		dq.SetInodeTimer(int64(grace.Inode / time.Second))
+	}
+	if grace.RTBlock > 0 {
+		dq.SetRTBlockTimer(int64(grace.RTBlock / time.Second))
+	}
+	if dq.FieldMask&FS_DQ_TIMER_MASK == 0 {
+		return &QuotaError{Op: "grace", Path: path, Err: fmt.Errorf("no grace time specified")}
+	}
+
+	return q.setQlim(ctx, quotaType, 0, path, "grace", &dq)
+}
+
+// SetGraceTimers 设置单个ID的宽限到期时间，用于延长或重置宽限期
+func (q *quotaManager) SetGraceTimers(ctx context.Context, quotaType QuotaType, id uint32, path string, timers GraceTimers) error {
+	if id == 0 {
+		return &QuotaError{Op: "grace", Path: path, Err: fmt.Errorf("ID 0 holds the default grace times, use SetGraceTimes")}
+	}
+
+	dq := FsDiskQuota{
+		Version: FS_DQUOT_VERSION,
+		Flags:   quotaTypeFlag(quotaType),
+	}
+	if !timers.Block.IsZero() {
+		dq.SetBlockTimer(timers.Block.Unix())
+	}
+	if !timers.Inode.IsZero() {
+		dq.SetInodeTimer(timers.Inode.Unix())
+	}
+	if !timers.RTBlock.IsZero() {
+		dq.SetRTBlockTimer(timers.RTBlock.Unix())
+	}
+	if dq.FieldMask&FS_DQ_TIMER_MASK == 0 {
+		return &QuotaError{Op: "grace", Path: path, Err: fmt.Errorf("no grace timer specified")}
+	}
+
+	return q.setQlim(ctx, quotaType, id, path, "grace", &dq)
+}
+
+// ResetGrace 将超过软限制的ID的宽限期重新设置为从现在开始的默认宽限时间
+func (q *quotaManager) ResetGrace(ctx context.Context, quotaType QuotaType, id uint32, path string) error {
+	quota, err := q.GetQuota(ctx, quotaType, id, path)
+	if err != nil {
+		return err
+	}
+
+	grace, err := q.GetGraceTimes(ctx, quotaType, path)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var timers GraceTimers
+	if quota.BlockSoft > 0 && quota.BlockUsed > quota.BlockSoft {
+		timers.Block = now.Add(grace.Block)
+	}
+	if quota.InodeSoft > 0 && quota.InodeUsed > quota.InodeSoft {
+		timers.Inode = now.Add(grace.Inode)
+	}
+	if quota.RTBlockSoft > 0 && quota.RTBlockUsed > quota.RTBlockSoft {
+		timers.RTBlock = now.Add(grace.RTBlock)
+	}
+	if timers.Block.IsZero() && timers.Inode.IsZero() && timers.RTBlock.IsZero() {
+		return &QuotaError{Op: "grace", Path: path, Err: fmt.Errorf("%s ID %d is not over any soft limit", quotaType, id)}
+	}
+
+	return q.SetGraceTimers(ctx, quotaType, id, path, timers)
+}
@@ -0,0 +1,336 @@
+package xfs
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xfs-quota-kit/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// LimitRecord 导入文件中的一条配额限制
+type LimitRecord struct {
+	Line       int         `json:"line"` // 所在行号，从1开始
+	Type       QuotaType   `json:"type"`
+	ID         uint32      `json:"id"`
+	Name       string      `json:"name,omitempty"`       // 按名称指定时的名称
+	Filesystem string      `json:"filesystem,omitempty"` // xfs.filesystems 中的名称或挂载点，为空时由调用方决定
+	Limits     QuotaLimits `json:"limits"`
+}
+
+// LineError 导入文件中某一行的错误
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// ImportError 导入文件中所有无效行的错误
+type ImportError struct {
+	Errs []*LineError
+}
+
+// Add 记录某一行的错误
+func (e *ImportError) Add(line int, err error) {
+	e.Errs = append(e.Errs, &LineError{Line: line, Err: err})
+}
+
+// Err 按行号排序后返回，没有错误时返回nil
+func (e *ImportError) Err() error {
+	if len(e.Errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(e.Errs, func(i, j int) bool { return e.Errs[i].Line < e.Errs[j].Line })
+	return e
+}
+
+func (e *ImportError) Error() string {
+	lines := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		lines[i] = err.Error()
+	}
+	return fmt.Sprintf("%d errors:\n  %s", len(e.Errs), strings.Join(lines, "\n  "))
+}
+
+func (e *ImportError) Unwrap() []error {
+	errs := make([]error, len(e.Errs))
+	for i, err := range e.Errs {
+		errs[i] = err
+	}
+	return errs
+}
+
+// limitField 导入文件中的一个字段
+type limitField struct {
+	key   string
+	value string
+	line  int
+}
+
+// limitColumns 导入文件支持的字段
+var limitColumns = map[string]bool{
+	"type": true, "name": true, "id": true, "filesystem": true,
+	"block_soft": true, "block_hard": true, "inode_soft": true, "inode_hard": true,
+	"rt_block_soft": true, "rt_block_hard": true,
+}
+
+// ParseLimitsCSV 解析CSV格式的配额限制，第一行为列名，以 # 开头的行为注释
+// 列名见 ParseLimits。检查所有行后一起返回错误，错误类型为 *ImportError
+func ParseLimitsCSV(r io.Reader, names NameResolver) ([]LimitRecord, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("empty CSV file")
+	}
+	if err != nil {
+		return nil, err
+	}
+	headerLine, _ := reader.FieldPos(0)
+
+	errs := &ImportError{}
+	for i, column := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(column))
+		if !limitColumns[header[i]] {
+			errs.Add(headerLine, fmt.Errorf("unknown column %q", column))
+		}
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	var records []LimitRecord
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			errs.Add(parseErr.StartLine, parseErr.Err)
+			if parseErr.Err == csv.ErrFieldCount {
+				continue
+			}
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		fields := make([]limitField, len(row))
+		for i, value := range row {
+			fields[i] = limitField{key: header[i], value: strings.TrimSpace(value), line: line}
+		}
+		if record, ok := parseLimitRecord(line, fields, names, errs); ok {
+			records = append(records, record)
+		}
+	}
+	return records, errs.Err()
+}
+
+// ParseLimitsYAML 解析YAML格式的配额限制，文件为列表或包含 quotas 列表的映射，每项的键见 ParseLimits
+// 检查所有项后一起返回错误，错误类型为 *ImportError
+func ParseLimitsYAML(r io.Reader, names NameResolver) ([]LimitRecord, error) {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("empty YAML file")
+		}
+		return nil, err
+	}
+
+	list := doc.Content[0]
+	if list.Kind == yaml.MappingNode {
+		var quotas *yaml.Node
+		for i := 0; i+1 < len(list.Content); i += 2 {
+			if list.Content[i].Value == "quotas" {
+				quotas = list.Content[i+1]
+			}
+		}
+		if quotas == nil {
+			return nil, &ImportError{Errs: []*LineError{{Line: list.Line, Err: fmt.Errorf("missing quotas list")}}}
+		}
+		list = quotas
+	}
+	if list.Kind != yaml.SequenceNode {
+		return nil, &ImportError{Errs: []*LineError{{Line: list.Line, Err: fmt.Errorf("expected a list of quotas")}}}
+	}
+
+	errs := &ImportError{}
+	var records []LimitRecord
+	for _, item := range list.Content {
+		if item.Kind != yaml.MappingNode {
+			errs.Add(item.Line, fmt.Errorf("expected a mapping"))
+			continue
+		}
+
+		var fields []limitField
+		valid := true
+		for i := 0; i+1 < len(item.Content); i += 2 {
+			key, value := item.Content[i], item.Content[i+1]
+			switch {
+			case !limitColumns[key.Value]:
+				errs.Add(key.Line, fmt.Errorf("unknown key %q", key.Value))
+				valid = false
+			case value.Kind != yaml.ScalarNode:
+				errs.Add(value.Line, fmt.Errorf("%s: expected a single value", key.Value))
+				valid = false
+			case value.Tag == "!!null":
+				fields = append(fields, limitField{key: key.Value, line: value.Line})
+			default:
+				fields = append(fields, limitField{key: key.Value, value: strings.TrimSpace(value.Value), line: value.Line})
+			}
+		}
+		if !valid {
+			continue
+		}
+		if record, ok := parseLimitRecord(item.Line, fields, names, errs); ok {
+			records = append(records, record)
+		}
+	}
+	return records, errs.Err()
+}
+
+// ParseLimits 按格式（csv 或 yaml）解析配额限制
+// 字段: type (user/group/project)、name（名称或数字ID）或 id、filesystem，
+// 以及 block_soft/block_hard/rt_block_soft/rt_block_hard（需要单位，如 2GiB）和 inode_soft/inode_hard。
+// 缺少或为空的限制保持不变，只有给出的限制记录在 Limits.Fields 中，0表示取消该限制
+func ParseLimits(r io.Reader, format string, names NameResolver) ([]LimitRecord, error) {
+	switch strings.ToLower(format) {
+	case "csv":
+		return ParseLimitsCSV(r, names)
+	case "yaml", "yml":
+		return ParseLimitsYAML(r, names)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q (csv, yaml)", format)
+	}
+}
+
+// parseLimitRecord 检查一行中的所有字段，错误记录到errs中
+func parseLimitRecord(line int, fields []limitField, names NameResolver, errs *ImportError) (LimitRecord, bool) {
+	record := LimitRecord{Line: line}
+	before := len(errs.Errs)
+
+	values := make(map[string]limitField)
+	for _, field := range fields {
+		values[field.key] = field
+	}
+
+	typeField, ok := values["type"]
+	switch {
+	case !ok || typeField.value == "":
+		errs.Add(line, fmt.Errorf("type is required"))
+	default:
+		quotaType, err := ParseQuotaType(typeField.value)
+		if err != nil {
+			errs.Add(typeField.line, err)
+		}
+		record.Type = quotaType
+	}
+
+	name, id := values["name"], values["id"]
+	switch {
+	case name.value != "" && id.value != "":
+		errs.Add(line, fmt.Errorf("specify either name or id, not both"))
+	case id.value != "":
+		n, err := strconv.ParseUint(id.value, 10, 32)
+		if err != nil {
+			errs.Add(id.line, fmt.Errorf("invalid id %q", id.value))
+		}
+		record.ID = uint32(n)
+	case name.value != "":
+		if record.Type != 0 {
+			n, err := ResolveID(names, record.Type, name.value)
+			if err != nil {
+				errs.Add(name.line, err)
+			}
+			record.ID = n
+		}
+		if _, err := strconv.ParseUint(name.value, 10, 32); err != nil {
+			record.Name = name.value
+		}
+	default:
+		errs.Add(line, fmt.Errorf("name or id is required"))
+	}
+
+	record.Filesystem = values["filesystem"].value
+
+	sizes := []struct {
+		key   string
+		field LimitFields
+		value *utils.Size
+	}{
+		{"block_soft", LimitBlockSoft, &record.Limits.BlockSoft},
+		{"block_hard", LimitBlockHard, &record.Limits.BlockHard},
+		{"rt_block_soft", LimitRTBlockSoft, &record.Limits.RTBlockSoft},
+		{"rt_block_hard", LimitRTBlockHard, &record.Limits.RTBlockHard},
+	}
+	for _, size := range sizes {
+		field := values[size.key]
+		if field.value == "" {
+			continue
+		}
+		s, err := utils.ParseSize(field.value)
+		if err != nil {
+			errs.Add(field.line, fmt.Errorf("%s: %w", size.key, err))
+		}
+		*size.value = s
+		record.Limits.Fields |= size.field
+	}
+
+	counts := []struct {
+		key   string
+		field LimitFields
+		value *uint64
+	}{
+		{"inode_soft", LimitInodeSoft, &record.Limits.InodeSoft},
+		{"inode_hard", LimitInodeHard, &record.Limits.InodeHard},
+	}
+	for _, count := range counts {
+		field := values[count.key]
+		if field.value == "" {
+			continue
+		}
+		n, err := strconv.ParseUint(field.value, 10, 64)
+		if err != nil {
+			errs.Add(field.line, fmt.Errorf("%s: invalid count %q", count.key, field.value))
+		}
+		*count.value = n
+		record.Limits.Fields |= count.field
+	}
+
+	// Fields 为0表示设置全部限制，没有给出任何限制的行不能导入
+	limits := record.Limits
+	if limits.Fields == 0 {
+		errs.Add(line, fmt.Errorf("no limits given; use 0 to remove a limit"))
+	}
+
+	// 只检查同时给出的软硬限制，硬限制为0表示不限制，此时软限制可以为任意值
+	both := func(soft, hard LimitFields) bool { return limits.Fields&soft != 0 && limits.Fields&hard != 0 }
+	if both(LimitBlockSoft, LimitBlockHard) && limits.BlockHard > 0 && limits.BlockSoft > limits.BlockHard {
+		errs.Add(line, fmt.Errorf("block_soft %s exceeds block_hard %s", limits.BlockSoft, limits.BlockHard))
+	}
+	if both(LimitInodeSoft, LimitInodeHard) && limits.InodeHard > 0 && limits.InodeSoft > limits.InodeHard {
+		errs.Add(line, fmt.Errorf("inode_soft %d exceeds inode_hard %d", limits.InodeSoft, limits.InodeHard))
+	}
+	if both(LimitRTBlockSoft, LimitRTBlockHard) && limits.RTBlockHard > 0 && limits.RTBlockSoft > limits.RTBlockHard {
+		errs.Add(line, fmt.Errorf("rt_block_soft %s exceeds rt_block_hard %s", limits.RTBlockSoft, limits.RTBlockHard))
+	}
+
+	return record, len(errs.Errs) == before
+}
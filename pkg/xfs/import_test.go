@@ -0,0 +1,106 @@
+package xfs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xfs-quota-kit/pkg/utils"
+)
+
+func newImportTestResolver(t *testing.T) NameResolver {
+	dir := t.TempDir()
+	passwd := filepath.Join(dir, "passwd")
+	require.NoError(t, os.WriteFile(passwd, []byte("alice:x:1000:1000:::\nbob:x:1001:1001:::\n"), 0644))
+	return NewFileResolver(passwd, passwd, NewProjectStore(filepath.Join(dir, "projects"), filepath.Join(dir, "projid")))
+}
+
+func TestParseLimitsCSV(t *testing.T) {
+	names := newImportTestResolver(t)
+	input := `type,name,filesystem,block_soft,block_hard,inode_soft,inode_hard
+# 本学期新生
+user,alice,data,1GiB,2GiB,,100000
+group, 1001 ,/home,,500MB,0,0
+`
+	records, err := ParseLimitsCSV(strings.NewReader(input), names)
+	require.NoError(t, err)
+	assert.Equal(t, []LimitRecord{
+		{Line: 3, Type: UserQuota, ID: 1000, Name: "alice", Filesystem: "data",
+			Limits: QuotaLimits{BlockSoft: utils.GiB, BlockHard: 2 * utils.GiB, InodeHard: 100000,
+				Fields: LimitBlockSoft | LimitBlockHard | LimitInodeHard}},
+		{Line: 4, Type: GroupQuota, ID: 1001, Filesystem: "/home",
+			Limits: QuotaLimits{BlockHard: 500 * utils.MB, Fields: LimitBlockHard | LimitInodeSoft | LimitInodeHard}},
+	}, records)
+}
+
+func TestParseLimitsCSV_Errors(t *testing.T) {
+	names := newImportTestResolver(t)
+	input := `type,name,block_soft,block_hard,inode_hard
+user,alice,1GiB,2GiB,100
+staff,bob,,,
+user,carol,,,
+user,bob,2048,,
+user,bob,2GiB,1GiB,x
+user,bob
+user,alice,,3GiB,
+`
+	_, err := ParseLimitsCSV(strings.NewReader(input), names)
+	var importErr *ImportError
+	require.True(t, errors.As(err, &importErr))
+
+	var lines []int
+	for _, e := range importErr.Errs {
+		lines = append(lines, e.Line)
+	}
+	assert.Equal(t, []int{3, 3, 4, 4, 5, 6, 6, 7}, lines)
+	assert.True(t, errors.Is(err, ErrUnknownName))
+	assert.Contains(t, err.Error(), "line 3: invalid quota type: staff")
+	assert.Contains(t, err.Error(), "line 5: block_soft: size \"2048\" needs a unit")
+	assert.Contains(t, err.Error(), "line 6: block_soft 2GiB exceeds block_hard 1GiB")
+	assert.Contains(t, err.Error(), "line 4: no limits given")
+
+	_, err = ParseLimitsCSV(strings.NewReader("type,user,block_hard\n"), names)
+	assert.ErrorContains(t, err, `line 1: unknown column "user"`)
+}
+
+func TestParseLimitsYAML(t *testing.T) {
+	names := newImportTestResolver(t)
+	input := `quotas:
+  - type: user
+    name: alice
+    filesystem: data
+    block_hard: 2GiB
+    inode_hard: 100000
+  - type: project
+    id: 42
+    block_soft:
+    block_hard: 10GiB
+  - type: user
+    name: mallory
+    block_hard: 2
+    owner: hr
+`
+	_, err := ParseLimitsYAML(strings.NewReader(input), names)
+	var importErr *ImportError
+	require.True(t, errors.As(err, &importErr))
+	require.Len(t, importErr.Errs, 1)
+	assert.Equal(t, 14, importErr.Errs[0].Line)
+	assert.ErrorContains(t, err, `unknown key "owner"`)
+
+	input = strings.Join(strings.Split(input, "\n")[:10], "\n")
+	records, err := ParseLimits(strings.NewReader(input), "yml", names)
+	require.NoError(t, err)
+	assert.Equal(t, []LimitRecord{
+		{Line: 2, Type: UserQuota, ID: 1000, Name: "alice", Filesystem: "data",
+			Limits: QuotaLimits{BlockHard: 2 * utils.GiB, InodeHard: 100000, Fields: LimitBlockHard | LimitInodeHard}},
+		{Line: 7, Type: ProjectQuota, ID: 42, Limits: QuotaLimits{BlockHard: 10 * utils.GiB, Fields: LimitBlockHard}},
+	}, records)
+
+	// 不是列表时报告位置
+	_, err = ParseLimitsYAML(strings.NewReader("type: user\n"), names)
+	assert.ErrorContains(t, err, "line 1: missing quotas list")
+}
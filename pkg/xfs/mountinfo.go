@@ -0,0 +1,315 @@
+package xfs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultMountInfoPath 默认挂载信息文件
+const DefaultMountInfoPath = "/proc/self/mountinfo"
+
+// MountInfo /proc/self/mountinfo 中的一条挂载记录
+type MountInfo struct {
+	ID           int      `json:"id"`            // 挂载ID
+	ParentID     int      `json:"parent_id"`     // 父挂载ID
+	Major        uint32   `json:"major"`         // 设备主设备号
+	Minor        uint32   `json:"minor"`         // 设备次设备号
+	Root         string   `json:"root"`          // 挂载源在文件系统内的根路径（bind mount时非"/"）
+	MountPoint   string   `json:"mount_point"`   // 挂载点
+	Options      []string `json:"options"`       // 挂载点选项
+	FSType       string   `json:"fs_type"`       // 文件系统类型
+	Source       string   `json:"source"`        // 挂载源设备
+	SuperOptions []string `json:"super_options"` // 超级块选项
+}
+
+// IsXFS 判断是否为XFS挂载
+func (m *MountInfo) IsXFS() bool {
+	return m.FSType == "xfs"
+}
+
+// IsBindMount 判断是否为bind mount（挂载的不是文件系统根目录）
+func (m *MountInfo) IsBindMount() bool {
+	return m.Root != "/"
+}
+
+// Device 获取可用于quotactl的块设备路径
+func (m *MountInfo) Device() string {
+	// /dev/root 等伪名称以及非路径形式的源无法直接打开，退回到 /dev/block/MAJ:MIN
+	if !strings.HasPrefix(m.Source, "/") || m.Source == "/dev/root" {
+		return fmt.Sprintf("/dev/block/%d:%d", m.Major, m.Minor)
+	}
+	return m.Source
+}
+
+// QuotaOptions 解析挂载选项中的配额设置
+func (m *MountInfo) QuotaOptions() QuotaMountOptions {
+	var opts QuotaMountOptions
+	for _, list := range [][]string{m.Options, m.SuperOptions} {
+		for _, opt := range list {
+			opts.apply(opt)
+		}
+	}
+	return opts
+}
+
+// QuotaMountOptions 挂载选项中的配额状态
+type QuotaMountOptions struct {
+	UserAccounting    bool `json:"user_accounting"`
+	UserEnforced      bool `json:"user_enforced"`
+	GroupAccounting   bool `json:"group_accounting"`
+	GroupEnforced     bool `json:"group_enforced"`
+	ProjectAccounting bool `json:"project_accounting"`
+	ProjectEnforced   bool `json:"project_enforced"`
+}
+
+// apply 应用单个挂载选项
+func (o *QuotaMountOptions) apply(opt string) {
+	switch opt {
+	case "usrquota", "uquota", "quota":
+		o.UserAccounting, o.UserEnforced = true, true
+	case "uqnoenforce", "qnoenforce":
+		o.UserAccounting = true
+	case "grpquota", "gquota":
+		o.GroupAccounting, o.GroupEnforced = true, true
+	case "gqnoenforce":
+		o.GroupAccounting = true
+	case "prjquota", "pquota":
+		o.ProjectAccounting, o.ProjectEnforced = true, true
+	case "pqnoenforce":
+		o.ProjectAccounting = true
+	}
+}
+
+// Accounting 判断指定类型的配额统计是否开启
+func (o QuotaMountOptions) Accounting(quotaType QuotaType) bool {
+	switch quotaType {
+	case UserQuota:
+		return o.UserAccounting
+	case GroupQuota:
+		return o.GroupAccounting
+	case ProjectQuota:
+		return o.ProjectAccounting
+	default:
+		return false
+	}
+}
+
+// Enforced 判断指定类型的配额限制是否生效
+func (o QuotaMountOptions) Enforced(quotaType QuotaType) bool {
+	switch quotaType {
+	case UserQuota:
+		return o.UserEnforced
+	case GroupQuota:
+		return o.GroupEnforced
+	case ProjectQuota:
+		return o.ProjectEnforced
+	default:
+		return false
+	}
+}
+
+// Any 判断是否开启了任意类型的配额统计
+func (o QuotaMountOptions) Any() bool {
+	return o.UserAccounting || o.GroupAccounting || o.ProjectAccounting
+}
+
+// String 以挂载选项形式输出
+func (o QuotaMountOptions) String() string {
+	var opts []string
+	for _, qt := range []QuotaType{UserQuota, GroupQuota, ProjectQuota} {
+		prefix := qt.String()[:1]
+		switch {
+		case o.Enforced(qt):
+			opts = append(opts, prefix+"quota")
+		case o.Accounting(qt):
+			opts = append(opts, prefix+"qnoenforce")
+		}
+	}
+	if len(opts) == 0 {
+		return "none"
+	}
+	return strings.Join(opts, ",")
+}
+
+// MountTable 挂载表
+type MountTable struct {
+	Mounts []MountInfo
+}
+
+// LoadMountTable 从mountinfo文件加载挂载表
+func LoadMountTable(path string) (*MountTable, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	mounts, err := ParseMountInfo(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return &MountTable{Mounts: mounts}, nil
+}
+
+// ParseMountInfo 解析mountinfo格式内容
+func ParseMountInfo(r io.Reader) ([]MountInfo, error) {
+	var mounts []MountInfo
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		mount, err := parseMountInfoLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		mounts = append(mounts, mount)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return mounts, nil
+}
+
+// parseMountInfoLine 解析单行mountinfo
+// 格式: ID PARENT MAJ:MIN ROOT MOUNTPOINT OPTIONS [OPTIONAL...] - FSTYPE SOURCE SUPEROPTIONS
+func parseMountInfoLine(line string) (MountInfo, error) {
+	var mount MountInfo
+
+	fields := strings.Fields(line)
+	sep := -1
+	for i := 6; i < len(fields); i++ {
+		if fields[i] == "-" {
+			sep = i
+			break
+		}
+	}
+	if len(fields) < 6 || sep < 0 || len(fields) < sep+3 {
+		return mount, fmt.Errorf("malformed mountinfo entry: %q", line)
+	}
+
+	var err error
+	if mount.ID, err = strconv.Atoi(fields[0]); err != nil {
+		return mount, fmt.Errorf("invalid mount id %q", fields[0])
+	}
+	if mount.ParentID, err = strconv.Atoi(fields[1]); err != nil {
+		return mount, fmt.Errorf("invalid parent id %q", fields[1])
+	}
+
+	majMin := strings.SplitN(fields[2], ":", 2)
+	if len(majMin) != 2 {
+		return mount, fmt.Errorf("invalid device number %q", fields[2])
+	}
+	major, err := strconv.ParseUint(majMin[0], 10, 32)
+	if err != nil {
+		return mount, fmt.Errorf("invalid device number %q", fields[2])
+	}
+	minor, err := strconv.ParseUint(majMin[1], 10, 32)
+	if err != nil {
+		return mount, fmt.Errorf("invalid device number %q", fields[2])
+	}
+	mount.Major, mount.Minor = uint32(major), uint32(minor)
+
+	mount.Root = unescapeMountPath(fields[3])
+	mount.MountPoint = unescapeMountPath(fields[4])
+	mount.Options = strings.Split(fields[5], ",")
+	mount.FSType = fields[sep+1]
+	mount.Source = unescapeMountPath(fields[sep+2])
+	if len(fields) > sep+3 {
+		mount.SuperOptions = strings.Split(fields[sep+3], ",")
+	}
+
+	return mount, nil
+}
+
+// unescapeMountPath 还原内核以八进制转义的字符（如 \040 表示空格）
+func unescapeMountPath(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && isOctal(s[i+1]) && isOctal(s[i+2]) && isOctal(s[i+3]) {
+			b.WriteByte((s[i+1]-'0')<<6 | (s[i+2]-'0')<<3 | (s[i+3] - '0'))
+			i += 3
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func isOctal(c byte) bool {
+	return c >= '0' && c <= '7'
+}
+
+// FindMount 查找包含指定绝对路径的挂载点（最长前缀匹配）
+// 同一挂载点被多次挂载时，以最后出现（最上层）的记录为准
+func (t *MountTable) FindMount(path string) (*MountInfo, error) {
+	if !filepath.IsAbs(path) {
+		return nil, fmt.Errorf("path %s is not absolute", path)
+	}
+	path = filepath.Clean(path)
+
+	var found *MountInfo
+	for i := range t.Mounts {
+		mount := &t.Mounts[i]
+		if !pathHasPrefix(path, mount.MountPoint) {
+			continue
+		}
+		if found == nil || len(mount.MountPoint) >= len(found.MountPoint) {
+			found = mount
+		}
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("no mount point found for %s", path)
+	}
+	return found, nil
+}
+
+// FindByMountPoint 按挂载点精确查找
+func (t *MountTable) FindByMountPoint(mountPoint string) (*MountInfo, error) {
+	mountPoint = filepath.Clean(mountPoint)
+	for i := len(t.Mounts) - 1; i >= 0; i-- {
+		if t.Mounts[i].MountPoint == mountPoint {
+			return &t.Mounts[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%s is not a mount point", mountPoint)
+}
+
+// pathHasPrefix 按路径组件判断前缀关系，避免 /mnt/xfs2 匹配 /mnt/xfs
+func pathHasPrefix(path, prefix string) bool {
+	if prefix == "/" {
+		return true
+	}
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || path[len(prefix)] == '/'
+}
+
+// ResolvePath 将路径转换为解析符号链接后的绝对路径
+func ResolvePath(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(absPath)
+}
@@ -0,0 +1,129 @@
+package xfs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadFixtureMountTable(t *testing.T) *MountTable {
+	table, err := LoadMountTable("testdata/mountinfo")
+	require.NoError(t, err)
+	return table
+}
+
+func TestParseMountInfo(t *testing.T) {
+	table := loadFixtureMountTable(t)
+	require.Len(t, table.Mounts, 11)
+
+	mount := table.Mounts[3]
+	assert.Equal(t, 40, mount.ID)
+	assert.Equal(t, 22, mount.ParentID)
+	assert.Equal(t, uint32(8), mount.Major)
+	assert.Equal(t, uint32(17), mount.Minor)
+	assert.Equal(t, "/mnt/xfs", mount.MountPoint)
+	assert.Equal(t, "xfs", mount.FSType)
+	assert.Equal(t, "/dev/sdb1", mount.Source)
+	assert.Contains(t, mount.SuperOptions, "prjquota")
+}
+
+func TestParseMountInfo_Malformed(t *testing.T) {
+	_, err := ParseMountInfo(strings.NewReader("40 22 8:17 / /mnt/xfs rw shared:30 xfs /dev/sdb1 rw\n"))
+	assert.Error(t, err)
+
+	_, err = ParseMountInfo(strings.NewReader("x 22 8:17 / /mnt/xfs rw - xfs /dev/sdb1 rw\n"))
+	assert.Error(t, err)
+}
+
+func TestMountTable_FindMount(t *testing.T) {
+	table := loadFixtureMountTable(t)
+
+	tests := []struct {
+		path       string
+		mountPoint string
+		device     string
+	}{
+		{"/mnt/xfs", "/mnt/xfs", "/dev/sdb1"},
+		{"/mnt/xfs/projects/web/index.html", "/mnt/xfs", "/dev/sdb1"},
+		{"/mnt/xfs2/data", "/mnt/xfs2", "/dev/sdc1"},
+		{"/mnt/xfs/tmp/file", "/mnt/xfs/tmp", "/dev/block/0:45"},
+		{"/mnt/xfsx", "/", "/dev/mapper/rhel-root"},
+		{"/srv/web/static", "/srv/web", "/dev/sdb1"},
+		{"/data/my volume/clip.mp4", "/data/my volume", "/dev/mapper/vg--data-lv_media"},
+		{"/home/alice", "/home", "/dev/nvme0n1p2"},
+		{"/boot/grub", "/boot", "/dev/block/8:1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			mount, err := table.FindMount(tt.path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.mountPoint, mount.MountPoint)
+			assert.Equal(t, tt.device, mount.Device())
+		})
+	}
+
+	_, err := table.FindMount("relative/path")
+	assert.Error(t, err)
+}
+
+func TestMountInfo_BindMount(t *testing.T) {
+	table := loadFixtureMountTable(t)
+
+	mount, err := table.FindByMountPoint("/srv/web")
+	require.NoError(t, err)
+	assert.True(t, mount.IsBindMount())
+	assert.Equal(t, "/projects/web", mount.Root)
+
+	mount, err = table.FindByMountPoint("/mnt/xfs")
+	require.NoError(t, err)
+	assert.False(t, mount.IsBindMount())
+
+	_, err = table.FindByMountPoint("/mnt/none")
+	assert.Error(t, err)
+}
+
+func TestMountInfo_QuotaOptions(t *testing.T) {
+	table := loadFixtureMountTable(t)
+
+	mount, err := table.FindByMountPoint("/mnt/xfs")
+	require.NoError(t, err)
+	opts := mount.QuotaOptions()
+	assert.True(t, opts.Enforced(UserQuota))
+	assert.False(t, opts.Accounting(GroupQuota))
+	assert.True(t, opts.Enforced(ProjectQuota))
+	assert.Equal(t, "uquota,pquota", opts.String())
+
+	mount, err = table.FindByMountPoint("/mnt/xfs2")
+	require.NoError(t, err)
+	opts = mount.QuotaOptions()
+	assert.True(t, opts.Accounting(ProjectQuota))
+	assert.False(t, opts.Enforced(ProjectQuota))
+	assert.Equal(t, "uqnoenforce,gqnoenforce,pqnoenforce", opts.String())
+
+	mount, err = table.FindByMountPoint("/")
+	require.NoError(t, err)
+	assert.False(t, mount.QuotaOptions().Any())
+	assert.Equal(t, "none", mount.QuotaOptions().String())
+}
+
+func TestUnescapeMountPath(t *testing.T) {
+	assert.Equal(t, "/mnt/my dir", unescapeMountPath(`/mnt/my\040dir`))
+	assert.Equal(t, "/mnt/a\tb", unescapeMountPath(`/mnt/a\011b`))
+	assert.Equal(t, `/mnt/back\slash`, unescapeMountPath(`/mnt/back\134slash`))
+	assert.Equal(t, `/mnt/x\9`, unescapeMountPath(`/mnt/x\9`))
+}
+
+func TestQuotaManager_FindMount(t *testing.T) {
+	q := &quotaManager{mountInfoPath: "testdata/mountinfo"}
+
+	// 临时目录通常不在fixture中的挂载点下，应落到根挂载
+	mount, err := q.findMount(t.TempDir())
+	require.NoError(t, err)
+	assert.NotEmpty(t, mount.Device())
+
+	_, err = q.findMount("/does/not/exist")
+	assert.Error(t, err)
+}
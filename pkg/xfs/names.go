@@ -0,0 +1,259 @@
+package xfs
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 名称解析方式
+const (
+	// NameResolverFiles 读取 /etc/passwd、/etc/group 和 /etc/projid
+	NameResolverFiles = "files"
+	// NameResolverSystem 通过系统的NSS（os/user）解析用户和组，支持LDAP等来源
+	NameResolverSystem = "system"
+)
+
+// ErrUnknownName 名称或ID无法解析
+var ErrUnknownName = errors.New("unknown name")
+
+// NameResolver 在用户/组/项目名称和ID之间转换
+type NameResolver interface {
+	// LookupID 将名称解析为ID
+	LookupID(quotaType QuotaType, name string) (uint32, error)
+	// LookupName 将ID解析为名称
+	LookupName(quotaType QuotaType, id uint32) (string, error)
+}
+
+// ResolveID 解析名称或数字ID，与 xfs_quota 一致，纯数字直接作为ID
+func ResolveID(r NameResolver, quotaType QuotaType, nameOrID string) (uint32, error) {
+	if id, err := strconv.ParseUint(nameOrID, 10, 32); err == nil {
+		return uint32(id), nil
+	}
+	return r.LookupID(quotaType, nameOrID)
+}
+
+// fileResolver 从 passwd/group 格式文件和项目文件解析名称
+// 文件内容按修改时间缓存，遍历大量ID时不会重复读取
+type fileResolver struct {
+	passwdFile string
+	groupFile  string
+	projects   *ProjectStore
+
+	mu    sync.Mutex
+	cache map[string]*nameTable
+}
+
+// nameTable 名称与ID的双向映射
+type nameTable struct {
+	modTime time.Time
+	size    int64
+	byName  map[string]uint32
+	byID    map[uint32]string
+}
+
+// NewFileResolver 创建基于文件的名称解析器
+func NewFileResolver(passwdFile, groupFile string, projects *ProjectStore) NameResolver {
+	return &fileResolver{
+		passwdFile: passwdFile,
+		groupFile:  groupFile,
+		projects:   projects,
+		cache:      make(map[string]*nameTable),
+	}
+}
+
+func (r *fileResolver) LookupID(quotaType QuotaType, name string) (uint32, error) {
+	table, err := r.table(quotaType)
+	if err != nil {
+		return 0, err
+	}
+	id, ok := table.byName[name]
+	if !ok {
+		return 0, fmt.Errorf("%s %q: %w", quotaType, name, ErrUnknownName)
+	}
+	return id, nil
+}
+
+func (r *fileResolver) LookupName(quotaType QuotaType, id uint32) (string, error) {
+	table, err := r.table(quotaType)
+	if err != nil {
+		return "", err
+	}
+	name, ok := table.byID[id]
+	if !ok {
+		return "", fmt.Errorf("%s ID %d: %w", quotaType, id, ErrUnknownName)
+	}
+	return name, nil
+}
+
+// table 获取配额类型对应的名称表
+func (r *fileResolver) table(quotaType QuotaType) (*nameTable, error) {
+	var path string
+	switch quotaType {
+	case UserQuota:
+		path = r.passwdFile
+	case GroupQuota:
+		path = r.groupFile
+	case ProjectQuota:
+		path = r.projects.ProjidFile
+	default:
+		return nil, fmt.Errorf("invalid quota type %d", quotaType)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if quotaType == ProjectQuota && os.IsNotExist(err) {
+			return &nameTable{}, nil // 尚未创建任何项目
+		}
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cached, ok := r.cache[path]; ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached, nil
+	}
+
+	var entries []nameEntry
+	if quotaType == ProjectQuota {
+		projects, err := r.projects.List()
+		if err != nil {
+			return nil, err
+		}
+		for _, project := range projects {
+			entries = append(entries, nameEntry{name: project.Name, id: project.ID})
+		}
+	} else if entries, err = readNameFile(path); err != nil {
+		return nil, err
+	}
+
+	table := &nameTable{
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		byName:  make(map[string]uint32, len(entries)),
+		byID:    make(map[uint32]string, len(entries)),
+	}
+	for _, entry := range entries {
+		if _, exists := table.byName[entry.name]; !exists {
+			table.byName[entry.name] = entry.id
+		}
+		// 多个名称共用一个ID时以第一个为准，与 getpwuid 一致
+		if _, exists := table.byID[entry.id]; !exists {
+			table.byID[entry.id] = entry.name
+		}
+	}
+	r.cache[path] = table
+	return table, nil
+}
+
+// systemResolver 通过 os/user 解析用户和组，项目仍使用 /etc/projid
+// 使用cgo构建时会经过NSS，因此支持LDAP、SSSD等来源
+type systemResolver struct {
+	projects *fileResolver
+}
+
+// NewSystemResolver 创建基于系统用户数据库的名称解析器
+func NewSystemResolver(projects *ProjectStore) NameResolver {
+	return &systemResolver{projects: NewFileResolver("", "", projects).(*fileResolver)}
+}
+
+func (r *systemResolver) LookupID(quotaType QuotaType, name string) (uint32, error) {
+	var idStr string
+	switch quotaType {
+	case UserQuota:
+		u, err := user.Lookup(name)
+		if err != nil {
+			return 0, lookupError(quotaType, name, err)
+		}
+		idStr = u.Uid
+	case GroupQuota:
+		g, err := user.LookupGroup(name)
+		if err != nil {
+			return 0, lookupError(quotaType, name, err)
+		}
+		idStr = g.Gid
+	default:
+		return r.projects.LookupID(quotaType, name)
+	}
+
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%s %q has invalid ID %q", quotaType, name, idStr)
+	}
+	return uint32(id), nil
+}
+
+func (r *systemResolver) LookupName(quotaType QuotaType, id uint32) (string, error) {
+	idStr := strconv.FormatUint(uint64(id), 10)
+	switch quotaType {
+	case UserQuota:
+		u, err := user.LookupId(idStr)
+		if err != nil {
+			return "", lookupError(quotaType, "ID "+idStr, err)
+		}
+		return u.Username, nil
+	case GroupQuota:
+		g, err := user.LookupGroupId(idStr)
+		if err != nil {
+			return "", lookupError(quotaType, "ID "+idStr, err)
+		}
+		return g.Name, nil
+	default:
+		return r.projects.LookupName(quotaType, id)
+	}
+}
+
+// lookupError 将 os/user 的未找到错误转换为 ErrUnknownName
+func lookupError(quotaType QuotaType, key string, err error) error {
+	var unknownUser user.UnknownUserError
+	var unknownUserID user.UnknownUserIdError
+	var unknownGroup user.UnknownGroupError
+	var unknownGroupID user.UnknownGroupIdError
+	if errors.As(err, &unknownUser) || errors.As(err, &unknownUserID) ||
+		errors.As(err, &unknownGroup) || errors.As(err, &unknownGroupID) {
+		return fmt.Errorf("%s %s: %w", quotaType, key, ErrUnknownName)
+	}
+	return err
+}
+
+// nameEntry passwd/group 文件中的一条记录
+type nameEntry struct {
+	name string
+	id   uint32
+}
+
+// readNameFile 读取 passwd/group 格式文件的名称（第一列）和ID（第三列）
+func readNameFile(path string) ([]nameEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []nameEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, ":")
+		if len(fields) < 3 {
+			continue
+		}
+		id, err := strconv.ParseUint(fields[2], 10, 32)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, nameEntry{name: fields[0], id: uint32(id)})
+	}
+
+	return entries, scanner.Err()
+}
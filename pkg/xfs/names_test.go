@@ -0,0 +1,69 @@
+package xfs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileResolver(t *testing.T) {
+	dir := t.TempDir()
+	passwd := filepath.Join(dir, "passwd")
+	group := filepath.Join(dir, "group")
+	require.NoError(t, os.WriteFile(passwd, []byte("root:x:0:0:::\nalice:x:1001:1001:::\nalias:x:1001:1001:::\n"), 0644))
+	require.NoError(t, os.WriteFile(group, []byte("# comment\nstaff:x:50:alice\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "projid"), []byte("webdata:10\n"), 0644))
+
+	r := NewFileResolver(passwd, group, NewProjectStore(filepath.Join(dir, "projects"), filepath.Join(dir, "projid")))
+
+	id, err := r.LookupID(UserQuota, "alias")
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1001), id)
+	name, err := r.LookupName(UserQuota, 1001)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", name)
+
+	id, err = r.LookupID(GroupQuota, "staff")
+	require.NoError(t, err)
+	assert.Equal(t, uint32(50), id)
+
+	name, err = r.LookupName(ProjectQuota, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "webdata", name)
+
+	_, err = r.LookupID(UserQuota, "bob")
+	assert.True(t, errors.Is(err, ErrUnknownName))
+	_, err = r.LookupName(GroupQuota, 51)
+	assert.True(t, errors.Is(err, ErrUnknownName))
+
+	// 文件修改后重新读取
+	require.NoError(t, os.WriteFile(passwd, []byte("bob:x:1002:1002:::\n"), 0644))
+	require.NoError(t, os.Chtimes(passwd, time.Now(), time.Now().Add(time.Minute)))
+	id, err = r.LookupID(UserQuota, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1002), id)
+
+	id, err = ResolveID(r, UserQuota, "2000")
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2000), id)
+	id, err = ResolveID(r, ProjectQuota, "webdata")
+	require.NoError(t, err)
+	assert.Equal(t, uint32(10), id)
+}
+
+func TestQuotaManager_QuotaInfoName(t *testing.T) {
+	dir := t.TempDir()
+	passwd := filepath.Join(dir, "passwd")
+	require.NoError(t, os.WriteFile(passwd, []byte("alice:x:1001:1001:::\n"), 0644))
+
+	q := NewContextQuotaManager(WithNameResolver(NewFileResolver(passwd, passwd, NewProjectStore("", "")))).(*quotaManager)
+	info := q.quotaInfo(UserQuota, "/mnt/xfs", "/dev/sdb1", &FsDiskQuota{ID: 1001})
+	assert.Equal(t, "alice", info.Name)
+	info = q.quotaInfo(UserQuota, "/mnt/xfs", "/dev/sdb1", &FsDiskQuota{ID: 1002})
+	assert.Empty(t, info.Name)
+}
@@ -0,0 +1,366 @@
+package xfs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/xfs-quota-kit/pkg/utils"
+)
+
+const (
+	// DefaultProjectsFile 默认项目目录映射文件 (id:path)
+	DefaultProjectsFile = "/etc/projects"
+	// DefaultProjidFile 默认项目名称映射文件 (name:id)
+	DefaultProjidFile = "/etc/projid"
+)
+
+// ProjectStore 管理 /etc/projects 和 /etc/projid 文件
+// 读写时保留注释和无法识别的行，写入采用临时文件+rename保证原子性，
+// 并通过advisory lock避免并发修改
+type ProjectStore struct {
+	ProjectsFile string
+	ProjidFile   string
+}
+
+// NewProjectStore 创建项目文件存储
+func NewProjectStore(projectsFile, projidFile string) *ProjectStore {
+	if projectsFile == "" {
+		projectsFile = DefaultProjectsFile
+	}
+	if projidFile == "" {
+		projidFile = DefaultProjidFile
+	}
+	return &ProjectStore{
+		ProjectsFile: projectsFile,
+		ProjidFile:   projidFile,
+	}
+}
+
+// ProjectFiles 已加载的项目配置文件
+type ProjectFiles struct {
+	Projects *ProjectFile // id:path
+	Projid   *ProjectFile // name:id
+}
+
+// List 获取所有项目
+// 以 projid 中的条目为准，只出现在 projects 中的数字ID也会返回（名称为空）
+func (f *ProjectFiles) List() []ProjectInfo {
+	paths := make(map[uint32]string)
+	var ids []uint32
+	for _, entry := range f.Projects.Entries() {
+		id, err := parseProjectID(entry.Key)
+		if err != nil {
+			continue
+		}
+		if _, exists := paths[id]; !exists {
+			paths[id] = entry.Value
+			ids = append(ids, id)
+		}
+	}
+
+	var projects []ProjectInfo
+	named := make(map[uint32]bool)
+	for _, entry := range f.Projid.Entries() {
+		id, err := parseProjectID(entry.Value)
+		if err != nil {
+			continue
+		}
+		named[id] = true
+		projects = append(projects, ProjectInfo{ID: id, Name: entry.Key, Path: paths[id]})
+	}
+
+	for _, id := range ids {
+		if !named[id] {
+			projects = append(projects, ProjectInfo{ID: id, Path: paths[id]})
+		}
+	}
+
+	sort.SliceStable(projects, func(i, j int) bool { return projects[i].ID < projects[j].ID })
+	return projects
+}
+
+// Find 按名称查找项目
+func (f *ProjectFiles) Find(name string) (*ProjectInfo, bool) {
+	for _, project := range f.List() {
+		if project.Name == name {
+			return &project, true
+		}
+	}
+	return nil, false
+}
+
+// FindByID 按ID查找项目
+func (f *ProjectFiles) FindByID(id uint32) (*ProjectInfo, bool) {
+	for _, project := range f.List() {
+		if project.ID == id {
+			return &project, true
+		}
+	}
+	return nil, false
+}
+
+// Paths 获取项目的所有目录
+func (f *ProjectFiles) Paths(id uint32) []string {
+	var paths []string
+	for _, entry := range f.Projects.Entries() {
+		if entryID, err := parseProjectID(entry.Key); err == nil && entryID == id {
+			paths = append(paths, entry.Value)
+		}
+	}
+	return paths
+}
+
+// Add 添加项目条目
+func (f *ProjectFiles) Add(project ProjectInfo) error {
+	if err := validateProjectName(project.Name); err != nil {
+		return err
+	}
+	if existing, ok := f.Find(project.Name); ok {
+		return fmt.Errorf("project %s already exists with ID %d", project.Name, existing.ID)
+	}
+	if existing, ok := f.FindByID(project.ID); ok {
+		return fmt.Errorf("project ID %d is already used by %q", project.ID, existing.Name)
+	}
+
+	id := strconv.FormatUint(uint64(project.ID), 10)
+	f.Projid.Append(project.Name, id)
+	if project.Path != "" {
+		f.Projects.Append(id, project.Path)
+	}
+	return nil
+}
+
+// Remove 删除项目的所有条目
+func (f *ProjectFiles) Remove(name string) (*ProjectInfo, error) {
+	project, ok := f.Find(name)
+	if !ok {
+		return nil, fmt.Errorf("project %s not found", name)
+	}
+
+	f.Projid.RemoveIf(func(entry ProjectFileEntry) bool {
+		return entry.Key == name
+	})
+	f.Projects.RemoveIf(func(entry ProjectFileEntry) bool {
+		id, err := parseProjectID(entry.Key)
+		return err == nil && id == project.ID
+	})
+
+	return project, nil
+}
+
+// Load 读取项目配置文件（不加锁）
+func (s *ProjectStore) Load() (*ProjectFiles, error) {
+	projects, err := ReadProjectFile(s.ProjectsFile)
+	if err != nil {
+		return nil, err
+	}
+	projid, err := ReadProjectFile(s.ProjidFile)
+	if err != nil {
+		return nil, err
+	}
+	return &ProjectFiles{Projects: projects, Projid: projid}, nil
+}
+
+// List 获取所有项目
+func (s *ProjectStore) List() ([]ProjectInfo, error) {
+	files, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	return files.List(), nil
+}
+
+// Update 在持有锁的情况下读取、修改并原子写回项目配置文件
+// fn 返回错误时不写入任何文件
+func (s *ProjectStore) Update(fn func(files *ProjectFiles) error) error {
+	unlock, err := lockFile(s.ProjidFile + ".lock")
+	if err != nil {
+		return fmt.Errorf("failed to lock project files: %w", err)
+	}
+	defer unlock()
+
+	files, err := s.Load()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(files); err != nil {
+		return err
+	}
+
+	if files.Projects.modified {
+		if err := files.Projects.Write(); err != nil {
+			return err
+		}
+	}
+	if files.Projid.modified {
+		if err := files.Projid.Write(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProjectFileEntry 项目配置文件中的有效条目 (key:value)
+type ProjectFileEntry struct {
+	Key   string
+	Value string
+}
+
+// projectFileLine 项目配置文件中的一行，保留原始内容
+type projectFileLine struct {
+	raw   string
+	entry *ProjectFileEntry // 注释、空行和无法识别的行为nil
+}
+
+// ProjectFile 保留注释和未知行的 key:value 配置文件
+type ProjectFile struct {
+	Path     string
+	lines    []projectFileLine
+	modified bool
+}
+
+// ReadProjectFile 读取项目配置文件，文件不存在时返回空文件
+func ReadProjectFile(path string) (*ProjectFile, error) {
+	file := &ProjectFile{Path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return file, nil
+		}
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		file.lines = append(file.lines, parseProjectFileLine(scanner.Text()))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return file, nil
+}
+
+// parseProjectFileLine 解析单行，第一个冒号之前为key（路径中允许出现冒号）
+func parseProjectFileLine(raw string) projectFileLine {
+	line := strings.TrimSpace(raw)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return projectFileLine{raw: raw}
+	}
+
+	parts := strings.SplitN(line, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return projectFileLine{raw: raw}
+	}
+
+	return projectFileLine{
+		raw:   raw,
+		entry: &ProjectFileEntry{Key: parts[0], Value: parts[1]},
+	}
+}
+
+// Entries 获取所有有效条目
+func (f *ProjectFile) Entries() []ProjectFileEntry {
+	var entries []ProjectFileEntry
+	for _, line := range f.lines {
+		if line.entry != nil {
+			entries = append(entries, *line.entry)
+		}
+	}
+	return entries
+}
+
+// Append 追加条目
+func (f *ProjectFile) Append(key, value string) {
+	f.lines = append(f.lines, projectFileLine{
+		raw:   key + ":" + value,
+		entry: &ProjectFileEntry{Key: key, Value: value},
+	})
+	f.modified = true
+}
+
+// RemoveIf 删除满足条件的条目，返回删除数量
+func (f *ProjectFile) RemoveIf(match func(entry ProjectFileEntry) bool) int {
+	kept := f.lines[:0]
+	removed := 0
+	for _, line := range f.lines {
+		if line.entry != nil && match(*line.entry) {
+			removed++
+			continue
+		}
+		kept = append(kept, line)
+	}
+	f.lines = kept
+	if removed > 0 {
+		f.modified = true
+	}
+	return removed
+}
+
+// Bytes 序列化文件内容
+func (f *ProjectFile) Bytes() []byte {
+	var buf bytes.Buffer
+	for _, line := range f.lines {
+		buf.WriteString(line.raw)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// Write 原子写回文件
+func (f *ProjectFile) Write() error {
+	if err := utils.WriteFileAtomic(f.Path, f.Bytes(), 0644); err != nil {
+		return err
+	}
+	f.modified = false
+	return nil
+}
+
+// lockFile 获取文件的排他advisory lock，返回解锁函数
+func lockFile(path string) (func(), error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return func() {
+		syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		file.Close()
+	}, nil
+}
+
+// parseProjectID 解析项目ID
+func parseProjectID(s string) (uint32, error) {
+	id, err := strconv.ParseUint(strings.TrimSpace(s), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid project ID %q", s)
+	}
+	return uint32(id), nil
+}
+
+// validateProjectName 校验项目名称
+func validateProjectName(name string) error {
+	if name == "" {
+		return fmt.Errorf("project name cannot be empty")
+	}
+	if strings.ContainsAny(name, ":#\n") || strings.TrimSpace(name) != name {
+		return fmt.Errorf("invalid project name %q", name)
+	}
+	if _, err := strconv.ParseUint(name, 10, 32); err == nil {
+		return fmt.Errorf("project name %q must not be numeric", name)
+	}
+	return nil
+}
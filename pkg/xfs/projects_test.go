@@ -0,0 +1,169 @@
+package xfs
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestProjectStore(t *testing.T, projects, projid string) *ProjectStore {
+	dir := t.TempDir()
+	store := NewProjectStore(filepath.Join(dir, "projects"), filepath.Join(dir, "projid"))
+	if projects != "" {
+		require.NoError(t, os.WriteFile(store.ProjectsFile, []byte(projects), 0644))
+	}
+	if projid != "" {
+		require.NoError(t, os.WriteFile(store.ProjidFile, []byte(projid), 0600))
+	}
+	return store
+}
+
+func TestProjectStore_List(t *testing.T) {
+	store := newTestProjectStore(t,
+		"# project directories\n10:/export/web\n10:/export/web2\n11:/export/db\nbogus line\n42:/export/orphan\n",
+		"# project names\nweb:10\ndb:11\nbroken:abc\n")
+
+	projects, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, projects, 3)
+
+	assert.Equal(t, ProjectInfo{ID: 10, Name: "web", Path: "/export/web"}, projects[0])
+	assert.Equal(t, ProjectInfo{ID: 11, Name: "db", Path: "/export/db"}, projects[1])
+	assert.Equal(t, ProjectInfo{ID: 42, Path: "/export/orphan"}, projects[2])
+}
+
+func TestProjectStore_MissingFiles(t *testing.T) {
+	store := newTestProjectStore(t, "", "")
+
+	projects, err := store.List()
+	require.NoError(t, err)
+	assert.Empty(t, projects)
+}
+
+func TestProjectStore_UpdatePreservesComments(t *testing.T) {
+	store := newTestProjectStore(t,
+		"# managed by hand\n10:/export/web\n\nunknown entry\n",
+		"# names\nweb:10\n")
+
+	err := store.Update(func(files *ProjectFiles) error {
+		return files.Add(ProjectInfo{ID: 11, Name: "db", Path: "/export/db"})
+	})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(store.ProjectsFile)
+	require.NoError(t, err)
+	assert.Equal(t, "# managed by hand\n10:/export/web\n\nunknown entry\n11:/export/db\n", string(data))
+
+	data, err = os.ReadFile(store.ProjidFile)
+	require.NoError(t, err)
+	assert.Equal(t, "# names\nweb:10\ndb:11\n", string(data))
+
+	// 保留原有权限
+	info, err := os.Stat(store.ProjidFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	err = store.Update(func(files *ProjectFiles) error {
+		_, err := files.Remove("web")
+		return err
+	})
+	require.NoError(t, err)
+
+	data, err = os.ReadFile(store.ProjectsFile)
+	require.NoError(t, err)
+	assert.Equal(t, "# managed by hand\n\nunknown entry\n11:/export/db\n", string(data))
+}
+
+func TestProjectStore_UpdateErrorWritesNothing(t *testing.T) {
+	store := newTestProjectStore(t, "10:/export/web\n", "web:10\n")
+
+	err := store.Update(func(files *ProjectFiles) error {
+		return files.Add(ProjectInfo{ID: 10, Name: "other", Path: "/export/other"})
+	})
+	assert.Error(t, err)
+
+	err = store.Update(func(files *ProjectFiles) error {
+		return files.Add(ProjectInfo{ID: 12, Name: "web", Path: "/export/other"})
+	})
+	assert.Error(t, err)
+
+	err = store.Update(func(files *ProjectFiles) error {
+		_, err := files.Remove("missing")
+		return err
+	})
+	assert.Error(t, err)
+
+	data, err := os.ReadFile(store.ProjidFile)
+	require.NoError(t, err)
+	assert.Equal(t, "web:10\n", string(data))
+}
+
+func TestProjectStore_ConcurrentUpdates(t *testing.T) {
+	store := newTestProjectStore(t, "", "")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := store.Update(func(files *ProjectFiles) error {
+				return files.Add(ProjectInfo{
+					ID:   uint32(100 + i),
+					Name: "p" + string(rune('a'+i)),
+					Path: "/export/p",
+				})
+			})
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	projects, err := store.List()
+	require.NoError(t, err)
+	assert.Len(t, projects, 20)
+}
+
+func TestValidateProjectName(t *testing.T) {
+	assert.NoError(t, validateProjectName("webdata"))
+	assert.Error(t, validateProjectName(""))
+	assert.Error(t, validateProjectName("a:b"))
+	assert.Error(t, validateProjectName("#web"))
+	assert.Error(t, validateProjectName(" web"))
+	assert.Error(t, validateProjectName("1234"))
+}
+
+func TestQuotaManager_Projects(t *testing.T) {
+	dir := t.TempDir()
+	if isXFS, _ := NewQuotaManager().IsXFSFilesystem(dir); !isXFS {
+		t.Skip("applying project IDs requires an XFS filesystem")
+	}
+	manager := NewQuotaManager(WithProjectFiles(filepath.Join(dir, "projects"), filepath.Join(dir, "projid")))
+
+	project, err := manager.CreateProject("web", filepath.Join(dir, "web"), CreateProjectOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1000), project.ID)
+	assert.DirExists(t, project.Path)
+
+	project, err = manager.CreateProject("db", filepath.Join(dir, "db"), CreateProjectOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1001), project.ID)
+
+	_, err = manager.CreateProject("web", filepath.Join(dir, "web2"), CreateProjectOptions{})
+	assert.Error(t, err)
+
+	projects, err := manager.GetProjects()
+	require.NoError(t, err)
+	assert.Len(t, projects, 2)
+
+	require.NoError(t, manager.RemoveProject("web"))
+	assert.Error(t, manager.RemoveProject("web"))
+
+	projects, err = manager.GetProjects()
+	require.NoError(t, err)
+	require.Len(t, projects, 1)
+	assert.Equal(t, "db", projects[0].Name)
+}
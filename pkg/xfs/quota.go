@@ -1,24 +1,31 @@
 package xfs
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"syscall"
 	"time"
+
+	"github.com/xfs-quota-kit/pkg/utils"
 )
 
 const (
 	// quotactl 命令常量
-	QCMD_CMD  = 0xff
-	QCMD_TYPE = 0x0f00
-
-	// XFS配额命令
-	Q_XQUOTAON  = 0x800
-	Q_XQUOTAOFF = 0x801
-	Q_XGETQUOTA = 0x800
-	Q_XSETQLIM  = 0x803
-	Q_XGETQSTAT = 0x805
+	SUBCMDSHIFT = 8
+	SUBCMDMASK  = 0xff
+
+	// XFS配额命令 (XQM_CMD(x) = ('X'<<8) + x)
+	Q_XQUOTAON      = 0x5801
+	Q_XQUOTAOFF     = 0x5802
+	Q_XGETQUOTA     = 0x5803
+	Q_XSETQLIM      = 0x5804
+	Q_XGETQSTAT     = 0x5805
+	Q_XQUOTARM      = 0x5806
+	Q_XGETQSTATV    = 0x5808
+	Q_XGETNEXTQUOTA = 0x5809
 
 	// 配额类型
 	USRQUOTA = 0
@@ -29,104 +36,228 @@ const (
 	XFS_SUPER_MAGIC = 0x58465342
 )
 
-// DqBlk 配额块结构体（简化版本）
-type DqBlk struct {
-	BHardLimit uint64 // 块硬限制
-	BSoftLimit uint64 // 块软限制
-	CurSpace   uint64 // 当前使用空间
-	IHardLimit uint64 // inode硬限制
-	ISoftLimit uint64 // inode软限制
-	CurInodes  uint64 // 当前使用inode数
-	BTime      int64  // 块宽限时间
-	ITime      int64  // inode宽限时间
+// QuotaWalkFunc 遍历配额时的回调函数，返回 StopWalk 提前结束遍历
+type QuotaWalkFunc func(quota *QuotaInfo) error
+
+// StopWalk 在 QuotaWalkFunc 中返回以停止遍历，不会作为错误返回给调用方
+var StopWalk = errors.New("stop quota walk")
+
+// ContextQuotaManager 以context为第一个参数的配额管理器接口
+// 遍历和批量操作在处理每个ID之间检查context是否已取消，
+// xfs_quota 后端会将context的截止时间传递给子进程
+type ContextQuotaManager interface {
+	// 基础操作
+	GetQuota(ctx context.Context, quotaType QuotaType, id uint32, path string) (*QuotaInfo, error)
+	SetQuota(ctx context.Context, quotaType QuotaType, id uint32, path string, limits QuotaLimits) error
+	RemoveQuota(ctx context.Context, quotaType QuotaType, id uint32, path string) error
+
+	// 宽限期管理
+	GetGraceTimes(ctx context.Context, quotaType QuotaType, path string) (*GraceTimes, error)
+	SetGraceTimes(ctx context.Context, quotaType QuotaType, path string, grace GraceTimes) error
+	SetGraceTimers(ctx context.Context, quotaType QuotaType, id uint32, path string, timers GraceTimers) error
+	ResetGrace(ctx context.Context, quotaType QuotaType, id uint32, path string) error
+
+	// 批量操作
+	GetAllQuotas(ctx context.Context, quotaType QuotaType, path string) ([]QuotaInfo, error)
+	SetBatchQuotas(ctx context.Context, quotaType QuotaType, path string, quotas map[uint32]QuotaLimits, opts BatchOptions) (*BatchResult, error)
+	WalkQuotas(ctx context.Context, quotaType QuotaType, path string, fn QuotaWalkFunc) error
+	EnsureDefaultLimits(ctx context.Context, path string, opts EnsureOptions) (*EnsureResult, error)
+
+	// 项目配额特殊操作
+	CreateProject(ctx context.Context, name string, path string, opts CreateProjectOptions) (*ProjectInfo, error)
+	RemoveProject(ctx context.Context, name string) error
+	GetProjects(ctx context.Context) ([]ProjectInfo, error)
+	SetupProject(ctx context.Context, name string, opts ProjectTreeOptions) (*ProjectTreeResult, error)
+	ClearProject(ctx context.Context, name string, opts ProjectTreeOptions) (*ProjectTreeResult, error)
+	VerifyProject(ctx context.Context, name string, opts VerifyOptions) (*VerifyResult, error)
+
+	// 报告和监控
+	GenerateReport(ctx context.Context, path string) (*QuotaReport, error)
+	StreamReport(ctx context.Context, path string, fn QuotaWalkFunc) (*QuotaReport, error)
+	CheckQuotaStatus(ctx context.Context, path string) error
+	GetQuotaState(ctx context.Context, path string) (*QuotaState, error)
+
+	// 配额开关
+	SetEnforcement(ctx context.Context, quotaType QuotaType, path string, enforce bool) error
+	PurgeQuota(ctx context.Context, quotaType QuotaType, path string) error
+
+	// 文件系统操作
+	IsXFSFilesystem(ctx context.Context, path string) (bool, error)
+	GetFilesystemInfo(ctx context.Context, path string) (*FilesystemInfo, error)
+	DiscoverFilesystems(ctx context.Context) ([]DiscoveredFilesystem, error)
 }
 
-// QuotaManager 配额管理器接口
+// QuotaManager 配额管理器接口，使用 context.Background() 调用 ContextQuotaManager
 type QuotaManager interface {
 	// 基础操作
 	GetQuota(quotaType QuotaType, id uint32, path string) (*QuotaInfo, error)
 	SetQuota(quotaType QuotaType, id uint32, path string, limits QuotaLimits) error
 	RemoveQuota(quotaType QuotaType, id uint32, path string) error
 
+	// 宽限期管理
+	GetGraceTimes(quotaType QuotaType, path string) (*GraceTimes, error)
+	SetGraceTimes(quotaType QuotaType, path string, grace GraceTimes) error
+	SetGraceTimers(quotaType QuotaType, id uint32, path string, timers GraceTimers) error
+	ResetGrace(quotaType QuotaType, id uint32, path string) error
+
 	// 批量操作
 	GetAllQuotas(quotaType QuotaType, path string) ([]QuotaInfo, error)
-	SetBatchQuotas(quotaType QuotaType, path string, quotas map[uint32]QuotaLimits) error
+	SetBatchQuotas(quotaType QuotaType, path string, quotas map[uint32]QuotaLimits, opts BatchOptions) (*BatchResult, error)
+	WalkQuotas(quotaType QuotaType, path string, fn QuotaWalkFunc) error
+	EnsureDefaultLimits(path string, opts EnsureOptions) (*EnsureResult, error)
 
 	// 项目配额特殊操作
-	CreateProject(name string, path string) (*ProjectInfo, error)
+	CreateProject(name string, path string, opts CreateProjectOptions) (*ProjectInfo, error)
 	RemoveProject(name string) error
 	GetProjects() ([]ProjectInfo, error)
+	SetupProject(name string, opts ProjectTreeOptions) (*ProjectTreeResult, error)
+	ClearProject(name string, opts ProjectTreeOptions) (*ProjectTreeResult, error)
+	VerifyProject(name string, opts VerifyOptions) (*VerifyResult, error)
 
 	// 报告和监控
 	GenerateReport(path string) (*QuotaReport, error)
+	StreamReport(path string, fn QuotaWalkFunc) (*QuotaReport, error)
 	CheckQuotaStatus(path string) error
+	GetQuotaState(path string) (*QuotaState, error)
+
+	// 配额开关
+	SetEnforcement(quotaType QuotaType, path string, enforce bool) error
+	PurgeQuota(quotaType QuotaType, path string) error
 
 	// 文件系统操作
 	IsXFSFilesystem(path string) (bool, error)
-	GetFilesystemInfo(path string) (map[string]interface{}, error)
+	GetFilesystemInfo(path string) (*FilesystemInfo, error)
+	DiscoverFilesystems() ([]DiscoveredFilesystem, error)
 }
 
 // quotaManager 配额管理器实现
-type quotaManager struct{}
+type quotaManager struct {
+	mountInfoPath string              // 挂载信息文件路径
+	projects      *ProjectStore       // 项目配置文件
+	passwdFile    string              // 用户数据库，用于枚举已知UID
+	groupFile     string              // 组数据库，用于枚举已知GID
+	backend       Backend             // 与内核交互的底层实现
+	names         NameResolver        // 填充 QuotaInfo.Name
+	projectIDs    *ProjectIDAllocator // 项目ID分配器
+}
 
-// NewQuotaManager 创建新的配额管理器
-func NewQuotaManager() QuotaManager {
-	return &quotaManager{}
+// Option 配额管理器选项
+type Option func(*quotaManager)
+
+// WithMountInfoPath 指定挂载信息文件路径
+func WithMountInfoPath(path string) Option {
+	return func(q *quotaManager) {
+		q.mountInfoPath = path
+	}
 }
 
-// GetQuota 获取配额信息
-func (q *quotaManager) GetQuota(quotaType QuotaType, id uint32, path string) (*QuotaInfo, error) {
-	device, err := q.getDeviceFromPath(path)
-	if err != nil {
-		return nil, &QuotaError{Op: "get", Path: path, Err: err}
+// WithProjectFiles 指定 /etc/projects 和 /etc/projid 文件路径
+func WithProjectFiles(projectsFile, projidFile string) Option {
+	return func(q *quotaManager) {
+		q.projects = NewProjectStore(projectsFile, projidFile)
 	}
+}
 
-	var dqblk DqBlk
-	_ = makeQuotaCmd(Q_XGETQUOTA, quotaType) // 模拟系统调用
+// WithBackend 指定与内核交互的后端，默认使用原生 quotactl 后端
+func WithBackend(backend Backend) Option {
+	return func(q *quotaManager) {
+		q.backend = backend
+	}
+}
 
-	// 模拟系统调用（实际实现需要使用真实的quotactl系统调用）
-	// 这里为了演示，返回模拟数据
-	quota := &QuotaInfo{
-		ID:          id,
-		Type:        quotaType,
-		Path:        path,
-		Device:      device,
-		BlockUsed:   dqblk.CurSpace / 1024, // 转换为KB
-		BlockSoft:   dqblk.BSoftLimit / 1024,
-		BlockHard:   dqblk.BHardLimit / 1024,
-		InodeUsed:   dqblk.CurInodes,
-		InodeSoft:   dqblk.ISoftLimit,
-		InodeHard:   dqblk.IHardLimit,
-		LastUpdated: time.Now(),
+// WithNameResolver 指定名称解析器，默认读取 passwd/group 文件和 projid 文件
+func WithNameResolver(resolver NameResolver) Option {
+	return func(q *quotaManager) {
+		q.names = resolver
+	}
+}
+
+// WithProjectIDAllocator 指定项目ID分配器，默认从1000开始分配且不设隔离期
+func WithProjectIDAllocator(allocator *ProjectIDAllocator) Option {
+	return func(q *quotaManager) {
+		q.projectIDs = allocator
 	}
+}
+
+// NewQuotaManager 创建新的配额管理器
+func NewQuotaManager(opts ...Option) QuotaManager {
+	return BackgroundQuotaManager(NewContextQuotaManager(opts...))
+}
 
-	return quota, nil
+// NewContextQuotaManager 创建以context为第一个参数的配额管理器
+func NewContextQuotaManager(opts ...Option) ContextQuotaManager {
+	q := &quotaManager{
+		mountInfoPath: DefaultMountInfoPath,
+		projects:      NewProjectStore(DefaultProjectsFile, DefaultProjidFile),
+		passwdFile:    DefaultPasswdFile,
+		groupFile:     DefaultGroupFile,
+		backend:       NewNativeBackend(),
+		projectIDs:    &ProjectIDAllocator{},
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	if q.names == nil {
+		q.names = NewFileResolver(q.passwdFile, q.groupFile, q.projects)
+	}
+	return q
 }
 
-// SetQuota 设置配额限制
-func (q *quotaManager) SetQuota(quotaType QuotaType, id uint32, path string, limits QuotaLimits) error {
-	device, err := q.getDeviceFromPath(path)
+// GetQuota 获取配额信息
+func (q *quotaManager) GetQuota(ctx context.Context, quotaType QuotaType, id uint32, path string) (*QuotaInfo, error) {
+	mount, err := q.findMount(path)
+	if err != nil {
+		return nil, &QuotaError{Op: "get", Path: path, Err: err}
+	}
+
+	dq, err := q.backend.GetDquot(ctx, quotaType, id, mount)
 	if err != nil {
-		return &QuotaError{Op: "set", Path: path, Err: err}
+		return nil, &QuotaError{Op: "get", Path: path, Err: err}
 	}
 
-	var dqblk DqBlk
-	dqblk.BSoftLimit = limits.BlockSoft * 1024 // 转换为字节
-	dqblk.BHardLimit = limits.BlockHard * 1024
-	dqblk.ISoftLimit = limits.InodeSoft
-	dqblk.IHardLimit = limits.InodeHard
+	return q.quotaInfo(quotaType, path, mount.Device(), dq), nil
+}
 
-	_ = makeQuotaCmd(Q_XSETQLIM, quotaType) // 模拟系统调用
+// SetQuota 设置配额限制，只修改 limits.Fields 中的限制
+func (q *quotaManager) SetQuota(ctx context.Context, quotaType QuotaType, id uint32, path string, limits QuotaLimits) error {
+	dq := FsDiskQuota{
+		Version:      FS_DQUOT_VERSION,
+		Flags:        quotaTypeFlag(quotaType),
+		FieldMask:    limitFieldMask(limits),
+		BlkSoftLimit: limits.BlockSoft.BasicBlocks(),
+		BlkHardLimit: limits.BlockHard.BasicBlocks(),
+		InoSoftLimit: limits.InodeSoft,
+		InoHardLimit: limits.InodeHard,
+		RTBSoftLimit: limits.RTBlockSoft.BasicBlocks(),
+		RTBHardLimit: limits.RTBlockHard.BasicBlocks(),
+	}
 
-	// 模拟系统调用
-	_ = device
-	_ = dqblk
+	return q.setQlim(ctx, quotaType, id, path, "set", &dq)
+}
 
-	return nil
+// limitFieldMask 获取 limits.Fields 对应的 d_fieldmask
+func limitFieldMask(limits QuotaLimits) uint16 {
+	var mask uint16
+	for _, f := range []struct {
+		field LimitFields
+		mask  uint16
+	}{
+		{LimitBlockSoft, FS_DQ_BSOFT},
+		{LimitBlockHard, FS_DQ_BHARD},
+		{LimitInodeSoft, FS_DQ_ISOFT},
+		{LimitInodeHard, FS_DQ_IHARD},
+		{LimitRTBlockSoft, FS_DQ_RTBSOFT},
+		{LimitRTBlockHard, FS_DQ_RTBHARD},
+	} {
+		if limits.Has(f.field) {
+			mask |= f.mask
+		}
+	}
+	return mask
 }
 
 // RemoveQuota 删除配额限制
-func (q *quotaManager) RemoveQuota(quotaType QuotaType, id uint32, path string) error {
+func (q *quotaManager) RemoveQuota(ctx context.Context, quotaType QuotaType, id uint32, path string) error {
 	// 通过设置所有限制为0来删除配额
 	limits := QuotaLimits{
 		BlockSoft: 0,
@@ -135,117 +266,226 @@ func (q *quotaManager) RemoveQuota(quotaType QuotaType, id uint32, path string)
 		InodeHard: 0,
 	}
 
-	return q.SetQuota(quotaType, id, path, limits)
+	return q.SetQuota(ctx, quotaType, id, path, limits)
 }
 
 // GetAllQuotas 获取所有配额
-func (q *quotaManager) GetAllQuotas(quotaType QuotaType, path string) ([]QuotaInfo, error) {
-	// 这里应该遍历所有用户/组/项目ID，实际实现需要读取系统配置
+func (q *quotaManager) GetAllQuotas(ctx context.Context, quotaType QuotaType, path string) ([]QuotaInfo, error) {
 	var quotas []QuotaInfo
-
-	// 模拟返回一些配额数据
-	for i := uint32(1000); i <= 1005; i++ {
-		quota, err := q.GetQuota(quotaType, i, path)
-		if err != nil {
-			continue // 跳过不存在的配额
-		}
+	err := q.WalkQuotas(ctx, quotaType, path, func(quota *QuotaInfo) error {
 		quotas = append(quotas, *quota)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return quotas, nil
 }
 
-// SetBatchQuotas 批量设置配额
-func (q *quotaManager) SetBatchQuotas(quotaType QuotaType, path string, quotas map[uint32]QuotaLimits) error {
-	var lastErr error
-	for id, limits := range quotas {
-		if err := q.SetQuota(quotaType, id, path, limits); err != nil {
-			lastErr = err
-		}
+// WalkQuotas 流式遍历所有配额，内存占用与ID数量无关
+func (q *quotaManager) WalkQuotas(ctx context.Context, quotaType QuotaType, path string, fn QuotaWalkFunc) error {
+	mount, err := q.findMount(path)
+	if err != nil {
+		return &QuotaError{Op: "list", Path: path, Err: err}
+	}
+
+	err = q.forEachDquot(ctx, quotaType, path, mount, fn)
+	if err == StopWalk {
+		return nil
 	}
-	return lastErr
+	return err
 }
 
 // CreateProject 创建项目配额
-func (q *quotaManager) CreateProject(name string, path string) (*ProjectInfo, error) {
-	// 检查项目是否已存在
-	projects, err := q.GetProjects()
+// 项目ID在创建目录和修改任何文件之前分配并检查冲突
+func (q *quotaManager) CreateProject(ctx context.Context, name string, path string, opts CreateProjectOptions) (*ProjectInfo, error) {
+	if err := validateProjectName(name); err != nil {
+		return nil, err
+	}
+
+	absPath, err := filepath.Abs(path)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, project := range projects {
-		if project.Name == name {
-			return nil, fmt.Errorf("project %s already exists", name)
-		}
+	// 项目目录可能尚不存在，以最近的已存在上级目录确定文件系统
+	mount, err := q.findMount(existingParent(absPath))
+	if err != nil {
+		return nil, &QuotaError{Op: "project", Path: absPath, Err: err}
 	}
 
-	// 分配新的项目ID（实际实现需要更智能的ID分配）
-	projectID := uint32(1000 + len(projects))
+	var project *ProjectInfo
+	err = q.projects.Update(func(files *ProjectFiles) error {
+		if _, exists := files.Find(name); exists {
+			return fmt.Errorf("project %s already exists", name)
+		}
 
-	// 创建项目目录
-	if err := os.MkdirAll(path, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create project directory: %w", err)
-	}
+		used, err := q.usedProjectIDs(ctx, files, mount)
+		if err != nil {
+			return err
+		}
+		projectID, err := q.projectIDs.Choose(mount.MountPoint, opts.ID, used)
+		if err != nil {
+			return fmt.Errorf("project %s: %w", name, err)
+		}
+
+		// 创建项目目录
+		if err := os.MkdirAll(absPath, 0755); err != nil {
+			return fmt.Errorf("failed to create project directory: %w", err)
+		}
+
+		// 为目录树设置项目ID，失败时不写入项目配置文件
+		if _, err := SetProjectTreeContext(ctx, absPath, projectID, ProjectTreeOptions{}); err != nil {
+			return fmt.Errorf("failed to apply project ID %d to %s: %w", projectID, absPath, err)
+		}
 
-	// 写入项目配置文件（实际实现需要处理 /etc/projects 和 /etc/projid）
-	project := &ProjectInfo{
-		ID:   projectID,
-		Name: name,
-		Path: path,
+		project = &ProjectInfo{
+			ID:   projectID,
+			Name: name,
+			Path: absPath,
+		}
+		return files.Add(*project)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return project, nil
 }
 
 // RemoveProject 删除项目配额
-func (q *quotaManager) RemoveProject(name string) error {
-	// 实际实现需要从 /etc/projects 和 /etc/projid 文件中删除项目配置
-	return nil
+func (q *quotaManager) RemoveProject(ctx context.Context, name string) error {
+	return q.projects.Update(func(files *ProjectFiles) error {
+		project, ok := files.Find(name)
+		if !ok {
+			return fmt.Errorf("project %s not found", name)
+		}
+
+		// 先清除目录树上的项目ID，已删除的目录直接跳过
+		for _, path := range files.Paths(project.ID) {
+			if _, err := ClearProjectTreeContext(ctx, path, ProjectTreeOptions{}); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to clear project ID from %s: %w", path, err)
+			}
+		}
+
+		if _, err := files.Remove(name); err != nil {
+			return err
+		}
+		// 记录释放时间，隔离期内不会再次分配该ID
+		return q.projectIDs.Release(project.ID)
+	})
 }
 
 // GetProjects 获取所有项目
-func (q *quotaManager) GetProjects() ([]ProjectInfo, error) {
-	// 实际实现需要读取 /etc/projects 和 /etc/projid 文件
-	var projects []ProjectInfo
-
-	// 模拟返回一些项目数据
-	projects = append(projects, ProjectInfo{
-		ID:   1000,
-		Name: "example-project",
-		Path: "/mnt/xfs/projects/example",
+func (q *quotaManager) GetProjects(ctx context.Context) ([]ProjectInfo, error) {
+	return q.projects.List()
+}
+
+// SetupProject 为项目的所有目录设置项目ID
+func (q *quotaManager) SetupProject(ctx context.Context, name string, opts ProjectTreeOptions) (*ProjectTreeResult, error) {
+	return q.walkProject(ctx, name, func(path string, id uint32) (*ProjectTreeResult, error) {
+		return SetProjectTreeContext(ctx, path, id, opts)
+	})
+}
+
+// ClearProject 清除项目所有目录上的项目ID
+func (q *quotaManager) ClearProject(ctx context.Context, name string, opts ProjectTreeOptions) (*ProjectTreeResult, error) {
+	return q.walkProject(ctx, name, func(path string, id uint32) (*ProjectTreeResult, error) {
+		return ClearProjectTreeContext(ctx, path, opts)
 	})
+}
+
+// walkProject 对项目的每个目录执行操作并汇总结果
+func (q *quotaManager) walkProject(ctx context.Context, name string, fn func(path string, id uint32) (*ProjectTreeResult, error)) (*ProjectTreeResult, error) {
+	files, err := q.projects.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	project, ok := files.Find(name)
+	if !ok {
+		return nil, fmt.Errorf("project %s not found", name)
+	}
+
+	paths := files.Paths(project.ID)
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("project %s has no directories in %s", name, q.projects.ProjectsFile)
+	}
+
+	total := &ProjectTreeResult{}
+	for _, path := range paths {
+		result, err := fn(path, project.ID)
+		if result != nil {
+			total.Processed += result.Processed
+			total.Skipped += result.Skipped
+		}
+		if err != nil {
+			return total, fmt.Errorf("project %s: %w", name, err)
+		}
+	}
 
-	return projects, nil
+	return total, nil
 }
 
 // GenerateReport 生成配额报告
-func (q *quotaManager) GenerateReport(path string) (*QuotaReport, error) {
+func (q *quotaManager) GenerateReport(ctx context.Context, path string) (*QuotaReport, error) {
+	allQuotas := []QuotaInfo{}
+
+	report, err := q.StreamReport(ctx, path, func(quota *QuotaInfo) error {
+		allQuotas = append(allQuotas, *quota)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report.Quotas = allQuotas
+	return report, nil
+}
+
+// StreamReport 流式生成配额报告，只统计汇总信息而不保存配额详情
+// fn 非空时对每条配额调用，返回 StopWalk 提前结束
+func (q *quotaManager) StreamReport(ctx context.Context, path string, fn QuotaWalkFunc) (*QuotaReport, error) {
+	// 文件系统不可用（如未挂载）时报错，而不是返回空报告
+	if _, err := q.findMount(path); err != nil {
+		return nil, &QuotaError{Op: "report", Path: path, Err: err}
+	}
+
 	report := &QuotaReport{
 		Filesystem:  path,
 		GeneratedAt: time.Now(),
 	}
 
-	// 收集所有类型的配额信息
-	allQuotas := []QuotaInfo{}
+	// 配额子系统状态仅作为附加信息，获取失败不影响报告
+	if state, err := q.GetQuotaState(ctx, path); err == nil {
+		report.State = state
+	}
 
 	for _, qType := range []QuotaType{UserQuota, GroupQuota, ProjectQuota} {
-		quotas, err := q.GetAllQuotas(qType, path)
-		if err != nil {
-			continue
+		var fnErr error
+		err := q.WalkQuotas(ctx, qType, path, func(quota *QuotaInfo) error {
+			report.Add(quota)
+			if fn != nil {
+				fnErr = fn(quota)
+			}
+			return fnErr
+		})
+		if fnErr == StopWalk {
+			break
 		}
-		allQuotas = append(allQuotas, quotas...)
-	}
-
-	report.Quotas = allQuotas
-	report.TotalQuotas = len(allQuotas)
-
-	// 统计超限和警告的配额
-	for _, quota := range allQuotas {
-		if quota.IsBlockExceeded() || quota.IsInodeExceeded() {
-			report.OverQuotas++
-		} else if quota.BlockUsagePercent() > 80 || quota.InodeUsagePercent() > 80 {
-			report.WarningQuotas++
+		if fnErr != nil {
+			return nil, fnErr
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		if err != nil {
+			// 只跳过未开启统计的配额类型，其他错误（如权限不足、I/O错误）不能当作空报告
+			if isQuotaNotEnabled(err) {
+				continue
+			}
+			return nil, err
 		}
 	}
 
@@ -253,19 +493,27 @@ func (q *quotaManager) GenerateReport(path string) (*QuotaReport, error) {
 }
 
 // CheckQuotaStatus 检查配额状态
-func (q *quotaManager) CheckQuotaStatus(path string) error {
-	isXFS, err := q.IsXFSFilesystem(path)
+func (q *quotaManager) CheckQuotaStatus(ctx context.Context, path string) error {
+	isXFS, err := q.IsXFSFilesystem(ctx, path)
 	if err != nil {
 		return err
 	}
 	if !isXFS {
-		return fmt.Errorf("path %s is not on an XFS filesystem", path)
+		return fmt.Errorf("path %s: %w", path, ErrNotXFS)
+	}
+
+	mount, err := q.findMount(path)
+	if err != nil {
+		return err
+	}
+	if !mount.QuotaOptions().Any() {
+		return fmt.Errorf("%w on %s (mount with uquota, gquota or pquota)", ErrQuotaNotEnabled, mount.MountPoint)
 	}
 	return nil
 }
 
 // IsXFSFilesystem 检查是否为XFS文件系统
-func (q *quotaManager) IsXFSFilesystem(path string) (bool, error) {
+func (q *quotaManager) IsXFSFilesystem(ctx context.Context, path string) (bool, error) {
 	var stat syscall.Statfs_t
 	if err := syscall.Statfs(path, &stat); err != nil {
 		return false, err
@@ -275,21 +523,34 @@ func (q *quotaManager) IsXFSFilesystem(path string) (bool, error) {
 	return stat.Type == XFS_SUPER_MAGIC, nil
 }
 
-// GetFilesystemInfo 获取文件系统信息
-func (q *quotaManager) GetFilesystemInfo(path string) (map[string]interface{}, error) {
+// GetFilesystemInfo 获取文件系统信息，XFS文件系统同时通过 XFS_IOC_FSGEOMETRY 获取几何信息
+// 几何信息获取失败时不返回错误，Geometry 为空并在 GeometryError 中记录原因
+func (q *quotaManager) GetFilesystemInfo(ctx context.Context, path string) (*FilesystemInfo, error) {
 	var stat syscall.Statfs_t
 	if err := syscall.Statfs(path, &stat); err != nil {
-		return nil, err
+		return nil, &QuotaError{Op: "statfs", Path: path, Err: err}
+	}
+
+	bsize := utils.Size(stat.Bsize)
+	info := &FilesystemInfo{
+		Path:        path,
+		Type:        int64(stat.Type),
+		IsXFS:       stat.Type == XFS_SUPER_MAGIC,
+		BlockSize:   uint64(stat.Bsize),
+		TotalSize:   utils.Size(stat.Blocks) * bsize,
+		FreeSize:    utils.Size(stat.Bfree) * bsize,
+		AvailSize:   utils.Size(stat.Bavail) * bsize,
+		TotalInodes: stat.Files,
+		FreeInodes:  stat.Ffree,
 	}
 
-	info := map[string]interface{}{
-		"type":         fmt.Sprintf("0x%X", stat.Type),
-		"block_size":   stat.Bsize,
-		"total_size":   FormatSize(uint64(stat.Blocks) * uint64(stat.Bsize)),
-		"free_size":    FormatSize(uint64(stat.Bavail) * uint64(stat.Bsize)),
-		"used_size":    FormatSize(uint64(stat.Blocks-stat.Bavail) * uint64(stat.Bsize)),
-		"total_inodes": stat.Files,
-		"free_inodes":  stat.Ffree,
+	if info.IsXFS {
+		geom, err := GetFsGeometry(path)
+		if err != nil {
+			info.GeometryError = (&QuotaError{Op: "geometry", Path: path, Err: err}).Error()
+		} else {
+			info.Geometry = NewGeometry(geom)
+		}
 	}
 
 	return info, nil
@@ -297,24 +558,49 @@ func (q *quotaManager) GetFilesystemInfo(path string) (map[string]interface{}, e
 
 // 辅助函数
 
-// getDeviceFromPath 从路径获取设备名
-func (q *quotaManager) getDeviceFromPath(path string) (string, error) {
-	absPath, err := filepath.Abs(path)
+// quotaInfo 转换 fs_disk_quota 并填充名称，名称无法解析时留空
+func (q *quotaManager) quotaInfo(quotaType QuotaType, path, device string, dq *FsDiskQuota) *QuotaInfo {
+	info := newQuotaInfo(quotaType, path, device, dq)
+	if name, err := q.names.LookupName(quotaType, info.ID); err == nil {
+		info.Name = name
+	}
+	return info
+}
+
+// findMount 查找路径所在的挂载点，不是XFS时返回 ErrNotXFS
+func (q *quotaManager) findMount(path string) (*MountInfo, error) {
+	resolved, err := ResolvePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	table, err := LoadMountTable(q.mountInfoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	mount, err := table.FindMount(resolved)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if !mount.IsXFS() {
+		return nil, fmt.Errorf("%s is on %s filesystem %s: %w", path, mount.FSType, mount.MountPoint, ErrNotXFS)
 	}
+	return mount, nil
+}
 
-	// 读取 /proc/mounts 查找挂载点
-	file, err := os.Open("/proc/mounts")
+// setQlim 对指定ID执行 Q_XSETQLIM
+func (q *quotaManager) setQlim(ctx context.Context, quotaType QuotaType, id uint32, path string, op string, dq *FsDiskQuota) error {
+	mount, err := q.findMount(path)
 	if err != nil {
-		return "", err
+		return &QuotaError{Op: op, Path: path, Err: err}
 	}
-	defer file.Close()
 
-	// 简化实现：假设设备名为 /dev/sdb1
-	// 实际实现需要解析 /proc/mounts 文件
-	_ = absPath
-	return "/dev/sdb1", nil
+	dq.ID = id
+	if err := q.backend.SetDquot(ctx, quotaType, id, mount, dq); err != nil {
+		return &QuotaError{Op: op, Path: path, Err: err}
+	}
+	return nil
 }
 
 // makeQuotaCmd 构造配额命令
@@ -329,17 +615,5 @@ func makeQuotaCmd(cmd int, quotaType QuotaType) uint32 {
 		qtype = PRJQUOTA
 	}
 
-	return uint32((cmd << 8) | qtype)
-}
-
-// quotactl 系统调用的模拟实现
-func quotactl(cmd uint32, device string, id uint32, data uintptr) error {
-	// 这里是模拟实现，实际需要调用真实的 quotactl 系统调用
-	// 在真实实现中，需要使用 syscall.Syscall6 或类似方法
-	_ = cmd
-	_ = device
-	_ = id
-	_ = data
-
-	return nil
+	return uint32((cmd << SUBCMDSHIFT) | (qtype & SUBCMDMASK))
 }
@@ -1,12 +1,15 @@
 package xfs
 
 import (
+	"encoding/json"
 	"fmt"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/xfs-quota-kit/pkg/utils"
 )
 
 func TestQuotaType_String(t *testing.T) {
@@ -27,6 +30,21 @@ func TestQuotaType_String(t *testing.T) {
 	}
 }
 
+func TestQuotaType_Text(t *testing.T) {
+	data, err := json.Marshal(BatchItem{Type: ProjectQuota, ID: 10})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"type":"project"`)
+
+	var item BatchItem
+	require.NoError(t, json.Unmarshal(data, &item))
+	assert.Equal(t, ProjectQuota, item.Type)
+
+	var qType QuotaType
+	require.NoError(t, qType.UnmarshalText([]byte("g")))
+	assert.Equal(t, GroupQuota, qType)
+	assert.Error(t, qType.UnmarshalText([]byte("other")))
+}
+
 func TestQuotaInfo_IsBlockExceeded(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -199,6 +217,115 @@ func TestQuotaInfo_InodeUsagePercent(t *testing.T) {
 	}
 }
 
+func TestQuotaInfo_GraceRemaining(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	quota := QuotaInfo{
+		BlockGraceExpires: now.Add(48 * time.Hour),
+		InodeGraceExpires: now.Add(-time.Hour),
+	}
+
+	assert.Equal(t, 48*time.Hour, quota.BlockGraceRemaining(now))
+	assert.Equal(t, -time.Hour, quota.InodeGraceRemaining(now))
+	assert.Equal(t, time.Duration(0), (&QuotaInfo{}).BlockGraceRemaining(now))
+
+	quota.InodeGraceExpires = now
+	assert.Less(t, quota.InodeGraceRemaining(now), time.Duration(0))
+}
+
+func TestNewQuotaInfo_Timers(t *testing.T) {
+	dq := FsDiskQuota{ID: 1001, BCount: 4096, BlkSoftLimit: 2048}
+	dq.SetBlockTimer(1700000000)
+	dq.RTBCount, dq.RTBHardLimit = 8192, 16384
+	dq.SetRTBlockTimer(1700000500)
+
+	quota := newQuotaInfo(UserQuota, "/mnt/xfs", "/dev/sdb1", &dq)
+	assert.Equal(t, uint32(1001), quota.ID)
+	assert.Equal(t, 2*utils.MiB, quota.BlockUsed)
+	assert.Equal(t, 1*utils.MiB, quota.BlockSoft)
+	assert.Equal(t, time.Unix(1700000000, 0), quota.BlockGraceExpires)
+	assert.True(t, quota.InodeGraceExpires.IsZero())
+	assert.Equal(t, 4*utils.MiB, quota.RTBlockUsed)
+	assert.Equal(t, 8*utils.MiB, quota.RTBlockHard)
+	assert.Equal(t, time.Unix(1700000500, 0), quota.RTBlockGraceExpires)
+}
+
+func TestQuotaInfo_RT(t *testing.T) {
+	quota := &QuotaInfo{RTBlockUsed: 512, RTBlockHard: 1024}
+	assert.False(t, quota.IsRTExceeded())
+	assert.Equal(t, 50.0, quota.RTUsagePercent())
+	assert.False(t, quota.IsBlockExceeded())
+
+	quota.RTBlockUsed = 1024
+	assert.True(t, quota.IsRTExceeded())
+
+	quota = &QuotaInfo{RTBlockUsed: 1024}
+	assert.False(t, quota.IsRTExceeded())
+	assert.Equal(t, 0.0, quota.RTUsagePercent())
+}
+
+func TestQuotaLimits_Merge(t *testing.T) {
+	current := QuotaLimits{BlockSoft: utils.GiB, BlockHard: 2 * utils.GiB, InodeHard: 1000, RTBlockHard: utils.GiB}
+
+	limits := QuotaLimits{BlockSoft: 3 * utils.GiB, InodeHard: 0, Fields: LimitBlockSoft | LimitInodeHard}
+	assert.Equal(t, QuotaLimits{BlockSoft: 3 * utils.GiB, BlockHard: 2 * utils.GiB, RTBlockHard: utils.GiB}, limits.Merge(current))
+	assert.Equal(t, uint16(FS_DQ_BSOFT|FS_DQ_IHARD), limitFieldMask(limits))
+
+	// 未指定字段时设置全部限制
+	limits = QuotaLimits{BlockSoft: 3 * utils.GiB}
+	assert.Equal(t, limits, limits.Merge(current))
+	assert.Equal(t, uint16(FS_DQ_LIMIT_MASK), limitFieldMask(limits))
+}
+
+func TestLimitFields_Text(t *testing.T) {
+	data, err := json.Marshal(QuotaLimits{BlockHard: utils.GiB, Fields: LimitBlockHard | LimitRTBlockSoft})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"fields":"block_hard,rt_block_soft"`)
+
+	var limits QuotaLimits
+	require.NoError(t, json.Unmarshal(data, &limits))
+	assert.Equal(t, LimitBlockHard|LimitRTBlockSoft, limits.Fields)
+
+	data, err = json.Marshal(QuotaLimits{})
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "fields")
+
+	var fields LimitFields
+	assert.Error(t, fields.UnmarshalText([]byte("block_soft,blocks")))
+}
+
+func TestQuotaReport_Add(t *testing.T) {
+	report := &QuotaReport{}
+
+	report.Add(&QuotaInfo{BlockUsed: 100, BlockHard: 1000})
+	report.Add(&QuotaInfo{BlockUsed: 900, BlockHard: 1000})
+	report.Add(&QuotaInfo{InodeUsed: 10, InodeHard: 10})
+	report.Add(&QuotaInfo{RTBlockUsed: 2048, RTBlockHard: 1024})
+	report.Add(&QuotaInfo{RTBlockUsed: 900, RTBlockHard: 1000})
+	report.Add(&QuotaInfo{})
+
+	assert.Equal(t, 6, report.TotalQuotas)
+	assert.Equal(t, 2, report.OverQuotas)
+	assert.Equal(t, 2, report.WarningQuotas)
+	assert.Empty(t, report.Quotas)
+}
+
+func TestMultiReport(t *testing.T) {
+	home := &QuotaReport{Name: "home", Filesystem: "/home", TotalQuotas: 3, OverQuotas: 1}
+	data := &QuotaReport{Name: "data", Filesystem: "/data", TotalQuotas: 2, WarningQuotas: 2}
+
+	var report MultiReport
+	report.AddReport(home)
+	report.AddReport(data)
+	report.AddError("scratch", "/scratch", syscall.ENOENT)
+
+	assert.Equal(t, []*QuotaReport{home, data}, report.Filesystems)
+	assert.Equal(t, 5, report.TotalQuotas)
+	assert.Equal(t, 1, report.OverQuotas)
+	assert.Equal(t, 2, report.WarningQuotas)
+	require.Len(t, report.Failed, 1)
+	assert.Equal(t, FilesystemError{Name: "scratch", Filesystem: "/scratch", Error: "no such file or directory"}, report.Failed[0])
+}
+
 func TestFormatSize(t *testing.T) {
 	tests := []struct {
 		bytes    uint64
@@ -206,12 +333,12 @@ func TestFormatSize(t *testing.T) {
 	}{
 		{0, "0 B"},
 		{100, "100 B"},
-		{1024, "1.0 KB"},
-		{1536, "1.5 KB"},
-		{1048576, "1.0 MB"},
-		{1073741824, "1.0 GB"},
-		{1099511627776, "1.0 TB"},
-		{1125899906842624, "1.0 PB"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{1048576, "1.0 MiB"},
+		{1073741824, "1.0 GiB"},
+		{1099511627776, "1.0 TiB"},
+		{1125899906842624, "1.0 PiB"},
 	}
 
 	for _, tt := range tests {
@@ -300,13 +427,17 @@ func (m *mockQuotaManager) GetAllQuotas(quotaType QuotaType, path string) ([]Quo
 	return quotas, nil
 }
 
-func (m *mockQuotaManager) SetBatchQuotas(quotaType QuotaType, path string, quotas map[uint32]QuotaLimits) error {
-	for id, limits := range quotas {
-		if err := m.SetQuota(quotaType, id, path, limits); err != nil {
-			return err
+func (m *mockQuotaManager) SetBatchQuotas(quotaType QuotaType, path string, quotas map[uint32]QuotaLimits, opts BatchOptions) (*BatchResult, error) {
+	result := NewBatchResult(quotaType, path, quotas, opts)
+	for i := range result.Items {
+		item := &result.Items[i]
+		if err := m.SetQuota(quotaType, item.ID, path, item.Limits); err != nil {
+			item.SetError(BatchFailed, err)
+			continue
 		}
+		item.Status = BatchApplied
 	}
-	return nil
+	return result, result.Err()
 }
 
 func (m *mockQuotaManager) CreateProject(name string, path string) (*ProjectInfo, error) {
@@ -347,14 +478,17 @@ func (m *mockQuotaManager) IsXFSFilesystem(path string) (bool, error) {
 	return true, nil
 }
 
-func (m *mockQuotaManager) GetFilesystemInfo(path string) (map[string]interface{}, error) {
-	return map[string]interface{}{
-		"type":         "0x58465342",
-		"total_size":   "100 GB",
-		"free_size":    "50 GB",
-		"used_size":    "50 GB",
-		"total_inodes": 1000000,
-		"free_inodes":  500000,
+func (m *mockQuotaManager) GetFilesystemInfo(path string) (*FilesystemInfo, error) {
+	return &FilesystemInfo{
+		Path:        path,
+		Type:        XFS_SUPER_MAGIC,
+		IsXFS:       true,
+		BlockSize:   4096,
+		TotalSize:   100 * utils.GiB,
+		FreeSize:    50 * utils.GiB,
+		AvailSize:   50 * utils.GiB,
+		TotalInodes: 1000000,
+		FreeInodes:  500000,
 	}, nil
 }
 
@@ -381,8 +515,8 @@ func TestMockQuotaManager(t *testing.T) {
 	assert.Equal(t, uint32(1001), quota.ID)
 	assert.Equal(t, UserQuota, quota.Type)
 	assert.Equal(t, "/mnt/xfs", quota.Path)
-	assert.Equal(t, uint64(1024), quota.BlockSoft)
-	assert.Equal(t, uint64(2048), quota.BlockHard)
+	assert.Equal(t, utils.Size(1024), quota.BlockSoft)
+	assert.Equal(t, utils.Size(2048), quota.BlockHard)
 
 	// Test GetAllQuotas
 	quotas, err := manager.GetAllQuotas(UserQuota, "/mnt/xfs")
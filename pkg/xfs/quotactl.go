@@ -0,0 +1,206 @@
+package xfs
+
+import (
+	"math"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/xfs-quota-kit/pkg/utils"
+)
+
+const (
+	// fs_disk_quota 版本
+	FS_DQUOT_VERSION = 1
+
+	// fs_disk_quota.d_flags 配额类型标志
+	FS_USER_QUOTA  = 1 << 0
+	FS_PROJ_QUOTA  = 1 << 1
+	FS_GROUP_QUOTA = 1 << 2
+
+	// fs_disk_quota.d_fieldmask 字段掩码
+	FS_DQ_ISOFT      = 1 << 0
+	FS_DQ_IHARD      = 1 << 1
+	FS_DQ_BSOFT      = 1 << 2
+	FS_DQ_BHARD      = 1 << 3
+	FS_DQ_RTBSOFT    = 1 << 4
+	FS_DQ_RTBHARD    = 1 << 5
+	FS_DQ_LIMIT_MASK = FS_DQ_ISOFT | FS_DQ_IHARD | FS_DQ_BSOFT | FS_DQ_BHARD | FS_DQ_RTBSOFT | FS_DQ_RTBHARD
+
+	FS_DQ_BTIMER     = 1 << 6
+	FS_DQ_ITIMER     = 1 << 7
+	FS_DQ_RTBTIMER   = 1 << 8
+	FS_DQ_TIMER_MASK = FS_DQ_BTIMER | FS_DQ_ITIMER | FS_DQ_RTBTIMER
+
+	FS_DQ_BWARNS     = 1 << 9
+	FS_DQ_IWARNS     = 1 << 10
+	FS_DQ_RTBWARNS   = 1 << 11
+	FS_DQ_WARNS_MASK = FS_DQ_BWARNS | FS_DQ_IWARNS | FS_DQ_RTBWARNS
+
+	FS_DQ_BCOUNT    = 1 << 12
+	FS_DQ_ICOUNT    = 1 << 13
+	FS_DQ_RTBCOUNT  = 1 << 14
+	FS_DQ_ACCT_MASK = FS_DQ_BCOUNT | FS_DQ_ICOUNT | FS_DQ_RTBCOUNT
+
+	// 计时器使用高8位扩展 (bigtime)
+	FS_DQ_BIGTIME = 1 << 15
+
+	// XFS 配额以 512 字节的基本块 (basic block) 为单位
+	BBSHIFT = 9
+	BBSIZE  = 1 << BBSHIFT
+)
+
+// FsDiskQuota 对应内核 struct fs_disk_quota (linux/dqblk_xfs.h)
+// 字段顺序和大小必须与内核ABI保持一致，总大小为112字节
+type FsDiskQuota struct {
+	Version      int8   // d_version 结构体版本
+	Flags        int8   // d_flags 配额类型 FS_{USER,PROJ,GROUP}_QUOTA
+	FieldMask    uint16 // d_fieldmask 字段掩码
+	ID           uint32 // d_id 用户/组/项目ID
+	BlkHardLimit uint64 // d_blk_hardlimit 块硬限制 (BB)
+	BlkSoftLimit uint64 // d_blk_softlimit 块软限制 (BB)
+	InoHardLimit uint64 // d_ino_hardlimit inode硬限制
+	InoSoftLimit uint64 // d_ino_softlimit inode软限制
+	BCount       uint64 // d_bcount 已使用块数 (BB)
+	ICount       uint64 // d_icount 已使用inode数
+	ITimer       int32  // d_itimer inode宽限到期时间
+	BTimer       int32  // d_btimer 块宽限到期时间
+	IWarns       uint16 // d_iwarns inode警告次数
+	BWarns       uint16 // d_bwarns 块警告次数
+	ITimerHi     int8   // d_itimer_hi inode计时器高8位
+	BTimerHi     int8   // d_btimer_hi 块计时器高8位
+	RTBTimerHi   int8   // d_rtbtimer_hi 实时块计时器高8位
+	Padding2     int8   // d_padding2
+	RTBHardLimit uint64 // d_rtb_hardlimit 实时块硬限制 (BB)
+	RTBSoftLimit uint64 // d_rtb_softlimit 实时块软限制 (BB)
+	RTBCount     uint64 // d_rtbcount 已使用实时块数 (BB)
+	RTBTimer     int32  // d_rtbtimer 实时块宽限到期时间
+	RTBWarns     uint16 // d_rtbwarns 实时块警告次数
+	Padding3     int16  // d_padding3
+	Padding4     [8]byte
+}
+
+// BlockTimer 获取块宽限到期时间 (Unix 秒)
+func (d *FsDiskQuota) BlockTimer() int64 {
+	return d.timer(d.BTimer, d.BTimerHi)
+}
+
+// InodeTimer 获取inode宽限到期时间 (Unix 秒)
+func (d *FsDiskQuota) InodeTimer() int64 {
+	return d.timer(d.ITimer, d.ITimerHi)
+}
+
+// RTBlockTimer 获取实时块宽限到期时间 (Unix 秒)
+func (d *FsDiskQuota) RTBlockTimer() int64 {
+	return d.timer(d.RTBTimer, d.RTBTimerHi)
+}
+
+// SetBlockTimer 设置块宽限到期时间
+func (d *FsDiskQuota) SetBlockTimer(t int64) {
+	d.BTimer, d.BTimerHi = d.splitTimer(t)
+	d.FieldMask |= FS_DQ_BTIMER
+}
+
+// SetInodeTimer 设置inode宽限到期时间
+func (d *FsDiskQuota) SetInodeTimer(t int64) {
+	d.ITimer, d.ITimerHi = d.splitTimer(t)
+	d.FieldMask |= FS_DQ_ITIMER
+}
+
+// SetRTBlockTimer 设置实时块宽限到期时间
+func (d *FsDiskQuota) SetRTBlockTimer(t int64) {
+	d.RTBTimer, d.RTBTimerHi = d.splitTimer(t)
+	d.FieldMask |= FS_DQ_RTBTIMER
+}
+
+// timer 按内核 copy_from_xfs_dqblk_ts 的规则合并计时器
+func (d *FsDiskQuota) timer(lo int32, hi int8) int64 {
+	if d.FieldMask&FS_DQ_BIGTIME != 0 {
+		return int64(uint32(lo)) | int64(hi)<<32
+	}
+	return int64(lo)
+}
+
+// splitTimer 拆分计时器，超出32位范围时启用 bigtime
+func (d *FsDiskQuota) splitTimer(t int64) (int32, int8) {
+	if t > math.MaxInt32 || t < math.MinInt32 {
+		d.FieldMask |= FS_DQ_BIGTIME
+	}
+	if d.FieldMask&FS_DQ_BIGTIME != 0 {
+		return int32(uint32(t)), int8(t >> 32)
+	}
+	return int32(t), 0
+}
+
+// quotactl 调用 quotactl(2) 系统调用
+func quotactl(cmd uint32, device string, id uint32, addr unsafe.Pointer) error {
+	special, err := syscall.BytePtrFromString(device)
+	if err != nil {
+		return err
+	}
+
+	_, _, errno := syscall.Syscall6(syscall.SYS_QUOTACTL,
+		uintptr(cmd), uintptr(unsafe.Pointer(special)), uintptr(id), uintptr(addr), 0, 0)
+	if errno != 0 {
+		return NewSyscallError("quotactl", errno)
+	}
+
+	return nil
+}
+
+// quotaTypeFlag 获取配额类型对应的 d_flags
+func quotaTypeFlag(quotaType QuotaType) int8 {
+	switch quotaType {
+	case UserQuota:
+		return FS_USER_QUOTA
+	case GroupQuota:
+		return FS_GROUP_QUOTA
+	case ProjectQuota:
+		return FS_PROJ_QUOTA
+	default:
+		return 0
+	}
+}
+
+// bbToKB 将基本块数转换为KB，不足1KB的部分向上取整，避免非0限制变为0（不限制）
+func bbToKB(bb uint64) uint64 {
+	const perKB = 1 << (10 - BBSHIFT)
+	return (bb + perKB - 1) / perKB
+}
+
+// kbToBB 将KB转换为基本块数
+func kbToBB(kb uint64) uint64 {
+	return kb << (10 - BBSHIFT)
+}
+
+// newQuotaInfo 将 fs_disk_quota 转换为 QuotaInfo
+func newQuotaInfo(quotaType QuotaType, path, device string, dq *FsDiskQuota) *QuotaInfo {
+	return &QuotaInfo{
+		ID:          dq.ID,
+		Type:        quotaType,
+		Path:        path,
+		Device:      device,
+		BlockUsed:   utils.SizeFromBasicBlocks(dq.BCount),
+		BlockSoft:   utils.SizeFromBasicBlocks(dq.BlkSoftLimit),
+		BlockHard:   utils.SizeFromBasicBlocks(dq.BlkHardLimit),
+		InodeUsed:   dq.ICount,
+		InodeSoft:   dq.InoSoftLimit,
+		InodeHard:   dq.InoHardLimit,
+		RTBlockUsed: utils.SizeFromBasicBlocks(dq.RTBCount),
+		RTBlockSoft: utils.SizeFromBasicBlocks(dq.RTBSoftLimit),
+		RTBlockHard: utils.SizeFromBasicBlocks(dq.RTBHardLimit),
+		LastUpdated: time.Now(),
+
+		BlockGraceExpires:   timerToTime(dq.BlockTimer()),
+		InodeGraceExpires:   timerToTime(dq.InodeTimer()),
+		RTBlockGraceExpires: timerToTime(dq.RTBlockTimer()),
+	}
+}
+
+// timerToTime 将dquot计时器转换为时间，0表示未计时
+func timerToTime(timer int64) time.Time {
+	if timer == 0 {
+		return time.Time{}
+	}
+	return time.Unix(timer, 0)
+}
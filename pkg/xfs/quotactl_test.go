@@ -0,0 +1,54 @@
+package xfs
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFsDiskQuota_Layout(t *testing.T) {
+	var dq FsDiskQuota
+
+	// 必须与内核 struct fs_disk_quota 完全一致
+	assert.Equal(t, uintptr(112), unsafe.Sizeof(dq))
+	assert.Equal(t, uintptr(4), unsafe.Offsetof(dq.ID))
+	assert.Equal(t, uintptr(8), unsafe.Offsetof(dq.BlkHardLimit))
+	assert.Equal(t, uintptr(56), unsafe.Offsetof(dq.ITimer))
+	assert.Equal(t, uintptr(64), unsafe.Offsetof(dq.IWarns))
+	assert.Equal(t, uintptr(68), unsafe.Offsetof(dq.ITimerHi))
+	assert.Equal(t, uintptr(72), unsafe.Offsetof(dq.RTBHardLimit))
+	assert.Equal(t, uintptr(96), unsafe.Offsetof(dq.RTBTimer))
+	assert.Equal(t, uintptr(100), unsafe.Offsetof(dq.RTBWarns))
+}
+
+func TestFsDiskQuota_Timers(t *testing.T) {
+	var dq FsDiskQuota
+	dq.SetBlockTimer(1700000000)
+	assert.Equal(t, int64(1700000000), dq.BlockTimer())
+	assert.Zero(t, dq.FieldMask&FS_DQ_BIGTIME)
+	assert.NotZero(t, dq.FieldMask&FS_DQ_BTIMER)
+
+	// 超出32位范围时使用高8位
+	big := int64(1) << 33
+	dq.SetInodeTimer(big)
+	assert.NotZero(t, dq.FieldMask&FS_DQ_BIGTIME)
+	assert.Equal(t, big, dq.InodeTimer())
+	assert.Equal(t, int8(2), dq.ITimerHi)
+
+	dq.SetRTBlockTimer(42)
+	assert.Equal(t, int64(42), dq.RTBlockTimer())
+}
+
+func TestMakeQuotaCmd(t *testing.T) {
+	assert.Equal(t, uint32(0x580300), makeQuotaCmd(Q_XGETQUOTA, UserQuota))
+	assert.Equal(t, uint32(0x580401), makeQuotaCmd(Q_XSETQLIM, GroupQuota))
+	assert.Equal(t, uint32(0x580302), makeQuotaCmd(Q_XGETQUOTA, ProjectQuota))
+}
+
+func TestBasicBlockConversion(t *testing.T) {
+	assert.Equal(t, uint64(2048), kbToBB(1024))
+	assert.Equal(t, uint64(1024), bbToKB(2048))
+	assert.Equal(t, uint64(1), bbToKB(1))
+	assert.Equal(t, int8(FS_PROJ_QUOTA), quotaTypeFlag(ProjectQuota))
+}
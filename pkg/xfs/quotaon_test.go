@@ -0,0 +1,36 @@
+package xfs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuotaTypeStateFlags(t *testing.T) {
+	acct, enfd := quotaTypeStateFlags(UserQuota)
+	assert.Equal(t, uint32(FS_QUOTA_UDQ_ACCT), acct)
+	assert.Equal(t, uint32(FS_QUOTA_UDQ_ENFD), enfd)
+
+	acct, enfd = quotaTypeStateFlags(GroupQuota)
+	assert.Equal(t, uint32(FS_QUOTA_GDQ_ACCT), acct)
+	assert.Equal(t, uint32(FS_QUOTA_GDQ_ENFD), enfd)
+
+	acct, enfd = quotaTypeStateFlags(ProjectQuota)
+	assert.Equal(t, uint32(FS_QUOTA_PDQ_ACCT), acct)
+	assert.Equal(t, uint32(FS_QUOTA_PDQ_ENFD), enfd)
+}
+
+func TestQuotaMountOption(t *testing.T) {
+	assert.Equal(t, "uquota", quotaMountOption(UserQuota, true))
+	assert.Equal(t, "gqnoenforce", quotaMountOption(GroupQuota, false))
+	assert.Equal(t, "pquota", quotaMountOption(ProjectQuota, true))
+}
+
+func TestQuotaManager_SetEnforcementRequiresAccounting(t *testing.T) {
+	manager := NewQuotaManager(WithMountInfoPath("testdata/mountinfo"))
+
+	// 根文件系统以 noquota 挂载
+	err := manager.SetEnforcement(ProjectQuota, "/", true)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "remount with pquota")
+}
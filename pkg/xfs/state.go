@@ -0,0 +1,169 @@
+package xfs
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+	"time"
+
+	"github.com/xfs-quota-kit/pkg/utils"
+)
+
+const (
+	// fs_quota_statv 版本
+	FS_QSTATV_VERSION1 = 1
+
+	// fs_quota_statv.qs_flags 标志
+	FS_QUOTA_UDQ_ACCT = 1 << 0 // 用户配额统计
+	FS_QUOTA_UDQ_ENFD = 1 << 1 // 用户配额限制
+	FS_QUOTA_GDQ_ACCT = 1 << 2 // 组配额统计
+	FS_QUOTA_GDQ_ENFD = 1 << 3 // 组配额限制
+	FS_QUOTA_PDQ_ACCT = 1 << 4 // 项目配额统计
+	FS_QUOTA_PDQ_ENFD = 1 << 5 // 项目配额限制
+)
+
+// FsQfilestatv 对应内核 struct fs_qfilestatv
+type FsQfilestatv struct {
+	Ino      uint64 // qfs_ino 配额inode号
+	NBlks    uint64 // qfs_nblks 占用的文件系统块数
+	NExtents uint32 // qfs_nextents extent数量
+	Pad      uint32
+}
+
+// FsQuotaStatv 对应内核 struct fs_quota_statv，总大小为160字节
+type FsQuotaStatv struct {
+	Version      int8   // qs_version
+	Pad1         uint8  // qs_pad1
+	Flags        uint16 // qs_flags FS_QUOTA_* 标志
+	IncoreDqs    uint32 // qs_incoredqs 内存中的dquot数量
+	UQuota       FsQfilestatv
+	GQuota       FsQfilestatv
+	PQuota       FsQfilestatv
+	BTimeLimit   int32  // qs_btimelimit 默认块宽限时间（秒）
+	ITimeLimit   int32  // qs_itimelimit 默认inode宽限时间（秒）
+	RTBTimeLimit int32  // qs_rtbtimelimit 默认实时块宽限时间（秒）
+	BWarnLimit   uint16 // qs_bwarnlimit
+	IWarnLimit   uint16 // qs_iwarnlimit
+	RTBWarnLimit uint16 // qs_rtbwarnlimit
+	Pad3         uint16
+	Pad4         uint32
+	Pad2         [7]uint64
+}
+
+// QuotaTypeState 单个配额类型的状态
+type QuotaTypeState struct {
+	Accounting       bool          `json:"accounting"`          // 是否开启统计
+	Enforced         bool          `json:"enforced"`            // 是否开启限制
+	Inode            uint64        `json:"inode"`               // 配额inode号
+	FileSize         utils.Size    `json:"file_size"`           // 配额文件大小
+	FileExtents      uint32        `json:"file_extents"`        // 配额文件extent数
+	BlockGrace       time.Duration `json:"block_grace"`         // 默认块宽限时间
+	InodeGrace       time.Duration `json:"inode_grace"`         // 默认inode宽限时间
+	RTBlockGrace     time.Duration `json:"rt_block_grace"`      // 默认实时块宽限时间
+	BlockWarnLimit   uint16        `json:"block_warn_limit"`    // 块警告次数上限
+	InodeWarnLimit   uint16        `json:"inode_warn_limit"`    // inode警告次数上限
+	RTBlockWarnLimit uint16        `json:"rt_block_warn_limit"` // 实时块警告次数上限
+}
+
+// QuotaState 文件系统配额子系统状态
+type QuotaState struct {
+	Path         string         `json:"path"`          // 查询路径
+	Device       string         `json:"device"`        // 设备
+	MountPoint   string         `json:"mount_point"`   // 挂载点
+	IncoreDquots uint32         `json:"incore_dquots"` // 内存中的dquot数量
+	User         QuotaTypeState `json:"user"`          // 用户配额
+	Group        QuotaTypeState `json:"group"`         // 组配额
+	Project      QuotaTypeState `json:"project"`       // 项目配额
+}
+
+// Type 获取指定配额类型的状态
+func (s *QuotaState) Type(quotaType QuotaType) *QuotaTypeState {
+	switch quotaType {
+	case UserQuota:
+		return &s.User
+	case GroupQuota:
+		return &s.Group
+	case ProjectQuota:
+		return &s.Project
+	default:
+		return nil
+	}
+}
+
+// Problems 检查常见的配额配置问题
+func (s *QuotaState) Problems() []string {
+	var problems []string
+	for _, qt := range []QuotaType{UserQuota, GroupQuota, ProjectQuota} {
+		state := s.Type(qt)
+		if state.Accounting && !state.Enforced {
+			problems = append(problems, fmt.Sprintf("%s quota accounting is on but enforcement is off", qt))
+		}
+	}
+	if !s.User.Accounting && !s.Group.Accounting && !s.Project.Accounting {
+		problems = append(problems, "quota accounting is off for all quota types")
+	}
+	return problems
+}
+
+// GetQuotaState 通过 Q_XGETQSTATV 获取配额子系统状态
+func (q *quotaManager) GetQuotaState(ctx context.Context, path string) (*QuotaState, error) {
+	mount, err := q.findMount(path)
+	if err != nil {
+		return nil, &QuotaError{Op: "state", Path: path, Err: err}
+	}
+
+	state := &QuotaState{
+		Path:       path,
+		Device:     mount.Device(),
+		MountPoint: mount.MountPoint,
+	}
+
+	// qfs_nblks 以文件系统块为单位
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(mount.MountPoint, &stat); err != nil {
+		return nil, &QuotaError{Op: "state", Path: path, Err: err}
+	}
+	blockSize := utils.Size(stat.Bsize)
+
+	// 较新的内核按类型返回各自的默认宽限时间，因此每种类型单独查询
+	for _, qt := range []QuotaType{UserQuota, GroupQuota, ProjectQuota} {
+		statv, err := q.backend.GetStatv(ctx, qt, mount)
+		if err != nil {
+			return nil, &QuotaError{Op: "state", Path: path, Err: err}
+		}
+
+		state.IncoreDquots = statv.IncoreDqs
+		applyQuotaStatv(state, qt, statv, blockSize)
+	}
+
+	return state, nil
+}
+
+// applyQuotaStatv 将 fs_quota_statv 中与指定类型相关的字段填入状态，blockSize 为文件系统块大小
+func applyQuotaStatv(state *QuotaState, quotaType QuotaType, statv *FsQuotaStatv, blockSize utils.Size) {
+	var file FsQfilestatv
+	var acct, enfd uint16
+	switch quotaType {
+	case UserQuota:
+		file, acct, enfd = statv.UQuota, FS_QUOTA_UDQ_ACCT, FS_QUOTA_UDQ_ENFD
+	case GroupQuota:
+		file, acct, enfd = statv.GQuota, FS_QUOTA_GDQ_ACCT, FS_QUOTA_GDQ_ENFD
+	case ProjectQuota:
+		file, acct, enfd = statv.PQuota, FS_QUOTA_PDQ_ACCT, FS_QUOTA_PDQ_ENFD
+	default:
+		return
+	}
+
+	typeState := state.Type(quotaType)
+	typeState.Accounting = statv.Flags&acct != 0
+	typeState.Enforced = statv.Flags&enfd != 0
+	typeState.Inode = file.Ino
+	typeState.FileSize = utils.Size(file.NBlks) * blockSize
+	typeState.FileExtents = file.NExtents
+	typeState.BlockGrace = time.Duration(statv.BTimeLimit) * time.Second
+	typeState.InodeGrace = time.Duration(statv.ITimeLimit) * time.Second
+	typeState.RTBlockGrace = time.Duration(statv.RTBTimeLimit) * time.Second
+	typeState.BlockWarnLimit = statv.BWarnLimit
+	typeState.InodeWarnLimit = statv.IWarnLimit
+	typeState.RTBlockWarnLimit = statv.RTBWarnLimit
+}
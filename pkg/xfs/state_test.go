@@ -0,0 +1,53 @@
+package xfs
+
+import (
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xfs-quota-kit/pkg/utils"
+)
+
+func TestFsQuotaStatv_Layout(t *testing.T) {
+	var statv FsQuotaStatv
+
+	// 必须与内核 struct fs_quota_statv 完全一致
+	assert.Equal(t, uintptr(24), unsafe.Sizeof(FsQfilestatv{}))
+	assert.Equal(t, uintptr(160), unsafe.Sizeof(statv))
+	assert.Equal(t, uintptr(8), unsafe.Offsetof(statv.UQuota))
+	assert.Equal(t, uintptr(80), unsafe.Offsetof(statv.BTimeLimit))
+	assert.Equal(t, uintptr(92), unsafe.Offsetof(statv.BWarnLimit))
+	assert.Equal(t, uintptr(104), unsafe.Offsetof(statv.Pad2))
+}
+
+func TestApplyQuotaStatv(t *testing.T) {
+	statv := FsQuotaStatv{
+		Flags:      FS_QUOTA_UDQ_ACCT | FS_QUOTA_UDQ_ENFD | FS_QUOTA_PDQ_ACCT,
+		UQuota:     FsQfilestatv{Ino: 131, NBlks: 16, NExtents: 1},
+		PQuota:     FsQfilestatv{Ino: 133, NBlks: 8, NExtents: 1},
+		BTimeLimit: 7 * 24 * 3600,
+		ITimeLimit: 3600,
+		BWarnLimit: 5,
+	}
+
+	state := &QuotaState{}
+	for _, qt := range []QuotaType{UserQuota, GroupQuota, ProjectQuota} {
+		applyQuotaStatv(state, qt, &statv, 4*utils.KiB)
+	}
+
+	assert.True(t, state.User.Accounting)
+	assert.True(t, state.User.Enforced)
+	assert.Equal(t, uint64(131), state.User.Inode)
+	assert.Equal(t, 64*utils.KiB, state.User.FileSize)
+	assert.Equal(t, 7*24*time.Hour, state.User.BlockGrace)
+	assert.Equal(t, time.Hour, state.User.InodeGrace)
+	assert.Equal(t, uint16(5), state.User.BlockWarnLimit)
+
+	assert.False(t, state.Group.Accounting)
+	assert.True(t, state.Project.Accounting)
+	assert.False(t, state.Project.Enforced)
+
+	assert.Equal(t, []string{"project quota accounting is on but enforcement is off"}, state.Problems())
+	assert.Equal(t, []string{"quota accounting is off for all quota types"}, (&QuotaState{}).Problems())
+}
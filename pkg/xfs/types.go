@@ -2,7 +2,10 @@ package xfs
 
 import (
 	"fmt"
+	"strings"
 	"time"
+
+	"github.com/xfs-quota-kit/pkg/utils"
 )
 
 // QuotaType 配额类型
@@ -27,19 +30,70 @@ func (q QuotaType) String() string {
 	}
 }
 
+// MarshalText 以名称形式输出到JSON
+func (q QuotaType) MarshalText() ([]byte, error) {
+	return []byte(q.String()), nil
+}
+
+// UnmarshalText 解析配额类型名称，见 ParseQuotaType
+func (q *QuotaType) UnmarshalText(text []byte) error {
+	quotaType, err := ParseQuotaType(string(text))
+	if err != nil {
+		return err
+	}
+	*q = quotaType
+	return nil
+}
+
+// ParseQuotaType 解析配额类型名称，如 user、group、project 或 u、g、p
+func ParseQuotaType(s string) (QuotaType, error) {
+	switch strings.ToLower(s) {
+	case "user", "u":
+		return UserQuota, nil
+	case "group", "g":
+		return GroupQuota, nil
+	case "project", "p":
+		return ProjectQuota, nil
+	default:
+		return 0, fmt.Errorf("invalid quota type: %s", s)
+	}
+}
+
 // QuotaInfo 配额信息结构
 type QuotaInfo struct {
-	ID          uint32    `json:"id"`           // 用户ID/组ID/项目ID
-	Type        QuotaType `json:"type"`         // 配额类型
-	Path        string    `json:"path"`         // 路径
-	Device      string    `json:"device"`       // 设备
-	BlockUsed   uint64    `json:"block_used"`   // 已使用块数 (KB)
-	BlockSoft   uint64    `json:"block_soft"`   // 块软限制 (KB)
-	BlockHard   uint64    `json:"block_hard"`   // 块硬限制 (KB)
-	InodeUsed   uint64    `json:"inode_used"`   // 已使用inode数
-	InodeSoft   uint64    `json:"inode_soft"`   // inode软限制
-	InodeHard   uint64    `json:"inode_hard"`   // inode硬限制
-	LastUpdated time.Time `json:"last_updated"` // 最后更新时间
+	ID          uint32     `json:"id"`           // 用户ID/组ID/项目ID
+	Type        QuotaType  `json:"type"`         // 配额类型
+	Path        string     `json:"path"`         // 路径
+	Device      string     `json:"device"`       // 设备
+	BlockUsed   utils.Size `json:"block_used"`   // 已使用空间
+	BlockSoft   utils.Size `json:"block_soft"`   // 块软限制
+	BlockHard   utils.Size `json:"block_hard"`   // 块硬限制
+	InodeUsed   uint64     `json:"inode_used"`   // 已使用inode数
+	InodeSoft   uint64     `json:"inode_soft"`   // inode软限制
+	InodeHard   uint64     `json:"inode_hard"`   // inode硬限制
+	LastUpdated time.Time  `json:"last_updated"` // 最后更新时间
+
+	Name string `json:"name,omitempty"` // 用户名/组名/项目名，无法解析时为空
+
+	RTBlockUsed utils.Size `json:"rt_block_used"` // 已使用实时设备空间
+	RTBlockSoft utils.Size `json:"rt_block_soft"` // 实时块软限制
+	RTBlockHard utils.Size `json:"rt_block_hard"` // 实时块硬限制
+
+	BlockGraceExpires   time.Time `json:"block_grace_expires"`    // 块宽限到期时间，未超过软限制时为零值
+	InodeGraceExpires   time.Time `json:"inode_grace_expires"`    // inode宽限到期时间，未超过软限制时为零值
+	RTBlockGraceExpires time.Time `json:"rt_block_grace_expires"` // 实时块宽限到期时间，未超过软限制时为零值
+}
+
+// Limits 获取配额限制
+func (q *QuotaInfo) Limits() QuotaLimits {
+	return QuotaLimits{
+		BlockSoft:   q.BlockSoft,
+		BlockHard:   q.BlockHard,
+		InodeSoft:   q.InodeSoft,
+		InodeHard:   q.InodeHard,
+		RTBlockSoft: q.RTBlockSoft,
+		RTBlockHard: q.RTBlockHard,
+	}
 }
 
 // IsBlockExceeded 检查块使用是否超限
@@ -52,6 +106,11 @@ func (q *QuotaInfo) IsInodeExceeded() bool {
 	return q.InodeHard > 0 && q.InodeUsed >= q.InodeHard
 }
 
+// IsRTExceeded 检查实时块使用是否超限
+func (q *QuotaInfo) IsRTExceeded() bool {
+	return q.RTBlockHard > 0 && q.RTBlockUsed >= q.RTBlockHard
+}
+
 // BlockUsagePercent 获取块使用百分比
 func (q *QuotaInfo) BlockUsagePercent() float64 {
 	if q.BlockHard == 0 {
@@ -68,12 +127,147 @@ func (q *QuotaInfo) InodeUsagePercent() float64 {
 	return float64(q.InodeUsed) / float64(q.InodeHard) * 100.0
 }
 
+// RTUsagePercent 获取实时块使用百分比
+func (q *QuotaInfo) RTUsagePercent() float64 {
+	if q.RTBlockHard == 0 {
+		return 0.0
+	}
+	return float64(q.RTBlockUsed) / float64(q.RTBlockHard) * 100.0
+}
+
+// BlockGraceRemaining 获取块宽限剩余时间，未计时返回0，已过期返回负值
+func (q *QuotaInfo) BlockGraceRemaining(now time.Time) time.Duration {
+	return graceRemaining(q.BlockGraceExpires, now)
+}
+
+// InodeGraceRemaining 获取inode宽限剩余时间，未计时返回0，已过期返回负值
+func (q *QuotaInfo) InodeGraceRemaining(now time.Time) time.Duration {
+	return graceRemaining(q.InodeGraceExpires, now)
+}
+
+// RTBlockGraceRemaining 获取实时块宽限剩余时间，未计时返回0，已过期返回负值
+func (q *QuotaInfo) RTBlockGraceRemaining(now time.Time) time.Duration {
+	return graceRemaining(q.RTBlockGraceExpires, now)
+}
+
+func graceRemaining(expires, now time.Time) time.Duration {
+	if expires.IsZero() {
+		return 0
+	}
+	remaining := expires.Sub(now)
+	if remaining == 0 {
+		remaining = -1 // 恰好到期视为已过期
+	}
+	return remaining
+}
+
 // QuotaLimits 配额限制结构
 type QuotaLimits struct {
-	BlockSoft uint64 `json:"block_soft"` // 块软限制 (KB)
-	BlockHard uint64 `json:"block_hard"` // 块硬限制 (KB)
-	InodeSoft uint64 `json:"inode_soft"` // inode软限制
-	InodeHard uint64 `json:"inode_hard"` // inode硬限制
+	BlockSoft utils.Size `json:"block_soft"` // 块软限制
+	BlockHard utils.Size `json:"block_hard"` // 块硬限制
+	InodeSoft uint64     `json:"inode_soft"` // inode软限制
+	InodeHard uint64     `json:"inode_hard"` // inode硬限制
+
+	RTBlockSoft utils.Size `json:"rt_block_soft"` // 实时块软限制
+	RTBlockHard utils.Size `json:"rt_block_hard"` // 实时块硬限制
+
+	// Fields 要设置的限制，其余限制保持不变，为0时设置全部限制
+	Fields LimitFields `json:"fields,omitempty"`
+}
+
+// Has 检查是否设置指定的限制
+func (l QuotaLimits) Has(field LimitFields) bool {
+	return l.Fields == 0 || l.Fields&field != 0
+}
+
+// Merge 将要设置的限制应用到当前限制上，返回的限制包含全部字段
+func (l QuotaLimits) Merge(current QuotaLimits) QuotaLimits {
+	merged := current
+	merged.Fields = 0
+	if l.Has(LimitBlockSoft) {
+		merged.BlockSoft = l.BlockSoft
+	}
+	if l.Has(LimitBlockHard) {
+		merged.BlockHard = l.BlockHard
+	}
+	if l.Has(LimitInodeSoft) {
+		merged.InodeSoft = l.InodeSoft
+	}
+	if l.Has(LimitInodeHard) {
+		merged.InodeHard = l.InodeHard
+	}
+	if l.Has(LimitRTBlockSoft) {
+		merged.RTBlockSoft = l.RTBlockSoft
+	}
+	if l.Has(LimitRTBlockHard) {
+		merged.RTBlockHard = l.RTBlockHard
+	}
+	return merged
+}
+
+// LimitFields QuotaLimits 中的限制字段集合
+type LimitFields uint8
+
+const (
+	LimitBlockSoft LimitFields = 1 << iota
+	LimitBlockHard
+	LimitInodeSoft
+	LimitInodeHard
+	LimitRTBlockSoft
+	LimitRTBlockHard
+
+	LimitAll = LimitBlockSoft | LimitBlockHard | LimitInodeSoft | LimitInodeHard | LimitRTBlockSoft | LimitRTBlockHard
+)
+
+// limitFieldNames 与 QuotaLimits 的JSON字段名一致
+var limitFieldNames = []struct {
+	field LimitFields
+	name  string
+}{
+	{LimitBlockSoft, "block_soft"},
+	{LimitBlockHard, "block_hard"},
+	{LimitInodeSoft, "inode_soft"},
+	{LimitInodeHard, "inode_hard"},
+	{LimitRTBlockSoft, "rt_block_soft"},
+	{LimitRTBlockHard, "rt_block_hard"},
+}
+
+func (f LimitFields) String() string {
+	var names []string
+	for _, n := range limitFieldNames {
+		if f&n.field != 0 {
+			names = append(names, n.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// MarshalText 以逗号分隔的字段名输出到JSON
+func (f LimitFields) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText 解析逗号分隔的字段名，如 block_soft,block_hard
+func (f *LimitFields) UnmarshalText(text []byte) error {
+	var fields LimitFields
+	for _, name := range strings.Split(string(text), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, n := range limitFieldNames {
+			if n.name == name {
+				fields |= n.field
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("invalid limit field %q", name)
+		}
+	}
+	*f = fields
+	return nil
 }
 
 // ProjectInfo 项目信息结构
@@ -85,12 +279,94 @@ type ProjectInfo struct {
 
 // QuotaReport 配额报告结构
 type QuotaReport struct {
-	Filesystem    string      `json:"filesystem"`     // 文件系统路径
-	TotalQuotas   int         `json:"total_quotas"`   // 总配额数
-	OverQuotas    int         `json:"over_quotas"`    // 超限配额数
-	WarningQuotas int         `json:"warning_quotas"` // 警告配额数
-	GeneratedAt   time.Time   `json:"generated_at"`   // 生成时间
-	Quotas        []QuotaInfo `json:"quotas"`         // 配额详情
+	Name          string      `json:"name,omitempty"`  // 配置中的文件系统名称
+	Filesystem    string      `json:"filesystem"`      // 文件系统路径
+	TotalQuotas   int         `json:"total_quotas"`    // 总配额数
+	OverQuotas    int         `json:"over_quotas"`     // 超限配额数
+	WarningQuotas int         `json:"warning_quotas"`  // 警告配额数
+	GeneratedAt   time.Time   `json:"generated_at"`    // 生成时间
+	State         *QuotaState `json:"state,omitempty"` // 配额子系统状态
+	Quotas        []QuotaInfo `json:"quotas"`          // 配额详情
+}
+
+// Add 将配额计入报告统计
+func (r *QuotaReport) Add(quota *QuotaInfo) {
+	r.TotalQuotas++
+	if quota.IsBlockExceeded() || quota.IsInodeExceeded() || quota.IsRTExceeded() {
+		r.OverQuotas++
+	} else if quota.BlockUsagePercent() > 80 || quota.InodeUsagePercent() > 80 || quota.RTUsagePercent() > 80 {
+		r.WarningQuotas++
+	}
+}
+
+// MultiReport 多个文件系统的汇总报告，按文件系统分组
+type MultiReport struct {
+	Filesystems   []*QuotaReport    `json:"filesystems"`      // 各文件系统的报告
+	Failed        []FilesystemError `json:"failed,omitempty"` // 无法生成报告的文件系统（如未挂载）
+	TotalQuotas   int               `json:"total_quotas"`     // 总配额数
+	OverQuotas    int               `json:"over_quotas"`      // 超限配额数
+	WarningQuotas int               `json:"warning_quotas"`   // 警告配额数
+	GeneratedAt   time.Time         `json:"generated_at"`     // 生成时间
+}
+
+// FilesystemError 某个文件系统的失败信息
+type FilesystemError struct {
+	Name       string `json:"name,omitempty"` // 配置中的文件系统名称
+	Filesystem string `json:"filesystem"`     // 文件系统路径
+	Error      string `json:"error"`          // 错误信息
+}
+
+// AddReport 将文件系统的报告计入汇总统计
+func (r *MultiReport) AddReport(report *QuotaReport) {
+	r.Filesystems = append(r.Filesystems, report)
+	r.TotalQuotas += report.TotalQuotas
+	r.OverQuotas += report.OverQuotas
+	r.WarningQuotas += report.WarningQuotas
+}
+
+// AddError 记录无法生成报告的文件系统
+func (r *MultiReport) AddError(name, path string, err error) {
+	r.Failed = append(r.Failed, FilesystemError{Name: name, Filesystem: path, Error: err.Error()})
+}
+
+// GraceTimes 文件系统默认宽限时间，零值表示不修改
+type GraceTimes struct {
+	Block   time.Duration `json:"block"`    // 块宽限时间
+	Inode   time.Duration `json:"inode"`    // inode宽限时间
+	RTBlock time.Duration `json:"rt_block"` // 实时块宽限时间
+}
+
+// GraceTimers 单个ID的宽限到期时间，零值表示不修改
+type GraceTimers struct {
+	Block   time.Time `json:"block"`    // 块宽限到期时间
+	Inode   time.Time `json:"inode"`    // inode宽限到期时间
+	RTBlock time.Time `json:"rt_block"` // 实时块宽限到期时间
+}
+
+// CreateProjectOptions 创建项目选项
+type CreateProjectOptions struct {
+	ID uint32 // 指定项目ID，0表示由分配器自动分配
+}
+
+// FilesystemInfo 文件系统信息，大小均以字节为单位
+type FilesystemInfo struct {
+	Path        string     `json:"path"`               // 查询路径
+	Type        int64      `json:"type"`               // statfs 返回的文件系统魔数
+	IsXFS       bool       `json:"is_xfs"`             // 是否为XFS
+	BlockSize   uint64     `json:"block_size"`         // 块大小
+	TotalSize   utils.Size `json:"total_size"`         // 总大小
+	FreeSize    utils.Size `json:"free_size"`          // 剩余空间（包括只有root可用的保留空间）
+	AvailSize   utils.Size `json:"avail_size"`         // 普通用户可用空间
+	TotalInodes uint64     `json:"total_inodes"`       // inode总数
+	FreeInodes  uint64     `json:"free_inodes"`        // 空闲inode数
+	Geometry    *Geometry  `json:"geometry,omitempty"` // XFS几何信息，非XFS或获取失败时为空
+
+	GeometryError string `json:"geometry_error,omitempty"` // 获取XFS几何信息失败的原因（如 ENOTTY、EPERM）
+}
+
+// UsedSize 已用空间
+func (i *FilesystemInfo) UsedSize() utils.Size {
+	return i.TotalSize - i.FreeSize
 }
 
 // QuotaError 配额操作错误
@@ -108,18 +384,7 @@ func (e *QuotaError) Unwrap() error {
 	return e.Err
 }
 
-// FormatSize 格式化字节数为可读格式
+// FormatSize 格式化字节数为可读格式，使用IEC单位，如 "1.5 GiB"
 func FormatSize(bytes uint64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
-	}
-
-	div, exp := uint64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
-	}
-
-	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+	return utils.Size(bytes).Human()
 }
@@ -0,0 +1,526 @@
+package xfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+
+	"github.com/xfs-quota-kit/pkg/utils"
+)
+
+const (
+	// DefaultVerifyWorkers 默认并发遍历目录的goroutine数
+	DefaultVerifyWorkers = 8
+	// DefaultVerifyMaxFiles 默认最多列出的项目ID不一致的文件数
+	DefaultVerifyMaxFiles = 100
+)
+
+// VerifyOptions 配额统计校验选项
+type VerifyOptions struct {
+	Workers  int // 并发遍历目录的goroutine数，0表示 DefaultVerifyWorkers
+	MaxFiles int // 最多列出的项目ID不一致的文件数，0表示 DefaultVerifyMaxFiles，负数表示不限制
+
+	// Progress 每统计一个文件后调用，调用来自多个goroutine但不会同时发生
+	Progress func(path string, scanned uint64)
+}
+
+// Usage 用量
+type Usage struct {
+	Blocks   utils.Size `json:"blocks"`
+	Inodes   uint64     `json:"inodes"`
+	RTBlocks utils.Size `json:"rt_blocks"`
+}
+
+// AccountingCheck 遍历目录树得到的用量与dquot中记录的用量的比较结果
+type AccountingCheck struct {
+	Type     QuotaType `json:"type"`
+	ID       uint32    `json:"id"`
+	Name     string    `json:"name,omitempty"`
+	Device   string    `json:"device"`
+	Scanned  Usage     `json:"scanned"`  // 遍历统计的用量
+	Reported Usage     `json:"reported"` // dquot中的用量
+	Exact    bool      `json:"exact"`    // 遍历覆盖了该ID的全部文件，用量应当相等
+	OK       bool      `json:"ok"`
+}
+
+// NewAccountingCheck 比较用量：exact为true时要求相等，
+// 否则只遍历了部分文件，遍历统计的用量不应超过dquot中的用量
+func NewAccountingCheck(quotaType QuotaType, id uint32, scanned, reported Usage, exact bool) AccountingCheck {
+	ok := scanned == reported
+	if !exact {
+		ok = scanned.Blocks <= reported.Blocks && scanned.Inodes <= reported.Inodes && scanned.RTBlocks <= reported.RTBlocks
+	}
+	return AccountingCheck{Type: quotaType, ID: id, Scanned: scanned, Reported: reported, Exact: exact, OK: ok}
+}
+
+// MismatchedFile 项目ID与所属项目不一致的文件，或缺少PROJINHERIT的目录
+type MismatchedFile struct {
+	Path           string `json:"path"`
+	ProjectID      uint32 `json:"project_id"`
+	MissingInherit bool   `json:"missing_inherit,omitempty"` // 目录没有PROJINHERIT，新文件不会继承项目ID
+}
+
+// VerifyResult 项目配额统计校验结果
+type VerifyResult struct {
+	Project         string            `json:"project"`
+	ProjectID       uint32            `json:"project_id"`
+	Paths           []string          `json:"paths"`
+	Scanned         uint64            `json:"scanned"` // 统计的文件和目录数，硬链接只计一次
+	Skipped         uint64            `json:"skipped"` // 跳过的其他挂载点
+	Checks          []AccountingCheck `json:"checks"`
+	MismatchedFiles []MismatchedFile  `json:"mismatched_files"`
+	Truncated       bool              `json:"truncated"` // 不一致的文件超过 MaxFiles，只列出了一部分
+}
+
+// OK 检查统计是否一致且所有文件都属于该项目
+func (r *VerifyResult) OK() bool {
+	for _, check := range r.Checks {
+		if !check.OK {
+			return false
+		}
+	}
+	return len(r.MismatchedFiles) == 0
+}
+
+// VerifyProject 遍历项目目录，按项目ID、UID和GID统计块和inode数并与dquot比较
+// 遍历使用有界的goroutine池且不跨越挂载点，统计期间目录树应保持静止
+func (q *quotaManager) VerifyProject(ctx context.Context, name string, opts VerifyOptions) (*VerifyResult, error) {
+	files, err := q.projects.Load()
+	if err != nil {
+		return nil, err
+	}
+	project, ok := files.Find(name)
+	if !ok {
+		return nil, fmt.Errorf("project %s not found", name)
+	}
+	paths := files.Paths(project.ID)
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("project %s has no directories in %s", name, q.projects.ProjectsFile)
+	}
+
+	// 项目目录可能分布在多个文件系统上，每个文件系统有各自的dquot
+	var mounts []*MountInfo
+	roots := make(map[string][]string)
+	for _, path := range paths {
+		mount, err := q.findMount(path)
+		if err != nil {
+			return nil, &QuotaError{Op: "verify", Path: path, Err: err}
+		}
+		if _, exists := roots[mount.MountPoint]; !exists {
+			mounts = append(mounts, mount)
+		}
+		roots[mount.MountPoint] = append(roots[mount.MountPoint], path)
+	}
+
+	result := &VerifyResult{Project: project.Name, ProjectID: project.ID, Paths: paths}
+	for _, mount := range mounts {
+		usage, err := scanTree(ctx, roots[mount.MountPoint], project.ID, opts, readFileAttr)
+		if err != nil {
+			return nil, fmt.Errorf("project %s: %w", name, err)
+		}
+		result.Scanned += usage.scanned
+		result.Skipped += usage.skipped
+		result.MismatchedFiles = append(result.MismatchedFiles, usage.mismatched...)
+		result.Truncated = result.Truncated || usage.truncated
+
+		checks, err := q.checkUsage(ctx, mount, usage, project.ID)
+		if err != nil {
+			return nil, err
+		}
+		result.Checks = append(result.Checks, checks...)
+	}
+	return result, nil
+}
+
+// checkUsage 将遍历统计的用量与dquot比较
+// 被校验的项目要求完全相等；其他ID只有遍历了整个文件系统时才要求相等
+func (q *quotaManager) checkUsage(ctx context.Context, mount *MountInfo, usage *treeUsage, projectID uint32) ([]AccountingCheck, error) {
+	if _, exists := usage.ids[ProjectQuota][projectID]; !exists {
+		usage.ids[ProjectQuota][projectID] = &Usage{}
+	}
+
+	var checks []AccountingCheck
+	for _, quotaType := range []QuotaType{ProjectQuota, UserQuota, GroupQuota} {
+		for _, id := range sortedUsageIDs(usage.ids[quotaType]) {
+			dq, err := q.backend.GetDquot(ctx, quotaType, id, mount)
+			switch {
+			case err == nil:
+			case errors.Is(err, syscall.ENOENT):
+				dq = &FsDiskQuota{} // 没有dquot表示没有用量
+			case errors.Is(err, syscall.ESRCH) && quotaType != ProjectQuota:
+				// 该类型的配额统计未开启，无法比较
+			default:
+				return nil, &QuotaError{Op: "verify", Path: mount.MountPoint, Err: err}
+			}
+			if dq == nil {
+				continue
+			}
+
+			exact := usage.complete || (quotaType == ProjectQuota && id == projectID)
+			check := NewAccountingCheck(quotaType, id, *usage.ids[quotaType][id],
+				Usage{Blocks: utils.SizeFromBasicBlocks(dq.BCount), Inodes: dq.ICount, RTBlocks: utils.SizeFromBasicBlocks(dq.RTBCount)},
+				exact)
+			check.Device = mount.Device()
+			check.Name, _ = q.names.LookupName(quotaType, id)
+			checks = append(checks, check)
+		}
+	}
+	return checks, nil
+}
+
+// fileAttrFunc 获取文件的项目ID和 fsx_xflags
+type fileAttrFunc func(path string) (projectID uint32, xflags uint32, err error)
+
+// readFileAttr 通过 FS_IOC_FSGETXATTR 获取文件的项目ID和标志
+func readFileAttr(path string) (uint32, uint32, error) {
+	file, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NOFOLLOW, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	attr, err := GetFsxattr(file)
+	if err != nil {
+		return 0, 0, &os.PathError{Op: "getxattr", Path: path, Err: err}
+	}
+	return attr.ProjID, attr.XFlags, nil
+}
+
+// treeUsage 目录树遍历统计结果
+type treeUsage struct {
+	ids        map[QuotaType]map[uint32]*Usage
+	scanned    uint64
+	skipped    uint64
+	complete   bool // 遍历从挂载点开始，覆盖了整个文件系统
+	mismatched []MismatchedFile
+	truncated  bool
+}
+
+// add 累加一个文件的用量
+func (u *treeUsage) add(quotaType QuotaType, id uint32, blocks utils.Size, realtime bool) {
+	usage, ok := u.ids[quotaType][id]
+	if !ok {
+		usage = &Usage{}
+		u.ids[quotaType][id] = usage
+	}
+	if realtime {
+		usage.RTBlocks += blocks
+	} else {
+		usage.Blocks += blocks
+	}
+	usage.Inodes++
+}
+
+// merge 合并另一个goroutine的统计结果
+func (u *treeUsage) merge(other *treeUsage) {
+	for quotaType, ids := range other.ids {
+		for id, usage := range ids {
+			total, ok := u.ids[quotaType][id]
+			if !ok {
+				total = &Usage{}
+				u.ids[quotaType][id] = total
+			}
+			total.Blocks += usage.Blocks
+			total.Inodes += usage.Inodes
+			total.RTBlocks += usage.RTBlocks
+		}
+	}
+	u.scanned += other.scanned
+	u.skipped += other.skipped
+}
+
+func newTreeUsage() *treeUsage {
+	return &treeUsage{ids: map[QuotaType]map[uint32]*Usage{
+		UserQuota:    {},
+		GroupQuota:   {},
+		ProjectQuota: {},
+	}}
+}
+
+// scanDir 待遍历的目录
+type scanDir struct {
+	path    string
+	projid  uint32
+	inherit bool
+}
+
+// inodeKey 用于硬链接去重
+type inodeKey struct {
+	dev uint64
+	ino uint64
+}
+
+// treeScanner 并发遍历目录树，每个goroutine从共享队列中取出目录并读取其中的条目
+type treeScanner struct {
+	ctx      context.Context
+	dev      uint64
+	expected uint32
+	opts     VerifyOptions
+	fileAttr fileAttrFunc
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []scanDir
+	pending int // 已入队但尚未处理完的目录数
+	err     error
+	links   map[inodeKey]struct{}
+	result  *treeUsage
+	scanned uint64
+}
+
+// scanTree 遍历roots（须在同一文件系统上）并按项目ID、UID和GID统计用量
+// 不跨越挂载点；硬链接只统计一次；符号链接和特殊文件无法读取项目ID，
+// 按创建时的规则视为继承父目录（有PROJINHERIT时）的项目ID
+// expected不为0时记录项目ID不同的文件和缺少PROJINHERIT的目录
+func scanTree(ctx context.Context, roots []string, expected uint32, opts VerifyOptions, fileAttr fileAttrFunc) (*treeUsage, error) {
+	if opts.Workers <= 0 {
+		opts.Workers = DefaultVerifyWorkers
+	}
+	if opts.MaxFiles == 0 {
+		opts.MaxFiles = DefaultVerifyMaxFiles
+	}
+
+	s := &treeScanner{
+		ctx:      ctx,
+		expected: expected,
+		opts:     opts,
+		fileAttr: fileAttr,
+		links:    make(map[inodeKey]struct{}),
+		result:   newTreeUsage(),
+	}
+	s.cond = sync.NewCond(&s.mu)
+
+	// 嵌套的目录只遍历最外层，避免重复统计
+	roots = outermostPaths(roots)
+	local := newTreeUsage()
+	for i, root := range roots {
+		info, err := os.Lstat(root)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("%s is not a directory", root)
+		}
+		stat := info.Sys().(*syscall.Stat_t)
+		if i == 0 {
+			s.dev = uint64(stat.Dev)
+		} else if uint64(stat.Dev) != s.dev {
+			return nil, fmt.Errorf("%s is not on the same filesystem as %s", root, roots[0])
+		}
+		if isMountRoot(root, stat) {
+			s.result.complete = true
+		}
+
+		dir, err := s.account(local, root, info, scanDir{})
+		if err != nil {
+			return nil, err
+		}
+		s.queue = append(s.queue, *dir)
+		s.pending++
+	}
+	s.result.merge(local)
+
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.work()
+		}()
+	}
+	wg.Wait()
+
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.result, nil
+}
+
+// work 循环处理队列中的目录，队列为空且没有正在处理的目录时退出
+func (s *treeScanner) work() {
+	local := newTreeUsage()
+	defer func() {
+		s.mu.Lock()
+		s.result.merge(local)
+		s.mu.Unlock()
+	}()
+
+	for {
+		s.mu.Lock()
+		for len(s.queue) == 0 && s.pending > 0 && s.err == nil {
+			s.cond.Wait()
+		}
+		if s.pending == 0 || s.err != nil {
+			s.mu.Unlock()
+			return
+		}
+		dir := s.queue[len(s.queue)-1]
+		s.queue = s.queue[:len(s.queue)-1]
+		s.mu.Unlock()
+
+		err := s.scanDir(local, dir)
+
+		s.mu.Lock()
+		s.pending--
+		if err != nil && s.err == nil {
+			s.err = err
+		}
+		if s.pending == 0 || s.err != nil {
+			s.cond.Broadcast()
+		}
+		s.mu.Unlock()
+	}
+}
+
+// scanDir 统计目录中的条目，子目录加入队列
+func (s *treeScanner) scanDir(local *treeUsage, dir scanDir) error {
+	entries, err := os.ReadDir(dir.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // 遍历期间被删除
+		}
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := s.ctx.Err(); err != nil {
+			return err
+		}
+
+		path := filepath.Join(dir.path, entry.Name())
+		info, err := os.Lstat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		if uint64(info.Sys().(*syscall.Stat_t).Dev) != s.dev {
+			local.skipped++
+			continue
+		}
+
+		sub, err := s.account(local, path, info, dir)
+		if err != nil {
+			return err
+		}
+		if sub != nil {
+			s.mu.Lock()
+			s.queue = append(s.queue, *sub)
+			s.pending++
+			s.cond.Signal()
+			s.mu.Unlock()
+		}
+	}
+	return nil
+}
+
+// account 统计单个文件的用量，是目录时返回待遍历的子目录
+func (s *treeScanner) account(local *treeUsage, path string, info os.FileInfo, parent scanDir) (*scanDir, error) {
+	stat := info.Sys().(*syscall.Stat_t)
+
+	// 符号链接和特殊文件不能打开读取属性，创建时从有PROJINHERIT的父目录继承项目ID
+	var projid, xflags uint32
+	if parent.inherit {
+		projid = parent.projid
+	}
+	if info.IsDir() || info.Mode().IsRegular() {
+		var err error
+		projid, xflags, err = s.fileAttr(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, nil
+			}
+			return nil, err
+		}
+	}
+
+	// 硬链接的多个路径共用一个inode
+	if !info.IsDir() && stat.Nlink > 1 {
+		key := inodeKey{dev: uint64(stat.Dev), ino: stat.Ino}
+		s.mu.Lock()
+		_, seen := s.links[key]
+		s.links[key] = struct{}{}
+		s.mu.Unlock()
+		if seen {
+			return nil, nil
+		}
+	}
+
+	blocks := utils.SizeFromBasicBlocks(uint64(stat.Blocks)) // st_blocks 的单位同样是512字节
+	realtime := xflags&FS_XFLAG_REALTIME != 0
+	local.add(UserQuota, stat.Uid, blocks, realtime)
+	local.add(GroupQuota, stat.Gid, blocks, realtime)
+	local.add(ProjectQuota, projid, blocks, realtime)
+	local.scanned++
+
+	inherit := xflags&FS_XFLAG_PROJINHERIT != 0
+	if s.expected != 0 && (projid != s.expected || (info.IsDir() && !inherit)) {
+		s.recordMismatch(MismatchedFile{Path: path, ProjectID: projid, MissingInherit: info.IsDir() && !inherit})
+	}
+
+	if s.opts.Progress != nil {
+		s.mu.Lock()
+		s.scanned++
+		s.opts.Progress(path, s.scanned)
+		s.mu.Unlock()
+	}
+
+	if !info.IsDir() {
+		return nil, nil
+	}
+	return &scanDir{path: path, projid: projid, inherit: inherit}, nil
+}
+
+// recordMismatch 记录不一致的文件，超过 MaxFiles 后只标记结果不完整
+func (s *treeScanner) recordMismatch(file MismatchedFile) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.opts.MaxFiles > 0 && len(s.result.mismatched) >= s.opts.MaxFiles {
+		s.result.truncated = true
+		return
+	}
+	s.result.mismatched = append(s.result.mismatched, file)
+}
+
+// outermostPaths 去掉位于其他路径之下的路径
+func outermostPaths(paths []string) []string {
+	sorted := append([]string(nil), paths...)
+	sort.Strings(sorted)
+
+	var result []string
+next:
+	for _, path := range sorted {
+		for _, outer := range result {
+			if pathHasPrefix(path, outer) {
+				continue next
+			}
+		}
+		result = append(result, path)
+	}
+	return result
+}
+
+// isMountRoot 判断目录是否为文件系统的根（上级目录在其他设备上或就是自身）
+func isMountRoot(path string, stat *syscall.Stat_t) bool {
+	parent, err := os.Stat(filepath.Dir(path))
+	if err != nil {
+		return false
+	}
+	parentStat := parent.Sys().(*syscall.Stat_t)
+	return parentStat.Dev != stat.Dev || parentStat.Ino == stat.Ino
+}
+
+func sortedUsageIDs(ids map[uint32]*Usage) []uint32 {
+	sorted := make([]uint32, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
@@ -0,0 +1,116 @@
+package xfs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xfs-quota-kit/pkg/utils"
+)
+
+// testFileAttrs 按相对路径返回预设的项目ID，未列出的文件属于项目100，目录带PROJINHERIT
+func testFileAttrs(root string, projids map[string]uint32, noInherit map[string]bool) fileAttrFunc {
+	return func(path string) (uint32, uint32, error) {
+		rel, _ := filepath.Rel(root, path)
+		info, err := os.Lstat(path)
+		if err != nil {
+			return 0, 0, err
+		}
+		projid, ok := projids[rel]
+		if !ok {
+			projid = 100
+		}
+		var xflags uint32
+		if info.IsDir() && !noInherit[rel] {
+			xflags |= FS_XFLAG_PROJINHERIT
+		}
+		return projid, xflags, nil
+	}
+}
+
+func TestScanTree(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "dir", "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a"), make([]byte, 8192), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "dir", "b"), make([]byte, 4096), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "dir", "sub", "moved"), []byte("x"), 0644))
+	require.NoError(t, os.Link(filepath.Join(root, "a"), filepath.Join(root, "dir", "a-link")))
+	require.NoError(t, os.Symlink("a", filepath.Join(root, "link")))
+	require.NoError(t, syscall.Mkfifo(filepath.Join(root, "dir", "sub", "fifo"), 0644))
+
+	// 期望值直接按 lstat 计算，硬链接只计一次
+	var blocks utils.Size
+	for _, rel := range []string{".", "a", "dir", "dir/b", "dir/sub", "dir/sub/moved", "link", "dir/sub/fifo"} {
+		info, err := os.Lstat(filepath.Join(root, rel))
+		require.NoError(t, err)
+		blocks += utils.SizeFromBasicBlocks(uint64(info.Sys().(*syscall.Stat_t).Blocks))
+	}
+	movedInfo, err := os.Lstat(filepath.Join(root, "dir", "sub", "moved"))
+	require.NoError(t, err)
+	movedBlocks := utils.SizeFromBasicBlocks(uint64(movedInfo.Sys().(*syscall.Stat_t).Blocks))
+
+	fileAttr := testFileAttrs(root, map[string]uint32{"dir/sub/moved": 200}, map[string]bool{"dir/sub": true})
+	var progress uint64
+	usage, err := scanTree(context.Background(), []string{root, filepath.Join(root, "dir")}, 100, VerifyOptions{
+		Workers:  4,
+		Progress: func(path string, scanned uint64) { progress++ },
+	}, fileAttr)
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(8), usage.scanned)
+	assert.Equal(t, uint64(8), progress)
+	assert.Equal(t, &Usage{Blocks: blocks - movedBlocks, Inodes: 6}, usage.ids[ProjectQuota][100])
+	assert.Equal(t, &Usage{Blocks: movedBlocks, Inodes: 1}, usage.ids[ProjectQuota][200])
+	assert.Equal(t, &Usage{Inodes: 1}, usage.ids[ProjectQuota][0])
+	assert.Equal(t, &Usage{Blocks: blocks, Inodes: 8}, usage.ids[UserQuota][uint32(os.Getuid())])
+
+	// 缺少PROJINHERIT的目录下的特殊文件不继承项目ID
+	assert.ElementsMatch(t, []MismatchedFile{
+		{Path: filepath.Join(root, "dir", "sub"), ProjectID: 100, MissingInherit: true},
+		{Path: filepath.Join(root, "dir", "sub", "moved"), ProjectID: 200},
+		{Path: filepath.Join(root, "dir", "sub", "fifo"), ProjectID: 0},
+	}, usage.mismatched)
+	assert.False(t, usage.truncated)
+	assert.False(t, usage.complete)
+
+	usage, err = scanTree(context.Background(), []string{root}, 100, VerifyOptions{MaxFiles: 1}, fileAttr)
+	require.NoError(t, err)
+	assert.Len(t, usage.mismatched, 1)
+	assert.True(t, usage.truncated)
+}
+
+func TestScanTree_Errors(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "a", "b"), 0755))
+	fileAttr := testFileAttrs(root, nil, nil)
+
+	_, err := scanTree(context.Background(), []string{filepath.Join(root, "missing")}, 100, VerifyOptions{}, fileAttr)
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = scanTree(context.Background(), []string{root}, 100, VerifyOptions{}, func(string) (uint32, uint32, error) {
+		return 0, 0, syscall.ENOTTY
+	})
+	assert.ErrorIs(t, err, syscall.ENOTTY)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = scanTree(ctx, []string{root}, 100, VerifyOptions{}, fileAttr)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestNewAccountingCheck(t *testing.T) {
+	scanned := Usage{Blocks: 100 * utils.KiB, Inodes: 3}
+
+	assert.True(t, NewAccountingCheck(ProjectQuota, 1, scanned, scanned, true).OK)
+	assert.False(t, NewAccountingCheck(ProjectQuota, 1, scanned, Usage{Blocks: 200 * utils.KiB, Inodes: 3}, true).OK)
+	assert.True(t, NewAccountingCheck(UserQuota, 1, scanned, Usage{Blocks: 200 * utils.KiB, Inodes: 3}, false).OK)
+	assert.False(t, NewAccountingCheck(UserQuota, 1, scanned, Usage{Blocks: 200 * utils.KiB, Inodes: 2}, false).OK)
+}
+
+func TestOutermostPaths(t *testing.T) {
+	assert.Equal(t, []string{"/a", "/a-x"}, outermostPaths([]string{"/a/b", "/a-x", "/a", "/a-x/c"}))
+}
@@ -0,0 +1,469 @@
+package xfs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultXFSQuotaPath 默认 xfs_quota 可执行文件
+const DefaultXFSQuotaPath = "xfs_quota"
+
+// XFSQuotaError xfs_quota 命令执行失败
+type XFSQuotaError struct {
+	Command string // 执行的 xfs_quota 子命令
+	Stderr  string // 标准错误输出
+	Err     error  // 底层错误，能识别时为对应的 errno
+}
+
+func (e *XFSQuotaError) Error() string {
+	if e.Stderr != "" {
+		return fmt.Sprintf("xfs_quota -c %q: %s", e.Command, e.Stderr)
+	}
+	return fmt.Sprintf("xfs_quota -c %q: %v", e.Command, e.Err)
+}
+
+func (e *XFSQuotaError) Unwrap() error {
+	return e.Err
+}
+
+// xfsQuotaBackend 通过 xfs_quota -x -c 命令实现，解析其文本输出
+type xfsQuotaBackend struct {
+	path string
+	run  func(ctx context.Context, path string, args ...string) (stdout, stderr []byte, err error)
+	now  func() time.Time
+}
+
+// NewXFSQuotaBackend 创建 xfs_quota 命令行后端，path为空时从PATH中查找
+func NewXFSQuotaBackend(path string) Backend {
+	if path == "" {
+		path = DefaultXFSQuotaPath
+	}
+	return &xfsQuotaBackend{
+		path: path,
+		run:  runCommand,
+		now:  time.Now,
+	}
+}
+
+func (b *xfsQuotaBackend) Name() string {
+	return BackendXFSQuota
+}
+
+func (b *xfsQuotaBackend) GetDquot(ctx context.Context, quotaType QuotaType, id uint32, mount *MountInfo) (*FsDiskQuota, error) {
+	command := fmt.Sprintf("report %s -N -n -b -i -r -L %d -U %d", quotaTypeOption(quotaType), id, id)
+	out, err := b.exec(ctx, command, mount)
+	if err != nil {
+		return nil, err
+	}
+
+	var found *FsDiskQuota
+	err = b.parseReport(ctx, quotaType, out, func(dq *FsDiskQuota) error {
+		if dq.ID == id {
+			found = dq
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, &XFSQuotaError{Command: command, Err: err}
+	}
+	if found == nil {
+		return nil, &XFSQuotaError{Command: command, Err: syscall.ENOENT}
+	}
+	return found, nil
+}
+
+func (b *xfsQuotaBackend) SetDquot(ctx context.Context, quotaType QuotaType, id uint32, mount *MountInfo, dq *FsDiskQuota) error {
+	if dq.FieldMask&FS_DQ_WARNS_MASK != 0 {
+		return &XFSQuotaError{Command: "warn", Err: fmt.Errorf("setting warning counts is not supported by the %s backend", BackendXFSQuota)}
+	}
+
+	commands := []string{}
+	if limits := limitArgs(dq); len(limits) > 0 {
+		commands = append(commands, fmt.Sprintf("limit %s %s %d", quotaTypeOption(quotaType), strings.Join(limits, " "), id))
+	}
+
+	timers := []struct {
+		mask  uint16
+		flag  string
+		timer int64
+	}{
+		{FS_DQ_BTIMER, "-b", dq.BlockTimer()},
+		{FS_DQ_ITIMER, "-i", dq.InodeTimer()},
+		{FS_DQ_RTBTIMER, "-r", dq.RTBlockTimer()},
+	}
+	for _, t := range timers {
+		if dq.FieldMask&t.mask == 0 {
+			continue
+		}
+		if id == 0 {
+			// ID 0 的计时器字段表示默认宽限时间（秒）
+			commands = append(commands, fmt.Sprintf("timer %s %s -d %d", quotaTypeOption(quotaType), t.flag, t.timer))
+			continue
+		}
+		// xfs_quota 按相对当前时间的秒数设置单个ID的计时器
+		remaining := t.timer - b.now().Unix()
+		if remaining < 1 {
+			remaining = 1
+		}
+		commands = append(commands, fmt.Sprintf("timer %s %s %d %d", quotaTypeOption(quotaType), t.flag, remaining, id))
+	}
+
+	for _, command := range commands {
+		if _, err := b.exec(ctx, command, mount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *xfsQuotaBackend) WalkDquots(ctx context.Context, quotaType QuotaType, mount *MountInfo, fn func(dq *FsDiskQuota) error) error {
+	command := fmt.Sprintf("report %s -N -n -b -i -r", quotaTypeOption(quotaType))
+	out, err := b.exec(ctx, command, mount)
+	if err != nil {
+		return err
+	}
+	return b.parseReport(ctx, quotaType, out, fn)
+}
+
+func (b *xfsQuotaBackend) GetStatv(ctx context.Context, quotaType QuotaType, mount *MountInfo) (*FsQuotaStatv, error) {
+	command := fmt.Sprintf("state %s", quotaTypeOption(quotaType))
+	out, err := b.exec(ctx, command, mount)
+	if err != nil {
+		return nil, err
+	}
+
+	statv, err := parseStateOutput(quotaType, out)
+	if err != nil {
+		return nil, &XFSQuotaError{Command: command, Err: err}
+	}
+	return statv, nil
+}
+
+func (b *xfsQuotaBackend) SetEnforcement(ctx context.Context, quotaType QuotaType, mount *MountInfo, enforce bool) error {
+	command := "disable"
+	if enforce {
+		command = "enable"
+	}
+	_, err := b.exec(ctx, command+" "+quotaTypeOption(quotaType), mount)
+	return err
+}
+
+func (b *xfsQuotaBackend) RemoveQuotaFile(ctx context.Context, quotaType QuotaType, mount *MountInfo) error {
+	_, err := b.exec(ctx, "remove "+quotaTypeOption(quotaType), mount)
+	return err
+}
+
+// exec 在挂载点上以专家模式执行一条 xfs_quota 子命令，context取消时终止子进程
+// xfs_quota 出错时经常仍以0退出，因此标准错误输出非空也视为失败
+func (b *xfsQuotaBackend) exec(ctx context.Context, command string, mount *MountInfo) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	stdout, stderr, err := b.run(ctx, b.path, "-x", "-c", command, mount.MountPoint)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	msg := strings.TrimSpace(string(stderr))
+	if err != nil || msg != "" {
+		if errno := errnoFromMessage(msg); errno != 0 {
+			err = NewSyscallError("quotactl", errno)
+		} else if err == nil {
+			err = fmt.Errorf("%s", msg)
+		}
+		return nil, &XFSQuotaError{Command: command, Stderr: msg, Err: err}
+	}
+	return stdout, nil
+}
+
+// parseReport 解析 report -N -n -b -i -r 的输出
+func (b *xfsQuotaBackend) parseReport(ctx context.Context, quotaType QuotaType, out []byte, fn func(dq *FsDiskQuota) error) error {
+	now := b.now()
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		dq, err := parseReportLine(quotaType, line, now)
+		if err != nil {
+			return err
+		}
+		if err := fn(dq); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// parseReportLine 解析一行报告：#ID，随后依次为块、inode、实时块的
+// 已用、软限制、硬限制、警告次数和宽限时间，块数量单位为KB
+func parseReportLine(quotaType QuotaType, line string, now time.Time) (*FsDiskQuota, error) {
+	fields := splitReportFields(line)
+	if len(fields) != 16 {
+		return nil, fmt.Errorf("unexpected report line %q", line)
+	}
+
+	id, err := strconv.ParseUint(strings.TrimPrefix(fields[0], "#"), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID in report line %q", line)
+	}
+
+	var values [3][4]uint64
+	var timers [3]int64
+	for i := 0; i < 3; i++ {
+		group := fields[1+i*5 : 6+i*5]
+		for j := 0; j < 4; j++ {
+			if values[i][j], err = strconv.ParseUint(group[j], 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid value %q in report line %q", group[j], line)
+			}
+		}
+		if timers[i], err = parseReportTimer(group[4], now); err != nil {
+			return nil, fmt.Errorf("%w in report line %q", err, line)
+		}
+	}
+
+	dq := &FsDiskQuota{
+		Version:      FS_DQUOT_VERSION,
+		Flags:        quotaTypeFlag(quotaType),
+		ID:           uint32(id),
+		BCount:       kbToBB(values[0][0]),
+		BlkSoftLimit: kbToBB(values[0][1]),
+		BlkHardLimit: kbToBB(values[0][2]),
+		BWarns:       uint16(values[0][3]),
+		ICount:       values[1][0],
+		InoSoftLimit: values[1][1],
+		InoHardLimit: values[1][2],
+		IWarns:       uint16(values[1][3]),
+		RTBCount:     kbToBB(values[2][0]),
+		RTBSoftLimit: kbToBB(values[2][1]),
+		RTBHardLimit: kbToBB(values[2][2]),
+		RTBWarns:     uint16(values[2][3]),
+	}
+	dq.SetBlockTimer(timers[0])
+	dq.SetInodeTimer(timers[1])
+	dq.SetRTBlockTimer(timers[2])
+	dq.FieldMask = 0
+	return dq, nil
+}
+
+// splitReportFields 按空白分割，方括号内的内容作为一个字段
+func splitReportFields(line string) []string {
+	var fields []string
+	for len(line) > 0 {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" {
+			break
+		}
+
+		end := strings.IndexAny(line, " \t")
+		if line[0] == '[' {
+			end = strings.IndexByte(line, ']') + 1
+		}
+		if end <= 0 {
+			end = len(line)
+		}
+		fields = append(fields, line[:end])
+		line = line[end:]
+	}
+	return fields
+}
+
+// parseReportTimer 将报告中的剩余宽限时间转换为到期时间
+// [--------] 表示未计时，[-none-] 表示宽限期已用完
+func parseReportTimer(field string, now time.Time) (int64, error) {
+	value := strings.Trim(field, "[]")
+	switch {
+	case strings.Trim(value, "-") == "":
+		return 0, nil
+	case strings.Contains(value, "none"):
+		return now.Unix(), nil
+	}
+
+	remaining, err := parseXFSQuotaDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	return now.Add(remaining).Unix(), nil
+}
+
+// parseXFSQuotaDuration 解析 xfs_quota 输出的时间，如 "7 days"、"1 day 02:00:00"、"6 23:59:59"、"01:30:00"
+func parseXFSQuotaDuration(value string) (time.Duration, error) {
+	fields := strings.Fields(strings.Trim(value, "[]"))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+
+	var d time.Duration
+	for _, field := range fields {
+		switch {
+		case field == "day" || field == "days":
+			continue
+		case strings.Count(field, ":") == 2:
+			parts := strings.Split(field, ":")
+			var hms [3]uint64
+			for i, part := range parts {
+				n, err := strconv.ParseUint(part, 10, 32)
+				if err != nil {
+					return 0, fmt.Errorf("invalid time %q", value)
+				}
+				hms[i] = n
+			}
+			d += time.Duration(hms[0])*time.Hour + time.Duration(hms[1])*time.Minute + time.Duration(hms[2])*time.Second
+		default:
+			days, err := strconv.ParseUint(field, 10, 32)
+			if err != nil {
+				return 0, fmt.Errorf("invalid time %q", value)
+			}
+			d += time.Duration(days) * 24 * time.Hour
+		}
+	}
+	return d, nil
+}
+
+// parseStateOutput 解析 state 命令的输出
+func parseStateOutput(quotaType QuotaType, out []byte) (*FsQuotaStatv, error) {
+	acct, enfd := quotaTypeStateFlags(quotaType)
+	statv := &FsQuotaStatv{Version: FS_QSTATV_VERSION1}
+	file := &statv.UQuota
+	switch quotaType {
+	case GroupQuota:
+		file = &statv.GQuota
+	case ProjectQuota:
+		file = &statv.PQuota
+	}
+
+	found := false
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		var err error
+		switch key {
+		case "Accounting":
+			found = true
+			if value == "ON" {
+				statv.Flags |= uint16(acct)
+			}
+		case "Enforcement":
+			if value == "ON" {
+				statv.Flags |= uint16(enfd)
+			}
+		case "Inode":
+			// 格式为 "#131 (2 blocks, 2 extents)" 或 "N/A"
+			if value != "N/A" {
+				_, err = fmt.Sscanf(value, "#%d (%d blocks, %d extents)", &file.Ino, &file.NBlks, &file.NExtents)
+			}
+		case "Blocks grace time":
+			statv.BTimeLimit, err = parseStateGrace(value)
+		case "Inodes grace time":
+			statv.ITimeLimit, err = parseStateGrace(value)
+		case "Realtime Blocks grace time":
+			statv.RTBTimeLimit, err = parseStateGrace(value)
+		case "Blocks max warnings":
+			statv.BWarnLimit, err = parseStateWarnings(value)
+		case "Inodes max warnings":
+			statv.IWarnLimit, err = parseStateWarnings(value)
+		case "Realtime Blocks max warnings":
+			statv.RTBWarnLimit, err = parseStateWarnings(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid state line %q: %w", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("no %s quota state in xfs_quota output", quotaType)
+	}
+	return statv, nil
+}
+
+func parseStateGrace(value string) (int32, error) {
+	d, err := parseXFSQuotaDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	return int32(d / time.Second), nil
+}
+
+func parseStateWarnings(value string) (uint16, error) {
+	n, err := strconv.ParseUint(value, 10, 16)
+	return uint16(n), err
+}
+
+// limitArgs 根据字段掩码生成 limit 命令参数
+func limitArgs(dq *FsDiskQuota) []string {
+	var args []string
+	if dq.FieldMask&FS_DQ_BSOFT != 0 {
+		args = append(args, fmt.Sprintf("bsoft=%dk", bbToKB(dq.BlkSoftLimit)))
+	}
+	if dq.FieldMask&FS_DQ_BHARD != 0 {
+		args = append(args, fmt.Sprintf("bhard=%dk", bbToKB(dq.BlkHardLimit)))
+	}
+	if dq.FieldMask&FS_DQ_ISOFT != 0 {
+		args = append(args, fmt.Sprintf("isoft=%d", dq.InoSoftLimit))
+	}
+	if dq.FieldMask&FS_DQ_IHARD != 0 {
+		args = append(args, fmt.Sprintf("ihard=%d", dq.InoHardLimit))
+	}
+	if dq.FieldMask&FS_DQ_RTBSOFT != 0 {
+		args = append(args, fmt.Sprintf("rtbsoft=%dk", bbToKB(dq.RTBSoftLimit)))
+	}
+	if dq.FieldMask&FS_DQ_RTBHARD != 0 {
+		args = append(args, fmt.Sprintf("rtbhard=%dk", bbToKB(dq.RTBHardLimit)))
+	}
+	return args
+}
+
+// quotaTypeOption 获取配额类型对应的 xfs_quota 选项
+func quotaTypeOption(quotaType QuotaType) string {
+	switch quotaType {
+	case GroupQuota:
+		return "-g"
+	case ProjectQuota:
+		return "-p"
+	default:
+		return "-u"
+	}
+}
+
+// errnoFromMessage 从 xfs_quota 的错误信息中识别 errno
+func errnoFromMessage(msg string) syscall.Errno {
+	for _, errno := range []syscall.Errno{
+		syscall.EPERM, syscall.ESRCH, syscall.ENOENT, syscall.ENOSYS, syscall.EINVAL, syscall.EEXIST,
+	} {
+		if strings.Contains(strings.ToLower(msg), errno.Error()) {
+			return errno
+		}
+	}
+	return 0
+}
+
+// runCommand 执行命令并分别返回标准输出和标准错误
+func runCommand(ctx context.Context, path string, args ...string) ([]byte, []byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	return stdout.Bytes(), stderr.Bytes(), err
+}
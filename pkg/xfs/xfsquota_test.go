@@ -0,0 +1,216 @@
+package xfs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xfs-quota-kit/pkg/utils"
+)
+
+// fakeXFSQuota 记录执行的命令并返回预设输出
+type fakeXFSQuota struct {
+	commands []string
+	stdout   map[string]string
+	stderr   map[string]string
+}
+
+func (f *fakeXFSQuota) run(ctx context.Context, path string, args ...string) ([]byte, []byte, error) {
+	command := args[2]
+	f.commands = append(f.commands, command)
+	return []byte(f.stdout[command]), []byte(f.stderr[command]), nil
+}
+
+func newFakeXFSQuotaBackend(fake *fakeXFSQuota, now time.Time) *xfsQuotaBackend {
+	return &xfsQuotaBackend{
+		path: DefaultXFSQuotaPath,
+		run:  fake.run,
+		now:  func() time.Time { return now },
+	}
+}
+
+var testMount = &MountInfo{MountPoint: "/mnt/xfs", Source: "/dev/sdb1", FSType: "xfs"}
+
+func TestNewBackend(t *testing.T) {
+	backend, err := NewBackend("", "")
+	require.NoError(t, err)
+	assert.Equal(t, BackendNative, backend.Name())
+
+	backend, err = NewBackend(BackendXFSQuota, "/usr/sbin/xfs_quota")
+	require.NoError(t, err)
+	assert.Equal(t, BackendXFSQuota, backend.Name())
+
+	_, err = NewBackend("ioctl", "")
+	assert.Error(t, err)
+}
+
+func TestXFSQuotaBackend_WalkDquots(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	fake := &fakeXFSQuota{stdout: map[string]string{
+		"report -p -N -n -b -i -r": `#0                  0          0          0     00 [--------]          3          0          0     00 [--------]          0          0          0     00 [--------]
+#1000          2097152    1048576    4194304     00 [6 days]          120          0          0     00 [--------]          0          0          0     00 [--------]
+#1001              512       1024       2048     00 [--------]         11         10         20     00 [0 days 01:30:00]    4096          0       8192     00 [-none-]
+`,
+	}}
+	backend := newFakeXFSQuotaBackend(fake, now)
+
+	var dquots []*FsDiskQuota
+	err := backend.WalkDquots(context.Background(), ProjectQuota, testMount, func(dq *FsDiskQuota) error {
+		dquots = append(dquots, dq)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, dquots, 3)
+	assert.Equal(t, []string{"report -p -N -n -b -i -r"}, fake.commands)
+
+	dq := dquots[1]
+	assert.Equal(t, uint32(1000), dq.ID)
+	assert.Equal(t, int8(FS_PROJ_QUOTA), dq.Flags)
+	assert.Equal(t, uint64(4194304), dq.BCount)
+	assert.Equal(t, uint64(2097152), dq.BlkSoftLimit)
+	assert.Equal(t, uint64(8388608), dq.BlkHardLimit)
+	assert.Equal(t, uint64(120), dq.ICount)
+	assert.Equal(t, now.Add(6*24*time.Hour).Unix(), dq.BlockTimer())
+	assert.Zero(t, dq.InodeTimer())
+
+	dq = dquots[2]
+	assert.Equal(t, uint64(10), dq.InoSoftLimit)
+	assert.Equal(t, now.Add(90*time.Minute).Unix(), dq.InodeTimer())
+	assert.Equal(t, uint64(8192), dq.RTBCount)
+	assert.Equal(t, uint64(16384), dq.RTBHardLimit)
+	assert.Equal(t, now.Unix(), dq.RTBlockTimer())
+}
+
+func TestXFSQuotaBackend_GetDquot(t *testing.T) {
+	fake := &fakeXFSQuota{stdout: map[string]string{
+		"report -u -N -n -b -i -r -L 1000 -U 1000": "#1000 4 0 0 00 [--------] 1 0 0 00 [--------] 0 0 0 00 [--------]\n",
+	}}
+	backend := newFakeXFSQuotaBackend(fake, time.Now())
+
+	dq, err := backend.GetDquot(context.Background(), UserQuota, 1000, testMount)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1000), dq.ID)
+	assert.Equal(t, uint64(8), dq.BCount)
+
+	_, err = backend.GetDquot(context.Background(), UserQuota, 1001, testMount)
+	assert.True(t, errors.Is(err, syscall.ENOENT))
+}
+
+func TestXFSQuotaBackend_SetDquot(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	fake := &fakeXFSQuota{}
+	backend := newFakeXFSQuotaBackend(fake, now)
+
+	dq := FsDiskQuota{
+		FieldMask:    FS_DQ_BSOFT | FS_DQ_BHARD | FS_DQ_IHARD,
+		BlkSoftLimit: 2048,
+		BlkHardLimit: 4096,
+		InoHardLimit: 100,
+	}
+	dq.SetInodeTimer(now.Add(time.Hour).Unix())
+	require.NoError(t, backend.SetDquot(context.Background(), GroupQuota, 50, testMount, &dq))
+
+	grace := FsDiskQuota{}
+	grace.SetBlockTimer(int64((7 * 24 * time.Hour).Seconds()))
+	require.NoError(t, backend.SetDquot(context.Background(), GroupQuota, 0, testMount, &grace))
+
+	assert.Equal(t, []string{
+		"limit -g bsoft=1024k bhard=2048k ihard=100 50",
+		"timer -g -i 3600 50",
+		"timer -g -b -d 604800",
+	}, fake.commands)
+
+	warns := FsDiskQuota{FieldMask: FS_DQ_BWARNS}
+	assert.Error(t, backend.SetDquot(context.Background(), GroupQuota, 50, testMount, &warns))
+}
+
+func TestXFSQuotaBackend_Errors(t *testing.T) {
+	fake := &fakeXFSQuota{stderr: map[string]string{
+		"enable -p": "xfs_quota: XFS_QUOTAON: Operation not permitted\n",
+	}}
+	backend := newFakeXFSQuotaBackend(fake, time.Now())
+
+	err := backend.SetEnforcement(context.Background(), ProjectQuota, testMount, true)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, syscall.EPERM))
+	assert.Contains(t, err.Error(), "Operation not permitted")
+
+	// 已取消的context不会启动子进程
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = backend.GetStatv(ctx, ProjectQuota, testMount)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []string{"enable -p"}, fake.commands)
+}
+
+func TestLimitArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits QuotaLimits
+		want   []string
+	}{
+		{"aligned", QuotaLimits{BlockSoft: utils.MiB, BlockHard: 2 * utils.MiB, Fields: LimitBlockSoft | LimitBlockHard},
+			[]string{"bsoft=1024k", "bhard=2048k"}},
+		{"sub-KiB", QuotaLimits{BlockHard: 512, RTBlockHard: 1, Fields: LimitBlockHard | LimitRTBlockHard},
+			[]string{"bhard=1k", "rtbhard=1k"}},
+		{"not KiB-aligned", QuotaLimits{BlockSoft: utils.MiB + 512, BlockHard: 1500, Fields: LimitBlockSoft | LimitBlockHard},
+			[]string{"bsoft=1025k", "bhard=2k"}},
+		{"zero removes the limit", QuotaLimits{BlockHard: 0, InodeHard: 0, Fields: LimitBlockHard | LimitInodeHard},
+			[]string{"bhard=0k", "ihard=0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dq := FsDiskQuota{
+				FieldMask:    limitFieldMask(tt.limits),
+				BlkSoftLimit: tt.limits.BlockSoft.BasicBlocks(),
+				BlkHardLimit: tt.limits.BlockHard.BasicBlocks(),
+				InoSoftLimit: tt.limits.InodeSoft,
+				InoHardLimit: tt.limits.InodeHard,
+				RTBSoftLimit: tt.limits.RTBlockSoft.BasicBlocks(),
+				RTBHardLimit: tt.limits.RTBlockHard.BasicBlocks(),
+			}
+			assert.Equal(t, tt.want, limitArgs(&dq))
+		})
+	}
+}
+
+func TestParseStateOutput(t *testing.T) {
+	out := `Project quota state on /mnt/xfs (/dev/sdb1)
+  Accounting: ON
+  Enforcement: OFF
+  Inode: #131 (2 blocks, 2 extents)
+Blocks grace time: [7 days]
+Blocks max warnings: 5
+Inodes grace time: [1 day 12:00:00]
+Inodes max warnings: 5
+Realtime Blocks grace time: [7 days 00:00:00]
+`
+	statv, err := parseStateOutput(ProjectQuota, []byte(out))
+	require.NoError(t, err)
+
+	state := &QuotaState{}
+	applyQuotaStatv(state, ProjectQuota, statv, 4*utils.KiB)
+	assert.True(t, state.Project.Accounting)
+	assert.False(t, state.Project.Enforced)
+	assert.Equal(t, uint64(131), state.Project.Inode)
+	assert.Equal(t, 8*utils.KiB, state.Project.FileSize)
+	assert.Equal(t, uint32(2), state.Project.FileExtents)
+	assert.Equal(t, 7*24*time.Hour, state.Project.BlockGrace)
+	assert.Equal(t, 36*time.Hour, state.Project.InodeGrace)
+	assert.Equal(t, 7*24*time.Hour, state.Project.RTBlockGrace)
+	assert.Equal(t, uint16(5), state.Project.BlockWarnLimit)
+
+	_, err = parseStateOutput(UserQuota, []byte(strings.Repeat("\n", 3)))
+	assert.Error(t, err)
+}
+
+func TestSplitReportFields(t *testing.T) {
+	assert.Equal(t,
+		[]string{"#1", "4", "[--------]", "2", "[1 day 02:00:00]"},
+		splitReportFields("#1  4 [--------]\t2 [1 day 02:00:00]"))
+}
@@ -0,0 +1,259 @@
+// Package xfstest 提供内存中的 xfs.ContextQuotaManager 实现，用于在没有root权限和
+// XFS文件系统的环境中测试基于配额库的代码，需要旧接口时使用 xfs.BackgroundQuotaManager 包装
+package xfstest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"syscall"
+
+	"github.com/xfs-quota-kit/pkg/utils"
+	"github.com/xfs-quota-kit/pkg/xfs"
+)
+
+// FakeQuotaManager 内存中的配额管理器
+// 模拟用量统计、软/硬限制、宽限计时器、项目文件以及故障注入
+type FakeQuotaManager struct {
+	mu          sync.Mutex
+	clock       Clock
+	filesystems []*Filesystem
+	projects    *xfs.ProjectFiles
+	names       xfs.NameResolver
+	projectIDs  *xfs.ProjectIDAllocator
+	faults      []*fault
+}
+
+var _ xfs.ContextQuotaManager = (*FakeQuotaManager)(nil)
+
+// Option 内存配额管理器选项
+type Option func(*FakeQuotaManager)
+
+// WithClock 指定时钟，默认使用系统时间
+func WithClock(clock Clock) Option {
+	return func(m *FakeQuotaManager) {
+		m.clock = clock
+	}
+}
+
+// WithNameResolver 指定填充 QuotaInfo.Name 的名称解析器，
+// 默认只根据内存中的项目文件填充项目名称
+func WithNameResolver(resolver xfs.NameResolver) Option {
+	return func(m *FakeQuotaManager) {
+		m.names = resolver
+	}
+}
+
+// WithProjectIDAllocator 指定项目ID分配器，默认从1000开始分配且不设隔离期
+func WithProjectIDAllocator(allocator *xfs.ProjectIDAllocator) Option {
+	return func(m *FakeQuotaManager) {
+		m.projectIDs = allocator
+	}
+}
+
+// NewFakeQuotaManager 创建内存配额管理器
+func NewFakeQuotaManager(opts ...Option) *FakeQuotaManager {
+	m := &FakeQuotaManager{
+		clock:      systemClock{},
+		projectIDs: &xfs.ProjectIDAllocator{},
+		projects: &xfs.ProjectFiles{
+			Projects: &xfs.ProjectFile{Path: xfs.DefaultProjectsFile},
+			Projid:   &xfs.ProjectFile{Path: xfs.DefaultProjidFile},
+		},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// AddFilesystem 添加文件系统，options为逗号分隔的挂载选项，如 "uquota,pqnoenforce"
+func (m *FakeQuotaManager) AddFilesystem(mountPoint, device, options string) *Filesystem {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fs := newFilesystem(mountPoint, device, options)
+	m.filesystems = append(m.filesystems, fs)
+	return fs
+}
+
+// ProjectFiles 获取内存中的 /etc/projects 和 /etc/projid 内容
+func (m *FakeQuotaManager) ProjectFiles() *xfs.ProjectFiles {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.projects
+}
+
+// MkdirAll 创建目录及其不存在的父目录
+func (m *FakeQuotaManager) MkdirAll(p string, uid, gid uint32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.mkdirAll(path.Clean(p), uid, gid)
+}
+
+func (m *FakeQuotaManager) mkdirAll(p string, uid, gid uint32) error {
+	fs, err := m.filesystem(p)
+	if err != nil {
+		return err
+	}
+	if f, ok := fs.files[p]; ok {
+		if !f.dir {
+			return &os.PathError{Op: "mkdir", Path: p, Err: syscall.ENOTDIR}
+		}
+		return nil
+	}
+	if err := m.mkdirAll(path.Dir(p), uid, gid); err != nil {
+		return err
+	}
+	return fs.create(p, true, uid, gid, 0, m.clock.Now())
+}
+
+// CreateFile 创建指定大小的文件，超出配额时返回 EDQUOT
+func (m *FakeQuotaManager) CreateFile(p string, uid, gid uint32, size utils.Size) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p = path.Clean(p)
+	fs, err := m.filesystem(p)
+	if err != nil {
+		return err
+	}
+	return fs.create(p, false, uid, gid, size, m.clock.Now())
+}
+
+// Truncate 修改文件大小，增长超出配额时返回 EDQUOT
+func (m *FakeQuotaManager) Truncate(p string, size utils.Size) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p = path.Clean(p)
+	fs, f, err := m.lookup(p)
+	if err != nil {
+		return err
+	}
+	if f.dir {
+		return &os.PathError{Op: "truncate", Path: p, Err: syscall.EISDIR}
+	}
+
+	if err := fs.charge(p, f.owners(), int64(size)-int64(f.size), 0, true, m.clock.Now()); err != nil {
+		return err
+	}
+	f.size = size
+	return nil
+}
+
+// Remove 删除文件或空目录并释放用量
+func (m *FakeQuotaManager) Remove(p string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p = path.Clean(p)
+	fs, f, err := m.lookup(p)
+	if err != nil {
+		return err
+	}
+	if p == fs.MountPoint {
+		return &os.PathError{Op: "remove", Path: p, Err: syscall.EBUSY}
+	}
+	if f.dir && len(fs.tree(p)) > 1 {
+		return &os.PathError{Op: "remove", Path: p, Err: syscall.ENOTEMPTY}
+	}
+
+	fs.charge(p, f.owners(), -int64(f.size), -1, false, m.clock.Now())
+	delete(fs.files, p)
+	return nil
+}
+
+// ProjectID 获取文件的项目ID和PROJINHERIT标志，对应 xfs.GetProjectID
+func (m *FakeQuotaManager) ProjectID(p string) (uint32, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, f, err := m.lookup(path.Clean(p))
+	if err != nil {
+		return 0, false, err
+	}
+	return f.projid, f.inherit, nil
+}
+
+// SetUsage 直接设置ID的用量，用于构造测试场景，不检查限制
+func (m *FakeQuotaManager) SetUsage(quotaType xfs.QuotaType, id uint32, p string, blocks utils.Size, inodes uint64, rtblocks utils.Size) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fs, err := m.filesystem(path.Clean(p))
+	if err != nil {
+		return err
+	}
+	dq := fs.dquot(quotaType, id, true)
+	dq.blocks, dq.inodes, dq.rtblocks = blocks, inodes, rtblocks
+	fs.adjustTimers(quotaType, id, dq, m.clock.Now())
+	return nil
+}
+
+// filesystem 查找路径所在的文件系统（最长前缀匹配）
+func (m *FakeQuotaManager) filesystem(p string) (*Filesystem, error) {
+	var found *Filesystem
+	for _, fs := range m.filesystems {
+		if fs.contains(p) && (found == nil || len(fs.MountPoint) >= len(found.MountPoint)) {
+			found = fs
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("path %s: %w", p, xfs.ErrNotXFS)
+	}
+	return found, nil
+}
+
+// lookup 查找已存在的文件
+func (m *FakeQuotaManager) lookup(p string) (*Filesystem, *file, error) {
+	fs, err := m.filesystem(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, ok := fs.files[p]
+	if !ok {
+		return nil, nil, &os.PathError{Op: "lstat", Path: p, Err: syscall.ENOENT}
+	}
+	return fs, f, nil
+}
+
+// quotaFilesystem 查找配额操作的目标文件系统，检查故障注入和配额统计状态
+func (m *FakeQuotaManager) quotaFilesystem(ctx context.Context, method, op string, quotaType xfs.QuotaType, p string) (*Filesystem, error) {
+	if err := m.checkFault(ctx, method, p); err != nil {
+		return nil, err
+	}
+
+	fs, _, err := m.lookup(path.Clean(p))
+	if err != nil {
+		return nil, &xfs.QuotaError{Op: op, Path: p, Err: err}
+	}
+	if quotaType != 0 && !fs.options.Accounting(quotaType) {
+		// 与内核一致，未开启统计的配额类型返回 ESRCH
+		return nil, syscallError(op, p, syscall.ESRCH)
+	}
+	return fs, nil
+}
+
+// quotaInfo 生成配额信息并填充名称，调用方需持有锁
+func (m *FakeQuotaManager) quotaInfo(fs *Filesystem, quotaType xfs.QuotaType, id uint32, p string, dq *dquot) *xfs.QuotaInfo {
+	info := fs.quotaInfo(quotaType, id, p, dq, m.clock.Now())
+	info.Name = m.lookupName(quotaType, id)
+	return info
+}
+
+// lookupName 解析ID对应的名称，无法解析时返回空字符串，调用方需持有锁
+func (m *FakeQuotaManager) lookupName(quotaType xfs.QuotaType, id uint32) string {
+	if m.names != nil {
+		name, _ := m.names.LookupName(quotaType, id)
+		return name
+	}
+	if quotaType == xfs.ProjectQuota {
+		if project, ok := m.projects.FindByID(id); ok {
+			return project.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,411 @@
+package xfstest
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xfs-quota-kit/pkg/utils"
+	"github.com/xfs-quota-kit/pkg/xfs"
+)
+
+func newTestManager(t *testing.T) (*FakeQuotaManager, *ManualClock) {
+	clock := NewManualClock(time.Unix(1700000000, 0))
+	m := NewFakeQuotaManager(WithClock(clock))
+	m.AddFilesystem("/mnt/xfs", "/dev/sdb1", "uquota,gqnoenforce,pquota")
+	require.NoError(t, m.MkdirAll("/mnt/xfs/home/alice", 1000, 1000))
+	return m, clock
+}
+
+func TestFakeQuotaManager_Usage(t *testing.T) {
+	m, _ := newTestManager(t)
+	ctx := context.Background()
+
+	require.NoError(t, m.CreateFile("/mnt/xfs/home/alice/a", 1000, 1000, 100*utils.KiB))
+	require.NoError(t, m.Truncate("/mnt/xfs/home/alice/a", 300*utils.KiB))
+
+	quota, err := m.GetQuota(ctx, xfs.UserQuota, 1000, "/mnt/xfs")
+	require.NoError(t, err)
+	assert.Equal(t, 300*utils.KiB, quota.BlockUsed)
+	assert.Equal(t, uint64(3), quota.InodeUsed) // home, alice, a
+
+	require.NoError(t, m.Remove("/mnt/xfs/home/alice/a"))
+	quota, err = m.GetQuota(ctx, xfs.GroupQuota, 1000, "/mnt/xfs")
+	require.NoError(t, err)
+	assert.Equal(t, utils.Size(0), quota.BlockUsed)
+	assert.Equal(t, uint64(2), quota.InodeUsed)
+
+	_, err = m.GetQuota(ctx, xfs.UserQuota, 2000, "/mnt/xfs")
+	assert.True(t, errors.Is(err, syscall.ENOENT))
+}
+
+func TestFakeQuotaManager_HardLimit(t *testing.T) {
+	m, _ := newTestManager(t)
+	ctx := context.Background()
+	require.NoError(t, m.SetQuota(ctx, xfs.UserQuota, 1000, "/mnt/xfs", xfs.QuotaLimits{BlockHard: 1024 * utils.KiB}))
+
+	require.NoError(t, m.CreateFile("/mnt/xfs/home/alice/a", 1000, 1000, 1024*utils.KiB))
+	err := m.CreateFile("/mnt/xfs/home/alice/b", 1000, 1000, utils.KiB)
+	assert.True(t, errors.Is(err, syscall.EDQUOT))
+
+	// 组配额未强制执行，root 不受限制
+	require.NoError(t, m.SetQuota(ctx, xfs.GroupQuota, 1000, "/mnt/xfs", xfs.QuotaLimits{BlockHard: utils.KiB}))
+	require.NoError(t, m.CreateFile("/mnt/xfs/home/root-file", 0, 1000, 4096*utils.KiB))
+}
+
+func TestFakeQuotaManager_PartialLimits(t *testing.T) {
+	m, _ := newTestManager(t)
+	ctx := context.Background()
+	require.NoError(t, m.SetQuota(ctx, xfs.UserQuota, 1000, "/mnt/xfs", xfs.QuotaLimits{BlockHard: utils.GiB, InodeHard: 100}))
+	require.NoError(t, m.SetQuota(ctx, xfs.UserQuota, 1000, "/mnt/xfs", xfs.QuotaLimits{BlockSoft: utils.MiB, Fields: xfs.LimitBlockSoft}))
+
+	quota, err := m.GetQuota(ctx, xfs.UserQuota, 1000, "/mnt/xfs")
+	require.NoError(t, err)
+	assert.Equal(t, xfs.QuotaLimits{BlockSoft: utils.MiB, BlockHard: utils.GiB, InodeHard: 100}, quota.Limits())
+}
+
+func TestFakeQuotaManager_SoftLimitGrace(t *testing.T) {
+	m, clock := newTestManager(t)
+	ctx := context.Background()
+	require.NoError(t, m.SetGraceTimes(ctx, xfs.UserQuota, "/mnt/xfs", xfs.GraceTimes{Block: time.Hour}))
+	require.NoError(t, m.SetQuota(ctx, xfs.UserQuota, 1000, "/mnt/xfs", xfs.QuotaLimits{BlockSoft: 100 * utils.KiB, BlockHard: 1000 * utils.KiB}))
+
+	start := clock.Now()
+	require.NoError(t, m.CreateFile("/mnt/xfs/home/alice/a", 1000, 1000, 200*utils.KiB))
+
+	quota, err := m.GetQuota(ctx, xfs.UserQuota, 1000, "/mnt/xfs")
+	require.NoError(t, err)
+	assert.Equal(t, start.Add(time.Hour), quota.BlockGraceExpires)
+
+	// 宽限期内仍可继续写入
+	clock.Advance(30 * time.Minute)
+	require.NoError(t, m.CreateFile("/mnt/xfs/home/alice/b", 1000, 1000, 10*utils.KiB))
+
+	// 宽限期过后软限制变为硬限制
+	clock.Advance(time.Hour)
+	err = m.CreateFile("/mnt/xfs/home/alice/c", 1000, 1000, 10*utils.KiB)
+	assert.True(t, errors.Is(err, syscall.EDQUOT))
+
+	// 降到软限制以下后计时器清零
+	require.NoError(t, m.Remove("/mnt/xfs/home/alice/a"))
+	quota, err = m.GetQuota(ctx, xfs.UserQuota, 1000, "/mnt/xfs")
+	require.NoError(t, err)
+	assert.True(t, quota.BlockGraceExpires.IsZero())
+}
+
+func TestFakeQuotaManager_DefaultLimits(t *testing.T) {
+	m, _ := newTestManager(t)
+	ctx := context.Background()
+	require.NoError(t, m.SetQuota(ctx, xfs.UserQuota, 0, "/mnt/xfs", xfs.QuotaLimits{InodeHard: 3}))
+
+	require.NoError(t, m.CreateFile("/mnt/xfs/home/alice/a", 1000, 1000, 0))
+	err := m.CreateFile("/mnt/xfs/home/alice/b", 1000, 1000, 0)
+	assert.True(t, errors.Is(err, syscall.EDQUOT))
+}
+
+func TestFakeQuotaManager_Projects(t *testing.T) {
+	m, _ := newTestManager(t)
+	ctx := context.Background()
+	require.NoError(t, m.CreateFile("/mnt/xfs/home/alice/a", 1000, 1000, 50*utils.KiB))
+
+	project, err := m.CreateProject(ctx, "alice", "/mnt/xfs/home/alice", xfs.CreateProjectOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1000), project.ID)
+
+	// 已有文件的用量转移到项目，新文件继承项目ID
+	require.NoError(t, m.CreateFile("/mnt/xfs/home/alice/b", 1000, 1000, 25*utils.KiB))
+	id, inherit, err := m.ProjectID("/mnt/xfs/home/alice/b")
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1000), id)
+	assert.False(t, inherit)
+
+	quota, err := m.GetQuota(ctx, xfs.ProjectQuota, 1000, "/mnt/xfs")
+	require.NoError(t, err)
+	assert.Equal(t, 75*utils.KiB, quota.BlockUsed)
+	assert.Equal(t, uint64(3), quota.InodeUsed)
+	assert.Equal(t, "alice", quota.Name)
+	assert.Equal(t, "1000:/mnt/xfs/home/alice\n", string(m.ProjectFiles().Projects.Bytes()))
+
+	_, err = m.CreateProject(ctx, "alice", "/mnt/xfs/other", xfs.CreateProjectOptions{})
+	assert.Error(t, err)
+
+	require.NoError(t, m.RemoveProject(ctx, "alice"))
+	quota, err = m.GetQuota(ctx, xfs.ProjectQuota, 1000, "/mnt/xfs")
+	require.NoError(t, err)
+	assert.Equal(t, utils.Size(0), quota.BlockUsed)
+
+	projects, err := m.GetProjects(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, projects)
+}
+
+func TestFakeQuotaManager_ProjectIDCollision(t *testing.T) {
+	m, _ := newTestManager(t)
+	ctx := context.Background()
+
+	// 1000 仍有项目限制，自动分配跳过，显式指定时报告冲突且不创建目录
+	require.NoError(t, m.SetQuota(ctx, xfs.ProjectQuota, 1000, "/mnt/xfs", xfs.QuotaLimits{BlockHard: 1024 * utils.KiB}))
+	project, err := m.CreateProject(ctx, "web", "/mnt/xfs/web", xfs.CreateProjectOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1001), project.ID)
+
+	_, err = m.CreateProject(ctx, "db", "/mnt/xfs/db", xfs.CreateProjectOptions{ID: 1000})
+	assert.True(t, errors.Is(err, xfs.ErrProjectIDInUse))
+	_, _, err = m.ProjectID("/mnt/xfs/db")
+	assert.True(t, errors.Is(err, syscall.ENOENT))
+
+	_, err = m.CreateProject(ctx, "db", "/mnt/xfs/db", xfs.CreateProjectOptions{ID: 1001})
+	assert.True(t, errors.Is(err, xfs.ErrProjectIDInUse))
+
+	project, err = m.CreateProject(ctx, "db", "/mnt/xfs/db", xfs.CreateProjectOptions{ID: 5000})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(5000), project.ID)
+}
+
+func TestFakeQuotaManager_VerifyProject(t *testing.T) {
+	m, _ := newTestManager(t)
+	ctx := context.Background()
+
+	_, err := m.CreateProject(ctx, "web", "/mnt/xfs/web", xfs.CreateProjectOptions{})
+	require.NoError(t, err)
+	require.NoError(t, m.CreateFile("/mnt/xfs/web/index", 1000, 1000, 40*utils.KiB))
+
+	result, err := m.VerifyProject(ctx, "web", xfs.VerifyOptions{})
+	require.NoError(t, err)
+	assert.True(t, result.OK())
+	assert.Equal(t, uint64(2), result.Scanned)
+	require.NotEmpty(t, result.Checks)
+	assert.Equal(t, xfs.Usage{Blocks: 40 * utils.KiB, Inodes: 2}, result.Checks[0].Scanned)
+	assert.True(t, result.Checks[0].Exact)
+
+	// 嵌套的项目目录属于其他项目ID，dquot用量偏离实际文件
+	_, err = m.CreateProject(ctx, "tmp", "/mnt/xfs/web/tmp", xfs.CreateProjectOptions{})
+	require.NoError(t, err)
+	require.NoError(t, m.SetUsage(xfs.ProjectQuota, 1000, "/mnt/xfs", 10*utils.KiB, 2, 0))
+
+	result, err = m.VerifyProject(ctx, "web", xfs.VerifyOptions{})
+	require.NoError(t, err)
+	assert.False(t, result.OK())
+	assert.Equal(t, []xfs.MismatchedFile{{Path: "/mnt/xfs/web/tmp", ProjectID: 1001}}, result.MismatchedFiles)
+	assert.Equal(t, xfs.ProjectQuota, result.Checks[0].Type)
+	assert.False(t, result.Checks[0].OK)
+	assert.Equal(t, xfs.Usage{Blocks: 10 * utils.KiB, Inodes: 2}, result.Checks[0].Reported)
+
+	_, err = m.VerifyProject(ctx, "missing", xfs.VerifyOptions{})
+	assert.Error(t, err)
+}
+
+func TestFakeQuotaManager_EnsureDefaultLimits(t *testing.T) {
+	m, _ := newTestManager(t)
+	ctx := context.Background()
+	require.NoError(t, m.CreateFile("/mnt/xfs/home/bob", 1001, 1000, utils.KiB))
+	require.NoError(t, m.SetQuota(ctx, xfs.UserQuota, 1001, "/mnt/xfs", xfs.QuotaLimits{InodeHard: 10}))
+
+	defaults := xfs.QuotaLimits{BlockSoft: utils.GiB, BlockHard: 2 * utils.GiB}
+	opts := xfs.EnsureOptions{
+		Limits: map[xfs.QuotaType]xfs.QuotaLimits{xfs.UserQuota: defaults},
+		DryRun: true,
+	}
+
+	// 试运行只列出 alice (1000)，root 和已有限制的 bob 不变
+	result, err := m.EnsureDefaultLimits(ctx, "/mnt/xfs", opts)
+	require.NoError(t, err)
+	assert.Equal(t, []xfs.EnsureAction{{Type: xfs.UserQuota, ID: 1000, Limits: defaults}}, result.Applied)
+	assert.Equal(t, 1, result.Existing)
+	quota, err := m.GetQuota(ctx, xfs.UserQuota, 1000, "/mnt/xfs")
+	require.NoError(t, err)
+	assert.Equal(t, utils.Size(0), quota.BlockHard)
+
+	opts.DryRun = false
+	result, err = m.EnsureDefaultLimits(ctx, "/mnt/xfs", opts)
+	require.NoError(t, err)
+	assert.Len(t, result.Applied, 1)
+	quota, err = m.GetQuota(ctx, xfs.UserQuota, 1000, "/mnt/xfs")
+	require.NoError(t, err)
+	assert.Equal(t, 2*utils.GiB, quota.BlockHard)
+	quota, err = m.GetQuota(ctx, xfs.UserQuota, 1001, "/mnt/xfs")
+	require.NoError(t, err)
+	assert.Equal(t, utils.Size(0), quota.BlockHard)
+
+	// 再次运行不会重复设置
+	result, err = m.EnsureDefaultLimits(ctx, "/mnt/xfs", opts)
+	require.NoError(t, err)
+	assert.Empty(t, result.Applied)
+	assert.Equal(t, 2, result.Existing)
+}
+
+func TestFakeQuotaManager_Faults(t *testing.T) {
+	m, _ := newTestManager(t)
+	ctx := context.Background()
+
+	m.FailOnce("SetQuota", syscall.EPERM)
+	err := m.SetQuota(ctx, xfs.UserQuota, 1000, "/mnt/xfs", xfs.QuotaLimits{})
+	var quotaErr *xfs.QuotaError
+	assert.True(t, errors.As(err, &quotaErr))
+	assert.True(t, errors.Is(err, syscall.EPERM))
+	assert.True(t, errors.Is(err, xfs.ErrPermission))
+	assert.NoError(t, m.SetQuota(ctx, xfs.UserQuota, 1000, "/mnt/xfs", xfs.QuotaLimits{}))
+
+	m.Fail("", syscall.ENOSYS)
+	_, err = m.GetQuotaState(ctx, "/mnt/xfs")
+	assert.True(t, errors.Is(err, syscall.ENOSYS))
+	m.ClearFaults()
+
+	// 未开启统计的配额类型返回 ESRCH
+	m.AddFilesystem("/mnt/noquota", "/dev/sdc1", "")
+	_, err = m.GetQuota(ctx, xfs.UserQuota, 0, "/mnt/noquota")
+	assert.True(t, errors.Is(err, syscall.ESRCH))
+	assert.True(t, errors.Is(err, xfs.ErrQuotaNotEnabled))
+	assert.True(t, errors.Is(m.CheckQuotaStatus(ctx, "/mnt/noquota"), xfs.ErrQuotaNotEnabled))
+	assert.True(t, errors.Is(m.CheckQuotaStatus(ctx, "/srv"), xfs.ErrNotXFS))
+
+	// 报告只跳过未开启统计的配额类型，其他错误不能变成空报告
+	report, err := m.StreamReport(ctx, "/mnt/noquota", nil)
+	require.NoError(t, err)
+	assert.Zero(t, report.TotalQuotas)
+	m.FailOnce("WalkQuotas", syscall.EIO)
+	_, err = m.StreamReport(ctx, "/mnt/xfs", nil)
+	assert.True(t, errors.Is(err, syscall.EIO))
+}
+
+func TestFakeQuotaManager_SetBatchQuotas(t *testing.T) {
+	m, _ := newTestManager(t)
+	ctx := context.Background()
+	old := xfs.QuotaLimits{BlockHard: utils.GiB}
+	require.NoError(t, m.SetQuota(ctx, xfs.UserQuota, 1000, "/mnt/xfs", old))
+
+	quotas := map[uint32]xfs.QuotaLimits{
+		1000: {BlockHard: 2 * utils.GiB},
+		1001: {BlockHard: 2 * utils.GiB},
+		1002: {BlockHard: 2 * utils.GiB},
+	}
+
+	// 全部成功或全部失败：第二个ID失败后恢复第一个ID，第三个ID不处理
+	m.FailNth("SetQuota", syscall.EPERM, 2)
+	result, err := m.SetBatchQuotas(ctx, xfs.UserQuota, "/mnt/xfs", quotas, xfs.BatchOptions{Mode: xfs.BatchAllOrNothing})
+	assert.True(t, errors.Is(err, xfs.ErrPermission))
+	var batchErr *xfs.BatchError
+	require.True(t, errors.As(err, &batchErr))
+	assert.True(t, batchErr.RolledBack)
+	require.Len(t, result.Items, 3)
+	assert.Equal(t, xfs.BatchRolledBack, result.Items[0].Status)
+	assert.Equal(t, &old, result.Items[0].Previous)
+	assert.Equal(t, xfs.BatchFailed, result.Items[1].Status)
+	assert.Equal(t, xfs.BatchPending, result.Items[2].Status)
+
+	quota, err := m.GetQuota(ctx, xfs.UserQuota, 1000, "/mnt/xfs")
+	require.NoError(t, err)
+	assert.Equal(t, utils.GiB, quota.BlockHard)
+
+	// 出错后继续：只有失败的ID未修改
+	m.FailNth("SetQuota", syscall.EPERM, 2)
+	result, err = m.SetBatchQuotas(ctx, xfs.UserQuota, "/mnt/xfs", quotas, xfs.BatchOptions{})
+	require.True(t, errors.As(err, &batchErr))
+	assert.Equal(t, 1, batchErr.Failed)
+	assert.False(t, batchErr.RolledBack)
+	assert.Equal(t, 2, result.Count(xfs.BatchApplied))
+	assert.Equal(t, xfs.BatchFailed, result.Items[1].Status)
+	assert.Equal(t, &xfs.QuotaLimits{}, result.Items[1].Previous)
+
+	for id, want := range map[uint32]utils.Size{1000: 2 * utils.GiB, 1002: 2 * utils.GiB} {
+		quota, err := m.GetQuota(ctx, xfs.UserQuota, id, "/mnt/xfs")
+		require.NoError(t, err)
+		assert.Equal(t, want, quota.BlockHard)
+	}
+	_, err = m.GetQuota(ctx, xfs.UserQuota, 1001, "/mnt/xfs")
+	assert.True(t, errors.Is(err, xfs.ErrNoDquot))
+
+	// 恢复失败的ID单独报告
+	m.FailNth("SetQuota", syscall.EPERM, 2)
+	m.FailNth("SetQuota", syscall.EIO, 3)
+	result, err = m.SetBatchQuotas(ctx, xfs.UserQuota, "/mnt/xfs", map[uint32]xfs.QuotaLimits{1000: {}, 1001: {}}, xfs.BatchOptions{Mode: xfs.BatchAllOrNothing})
+	require.True(t, errors.As(err, &batchErr))
+	assert.False(t, batchErr.RolledBack)
+	assert.Equal(t, xfs.BatchRollbackFailed, result.Items[0].Status)
+	assert.True(t, errors.Is(result.Items[0].Err, syscall.EIO))
+}
+
+func TestFakeQuotaManager_WalkAndReport(t *testing.T) {
+	m, _ := newTestManager(t)
+	ctx := context.Background()
+	require.NoError(t, m.SetQuota(ctx, xfs.UserQuota, 1001, "/mnt/xfs", xfs.QuotaLimits{BlockHard: 100 * utils.KiB}))
+	require.NoError(t, m.SetUsage(xfs.UserQuota, 1001, "/mnt/xfs", 100*utils.KiB, 1, 0))
+
+	var ids []uint32
+	require.NoError(t, m.WalkQuotas(ctx, xfs.UserQuota, "/mnt/xfs", func(quota *xfs.QuotaInfo) error {
+		ids = append(ids, quota.ID)
+		return nil
+	}))
+	assert.Equal(t, []uint32{0, 1000, 1001}, ids)
+
+	report, err := m.GenerateReport(ctx, "/mnt/xfs")
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.OverQuotas)
+	require.NotNil(t, report.State)
+	assert.True(t, report.State.User.Enforced)
+	assert.False(t, report.State.Group.Enforced)
+
+	_, err = m.GenerateReport(ctx, "/mnt/missing")
+	assert.ErrorIs(t, err, xfs.ErrNotXFS)
+
+	require.NoError(t, m.SetEnforcement(ctx, xfs.GroupQuota, "/mnt/xfs", true))
+	assert.Error(t, m.PurgeQuota(ctx, xfs.GroupQuota, "/mnt/xfs"))
+}
+
+func TestFakeQuotaManager_DiscoverFilesystems(t *testing.T) {
+	m, _ := newTestManager(t)
+	m.AddFilesystem("/home", "/dev/sdc1", "")
+
+	filesystems, err := m.DiscoverFilesystems(context.Background())
+	require.NoError(t, err)
+	require.Len(t, filesystems, 2)
+	assert.Equal(t, "/mnt/xfs", filesystems[0].MountPoint)
+	assert.Equal(t, "uquota,gqnoenforce,pquota", filesystems[0].Quota.String())
+	assert.Equal(t, "/dev/sdc1", filesystems[1].Device)
+	assert.False(t, filesystems[1].Quota.Any())
+}
+
+func TestFakeQuotaManager_GetFilesystemInfo(t *testing.T) {
+	m, _ := newTestManager(t)
+	require.NoError(t, m.CreateFile("/mnt/xfs/home/alice/a", 1000, 1000, utils.MiB))
+
+	info, err := m.GetFilesystemInfo(context.Background(), "/mnt/xfs")
+	require.NoError(t, err)
+	assert.True(t, info.IsXFS)
+	assert.Equal(t, utils.MiB, info.UsedSize())
+	require.NotNil(t, info.Geometry)
+	assert.Equal(t, DefaultFilesystemSize, info.Geometry.DataSize)
+	assert.Contains(t, info.Geometry.Features, "crc")
+
+	_, err = m.GetFilesystemInfo(context.Background(), "/mnt/missing")
+	assert.ErrorIs(t, err, xfs.ErrNotXFS)
+}
+
+func TestFakeQuotaManager_Cancel(t *testing.T) {
+	m, _ := newTestManager(t)
+	ctx := context.Background()
+	require.NoError(t, m.SetQuota(ctx, xfs.UserQuota, 1001, "/mnt/xfs", xfs.QuotaLimits{BlockHard: 100 * utils.KiB}))
+
+	ctx, cancel := context.WithCancel(ctx)
+	var ids []uint32
+	err := m.WalkQuotas(ctx, xfs.UserQuota, "/mnt/xfs", func(quota *xfs.QuotaInfo) error {
+		ids = append(ids, quota.ID)
+		cancel()
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []uint32{0}, ids)
+
+	_, err = m.GetQuota(ctx, xfs.UserQuota, 1001, "/mnt/xfs")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// 旧接口使用 context.Background()，不受影响
+	legacy := xfs.BackgroundQuotaManager(m)
+	quota, err := legacy.GetQuota(xfs.UserQuota, 1001, "/mnt/xfs")
+	require.NoError(t, err)
+	assert.Equal(t, 100*utils.KiB, quota.BlockHard)
+}
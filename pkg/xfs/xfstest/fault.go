@@ -0,0 +1,91 @@
+package xfstest
+
+import (
+	"context"
+	"syscall"
+
+	"github.com/xfs-quota-kit/pkg/xfs"
+)
+
+// fault 故障注入规则
+type fault struct {
+	op    string // 方法名，为空时匹配所有方法
+	err   error
+	count int // 剩余触发次数，0表示一直生效
+	skip  int // 触发前跳过的调用次数
+}
+
+// Fail 使指定方法（如 "SetQuota"）一直返回错误，op为空时作用于所有方法
+// err 为 syscall.Errno（如 syscall.EPERM、syscall.ESRCH、syscall.ENOSYS）时，
+// 会像真实实现一样包装为 *xfs.QuotaError
+func (m *FakeQuotaManager) Fail(op string, err error) {
+	m.FailTimes(op, err, 0)
+}
+
+// FailOnce 使指定方法的下一次调用返回错误
+func (m *FakeQuotaManager) FailOnce(op string, err error) {
+	m.FailTimes(op, err, 1)
+}
+
+// FailTimes 使指定方法接下来的count次调用返回错误，count为0表示一直生效
+func (m *FakeQuotaManager) FailTimes(op string, err error, count int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.faults = append(m.faults, &fault{op: op, err: err, count: count})
+}
+
+// FailNth 使指定方法从现在起的第n次调用返回错误，之前和之后的调用正常执行
+// 用于模拟批量操作中途失败
+func (m *FakeQuotaManager) FailNth(op string, err error, n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.faults = append(m.faults, &fault{op: op, err: err, count: 1, skip: n - 1})
+}
+
+// ClearFaults 清除所有故障注入规则
+func (m *FakeQuotaManager) ClearFaults() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.faults = nil
+}
+
+// checkFault 检查context是否已取消以及是否有匹配的故障，调用方需持有锁
+func (m *FakeQuotaManager) checkFault(ctx context.Context, op, path string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// 每次调用都要减少所有匹配规则的跳过次数，第一条无需跳过的规则生效
+	match := -1
+	for i, f := range m.faults {
+		if f.op != "" && f.op != op {
+			continue
+		}
+		if f.skip > 0 {
+			f.skip--
+			continue
+		}
+		if match < 0 {
+			match = i
+		}
+	}
+	if match < 0 {
+		return nil
+	}
+
+	f := m.faults[match]
+	if f.count > 0 {
+		f.count--
+		if f.count == 0 {
+			m.faults = append(m.faults[:match], m.faults[match+1:]...)
+		}
+	}
+	return syscallError(op, path, f.err)
+}
+
+// syscallError 将errno包装成与真实实现相同的错误类型
+func syscallError(op, path string, err error) error {
+	if errno, ok := err.(syscall.Errno); ok {
+		return &xfs.QuotaError{Op: op, Path: path, Err: xfs.NewSyscallError("quotactl", errno)}
+	}
+	return err
+}
@@ -0,0 +1,333 @@
+package xfstest
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/xfs-quota-kit/pkg/utils"
+	"github.com/xfs-quota-kit/pkg/xfs"
+)
+
+// DefaultGracePeriod XFS默认宽限时间
+const DefaultGracePeriod = 7 * 24 * time.Hour
+
+// DefaultFilesystemSize 内存文件系统的容量
+const DefaultFilesystemSize = utils.TiB
+
+// defaultGeometryFlags 与当前 mkfs.xfs 默认值一致的特性标志
+const defaultGeometryFlags = xfs.XFS_FSOP_GEOM_FLAGS_ATTR | xfs.XFS_FSOP_GEOM_FLAGS_NLINK |
+	xfs.XFS_FSOP_GEOM_FLAGS_QUOTA | xfs.XFS_FSOP_GEOM_FLAGS_IALIGN | xfs.XFS_FSOP_GEOM_FLAGS_EXTFLG |
+	xfs.XFS_FSOP_GEOM_FLAGS_DIRV2 | xfs.XFS_FSOP_GEOM_FLAGS_LOGV2 | xfs.XFS_FSOP_GEOM_FLAGS_ATTR2 |
+	xfs.XFS_FSOP_GEOM_FLAGS_PROJID32 | xfs.XFS_FSOP_GEOM_FLAGS_LAZYSB | xfs.XFS_FSOP_GEOM_FLAGS_V5SB |
+	xfs.XFS_FSOP_GEOM_FLAGS_FTYPE | xfs.XFS_FSOP_GEOM_FLAGS_FINOBT | xfs.XFS_FSOP_GEOM_FLAGS_SPINODES |
+	xfs.XFS_FSOP_GEOM_FLAGS_RMAPBT | xfs.XFS_FSOP_GEOM_FLAGS_REFLINK | xfs.XFS_FSOP_GEOM_FLAGS_BIGTIME |
+	xfs.XFS_FSOP_GEOM_FLAGS_INOBTCNT
+
+var quotaTypes = []xfs.QuotaType{xfs.UserQuota, xfs.GroupQuota, xfs.ProjectQuota}
+
+// Filesystem 内存中的XFS文件系统
+type Filesystem struct {
+	MountPoint string
+	Device     string
+
+	mountOptions []string
+	options      xfs.QuotaMountOptions
+	dquots       map[xfs.QuotaType]map[uint32]*dquot
+	grace        map[xfs.QuotaType]xfs.GraceTimes
+	files        map[string]*file
+}
+
+// dquot 单个ID的配额记录
+type dquot struct {
+	limits   xfs.QuotaLimits
+	blocks   utils.Size
+	inodes   uint64
+	rtblocks utils.Size
+	timers   xfs.GraceTimers
+}
+
+// file 文件或目录
+type file struct {
+	dir     bool
+	uid     uint32
+	gid     uint32
+	projid  uint32
+	inherit bool       // PROJINHERIT
+	size    utils.Size // 占用空间
+}
+
+// newFilesystem 创建文件系统，options为逗号分隔的挂载选项，如 "uquota,pqnoenforce"
+func newFilesystem(mountPoint, device, options string) *Filesystem {
+	mount := xfs.MountInfo{SuperOptions: strings.Split(options, ",")}
+	fs := &Filesystem{
+		MountPoint:   path.Clean(mountPoint),
+		Device:       device,
+		mountOptions: mount.SuperOptions,
+		options:      mount.QuotaOptions(),
+		dquots:       make(map[xfs.QuotaType]map[uint32]*dquot),
+		grace:        make(map[xfs.QuotaType]xfs.GraceTimes),
+		files:        make(map[string]*file),
+	}
+
+	fs.files[fs.MountPoint] = &file{dir: true}
+	for _, qt := range quotaTypes {
+		fs.dquots[qt] = map[uint32]*dquot{0: {}}
+		fs.grace[qt] = xfs.GraceTimes{Block: DefaultGracePeriod, Inode: DefaultGracePeriod, RTBlock: DefaultGracePeriod}
+	}
+	return fs
+}
+
+// contains 判断路径是否在文件系统内
+func (fs *Filesystem) contains(p string) bool {
+	return p == fs.MountPoint || fs.MountPoint == "/" || strings.HasPrefix(p, fs.MountPoint+"/")
+}
+
+// dquot 获取dquot，create为true时不存在则创建
+func (fs *Filesystem) dquot(quotaType xfs.QuotaType, id uint32, create bool) *dquot {
+	dq, ok := fs.dquots[quotaType][id]
+	if !ok && create {
+		dq = &dquot{}
+		fs.dquots[quotaType][id] = dq
+	}
+	return dq
+}
+
+// sortedIDs 获取按升序排列的dquot ID
+func (fs *Filesystem) sortedIDs(quotaType xfs.QuotaType) []uint32 {
+	ids := make([]uint32, 0, len(fs.dquots[quotaType]))
+	for id := range fs.dquots[quotaType] {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// effectiveLimits 获取生效的限制，未设置的限制使用ID 0上的默认限制
+func (fs *Filesystem) effectiveLimits(quotaType xfs.QuotaType, dq *dquot) xfs.QuotaLimits {
+	limits := dq.limits
+	defaults := fs.dquots[quotaType][0].limits
+	for _, pair := range []struct{ own, def *utils.Size }{
+		{&limits.BlockSoft, &defaults.BlockSoft},
+		{&limits.BlockHard, &defaults.BlockHard},
+		{&limits.RTBlockSoft, &defaults.RTBlockSoft},
+		{&limits.RTBlockHard, &defaults.RTBlockHard},
+	} {
+		if *pair.own == 0 {
+			*pair.own = *pair.def
+		}
+	}
+	for _, pair := range []struct{ own, def *uint64 }{
+		{&limits.InodeSoft, &defaults.InodeSoft},
+		{&limits.InodeHard, &defaults.InodeHard},
+	} {
+		if *pair.own == 0 {
+			*pair.own = *pair.def
+		}
+	}
+	return limits
+}
+
+// adjustTimers 超过软限制或硬限制时开始计时，回到软限制以下时停止计时
+// ID 0 的计时器保存默认宽限时间，不做调整
+func (fs *Filesystem) adjustTimers(quotaType xfs.QuotaType, id uint32, dq *dquot, now time.Time) {
+	if id == 0 {
+		return
+	}
+	limits := fs.effectiveLimits(quotaType, dq)
+	grace := fs.grace[quotaType]
+	adjustTimer(&dq.timers.Block, dq.blocks, limits.BlockSoft, limits.BlockHard, now.Add(grace.Block))
+	adjustTimer(&dq.timers.Inode, dq.inodes, limits.InodeSoft, limits.InodeHard, now.Add(grace.Inode))
+	adjustTimer(&dq.timers.RTBlock, dq.rtblocks, limits.RTBlockSoft, limits.RTBlockHard, now.Add(grace.RTBlock))
+}
+
+func adjustTimer[T ~uint64](timer *time.Time, used, soft, hard T, expires time.Time) {
+	over := (soft > 0 && used > soft) || (hard > 0 && used > hard)
+	switch {
+	case over && timer.IsZero():
+		*timer = expires
+	case !over:
+		*timer = time.Time{}
+	}
+}
+
+// exceeds 判断增加用量后是否违反限制：超过硬限制，或超过软限制且宽限期已过
+func exceeds[T ~uint64](used T, delta int64, soft, hard T, timer time.Time, now time.Time) bool {
+	if delta <= 0 {
+		return false
+	}
+	total := used + T(delta)
+	if hard > 0 && total > hard {
+		return true
+	}
+	return soft > 0 && total > soft && !timer.IsZero() && !now.Before(timer)
+}
+
+// owners 获取文件在三种配额类型下的ID
+func (f *file) owners() map[xfs.QuotaType]uint32 {
+	return map[xfs.QuotaType]uint32{
+		xfs.UserQuota:    f.uid,
+		xfs.GroupQuota:   f.gid,
+		xfs.ProjectQuota: f.projid,
+	}
+}
+
+// charge 调整文件所有者的用量（blocks单位为字节），enforce为true时按限制检查并在超限时返回 EDQUOT
+// 只更新已开启统计的配额类型；检查全部通过后才修改用量
+func (fs *Filesystem) charge(p string, owners map[xfs.QuotaType]uint32, blocks, inodes int64, enforce bool, now time.Time) error {
+	if enforce {
+		for _, qt := range quotaTypes {
+			id := owners[qt]
+			if !fs.options.Accounting(qt) || !fs.options.Enforced(qt) || id == 0 {
+				continue
+			}
+			dq := fs.dquot(qt, id, false)
+			if dq == nil {
+				dq = &dquot{}
+			}
+			limits := fs.effectiveLimits(qt, dq)
+			if exceeds(dq.blocks, blocks, limits.BlockSoft, limits.BlockHard, dq.timers.Block, now) ||
+				exceeds(dq.inodes, inodes, limits.InodeSoft, limits.InodeHard, dq.timers.Inode, now) {
+				return &os.PathError{Op: "write", Path: p, Err: syscall.EDQUOT}
+			}
+		}
+	}
+
+	for _, qt := range quotaTypes {
+		if !fs.options.Accounting(qt) {
+			continue
+		}
+		id := owners[qt]
+		dq := fs.dquot(qt, id, true)
+		dq.blocks = addDelta(dq.blocks, blocks)
+		dq.inodes = addDelta(dq.inodes, inodes)
+		fs.adjustTimers(qt, id, dq, now)
+	}
+	return nil
+}
+
+func addDelta[T ~uint64](value T, delta int64) T {
+	if delta < 0 && T(-delta) > value {
+		return 0
+	}
+	return T(int64(value) + delta)
+}
+
+// parent 获取父目录，调用方需确保p不是挂载点
+func (fs *Filesystem) parent(p string) (*file, error) {
+	dir, ok := fs.files[path.Dir(p)]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: path.Dir(p), Err: syscall.ENOENT}
+	}
+	if !dir.dir {
+		return nil, &os.PathError{Op: "open", Path: path.Dir(p), Err: syscall.ENOTDIR}
+	}
+	return dir, nil
+}
+
+// create 创建文件或目录，项目ID从带有PROJINHERIT的父目录继承
+func (fs *Filesystem) create(p string, dir bool, uid, gid uint32, size utils.Size, now time.Time) error {
+	if _, exists := fs.files[p]; exists {
+		return &os.PathError{Op: "create", Path: p, Err: syscall.EEXIST}
+	}
+	parent, err := fs.parent(p)
+	if err != nil {
+		return err
+	}
+
+	f := &file{dir: dir, uid: uid, gid: gid, size: size}
+	if parent.inherit {
+		f.projid = parent.projid
+		f.inherit = dir
+	}
+
+	if err := fs.charge(p, f.owners(), int64(size), 1, true, now); err != nil {
+		return err
+	}
+	fs.files[p] = f
+	return nil
+}
+
+// tree 获取root及其下所有路径，按字典序排列
+func (fs *Filesystem) tree(root string) []string {
+	var paths []string
+	for p := range fs.files {
+		if p == root || strings.HasPrefix(p, root+"/") {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// setProjectTree 为目录树设置项目ID，用量在项目dquot之间转移且不检查限制
+func (fs *Filesystem) setProjectTree(root string, projectID uint32, opts xfs.ProjectTreeOptions, now time.Time) (*xfs.ProjectTreeResult, error) {
+	f, ok := fs.files[root]
+	if !ok {
+		return nil, &os.PathError{Op: "lstat", Path: root, Err: syscall.ENOENT}
+	}
+	if !f.dir {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	result := &xfs.ProjectTreeResult{}
+	for _, p := range fs.tree(root) {
+		f := fs.files[p]
+		if f.projid != projectID {
+			owners := map[xfs.QuotaType]uint32{xfs.ProjectQuota: f.projid}
+			fs.chargeProject(p, owners, f, -1, now)
+			f.projid = projectID
+			owners[xfs.ProjectQuota] = projectID
+			fs.chargeProject(p, owners, f, 1, now)
+		}
+		f.inherit = f.dir && projectID != 0
+
+		result.Processed++
+		if opts.Progress != nil {
+			opts.Progress(p, result.Processed)
+		}
+	}
+	return result, nil
+}
+
+// chargeProject 只调整项目配额用量
+func (fs *Filesystem) chargeProject(p string, owners map[xfs.QuotaType]uint32, f *file, sign int64, now time.Time) {
+	if !fs.options.Accounting(xfs.ProjectQuota) {
+		return
+	}
+	id := owners[xfs.ProjectQuota]
+	dq := fs.dquot(xfs.ProjectQuota, id, true)
+	dq.blocks = addDelta(dq.blocks, sign*int64(f.size))
+	dq.inodes = addDelta(dq.inodes, sign)
+	fs.adjustTimers(xfs.ProjectQuota, id, dq, now)
+}
+
+// quotaInfo 将dquot转换为 QuotaInfo
+func (fs *Filesystem) quotaInfo(quotaType xfs.QuotaType, id uint32, p string, dq *dquot, now time.Time) *xfs.QuotaInfo {
+	info := &xfs.QuotaInfo{
+		ID:          id,
+		Type:        quotaType,
+		Path:        p,
+		Device:      fs.Device,
+		BlockUsed:   dq.blocks,
+		BlockSoft:   dq.limits.BlockSoft,
+		BlockHard:   dq.limits.BlockHard,
+		InodeUsed:   dq.inodes,
+		InodeSoft:   dq.limits.InodeSoft,
+		InodeHard:   dq.limits.InodeHard,
+		RTBlockUsed: dq.rtblocks,
+		RTBlockSoft: dq.limits.RTBlockSoft,
+		RTBlockHard: dq.limits.RTBlockHard,
+		LastUpdated: now,
+	}
+	if id != 0 {
+		info.BlockGraceExpires = dq.timers.Block
+		info.InodeGraceExpires = dq.timers.Inode
+		info.RTBlockGraceExpires = dq.timers.RTBlock
+	}
+	return info
+}
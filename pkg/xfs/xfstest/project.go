@@ -0,0 +1,270 @@
+package xfstest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"syscall"
+
+	"github.com/xfs-quota-kit/pkg/xfs"
+)
+
+// CreateProject 创建项目：分配项目ID、创建目录、设置项目ID并写入内存中的项目文件
+func (m *FakeQuotaManager) CreateProject(ctx context.Context, name string, p string, opts xfs.CreateProjectOptions) (*xfs.ProjectInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkFault(ctx, "CreateProject", p); err != nil {
+		return nil, err
+	}
+	p = path.Clean(p)
+	if !path.IsAbs(p) {
+		return nil, fmt.Errorf("project path %s must be absolute", p)
+	}
+	if _, exists := m.projects.Find(name); exists {
+		return nil, fmt.Errorf("project %s already exists", name)
+	}
+
+	fs, err := m.filesystem(p)
+	if err != nil {
+		return nil, err
+	}
+	projectID, err := m.projectIDs.Choose(fs.MountPoint, opts.ID, m.usedProjectIDs(fs))
+	if err != nil {
+		return nil, fmt.Errorf("project %s: %w", name, err)
+	}
+
+	project := xfs.ProjectInfo{ID: projectID, Name: name, Path: p}
+	// 先在副本上校验名称，失败时不创建目录
+	check := &xfs.ProjectFiles{Projects: &xfs.ProjectFile{}, Projid: &xfs.ProjectFile{}}
+	if err := check.Add(project); err != nil {
+		return nil, err
+	}
+
+	if err := m.mkdirAll(p, 0, 0); err != nil {
+		return nil, fmt.Errorf("failed to create project directory: %w", err)
+	}
+	if _, err := fs.setProjectTree(p, projectID, xfs.ProjectTreeOptions{}, m.clock.Now()); err != nil {
+		return nil, fmt.Errorf("failed to apply project ID %d to %s: %w", projectID, p, err)
+	}
+
+	if err := m.projects.Add(project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// RemoveProject 清除项目目录上的项目ID并删除项目文件中的条目
+func (m *FakeQuotaManager) RemoveProject(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkFault(ctx, "RemoveProject", ""); err != nil {
+		return err
+	}
+	project, ok := m.projects.Find(name)
+	if !ok {
+		return fmt.Errorf("project %s not found", name)
+	}
+
+	for _, p := range m.projects.Paths(project.ID) {
+		fs, err := m.filesystem(p)
+		if err != nil {
+			continue
+		}
+		if _, err := fs.setProjectTree(p, 0, xfs.ProjectTreeOptions{}, m.clock.Now()); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to clear project ID from %s: %w", p, err)
+		}
+	}
+
+	if _, err := m.projects.Remove(name); err != nil {
+		return err
+	}
+	return m.projectIDs.Release(project.ID)
+}
+
+// usedProjectIDs 收集项目文件中的ID和文件系统上仍有用量或限制的dquot，调用方需持有锁
+func (m *FakeQuotaManager) usedProjectIDs(fs *Filesystem) map[uint32]string {
+	used := map[uint32]string{0: "is reserved for the default limits"}
+	for _, project := range m.projects.List() {
+		used[project.ID] = fmt.Sprintf("is used by project %q in %s", project.Name, m.projects.Projid.Path)
+	}
+	for id, dq := range fs.dquots[xfs.ProjectQuota] {
+		if _, exists := used[id]; !exists && (dq.limits != xfs.QuotaLimits{} || dq.blocks > 0 || dq.inodes > 0 || dq.rtblocks > 0) {
+			used[id] = fmt.Sprintf("still has quota usage or limits on %s", fs.MountPoint)
+		}
+	}
+	return used
+}
+
+// GetProjects 获取所有项目
+func (m *FakeQuotaManager) GetProjects(ctx context.Context) ([]xfs.ProjectInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkFault(ctx, "GetProjects", ""); err != nil {
+		return nil, err
+	}
+	return m.projects.List(), nil
+}
+
+// SetupProject 为项目的所有目录设置项目ID
+func (m *FakeQuotaManager) SetupProject(ctx context.Context, name string, opts xfs.ProjectTreeOptions) (*xfs.ProjectTreeResult, error) {
+	return m.walkProject(ctx, "SetupProject", name, func(fs *Filesystem, p string, id uint32) (*xfs.ProjectTreeResult, error) {
+		return fs.setProjectTree(p, id, opts, m.clock.Now())
+	})
+}
+
+// ClearProject 清除项目所有目录上的项目ID
+func (m *FakeQuotaManager) ClearProject(ctx context.Context, name string, opts xfs.ProjectTreeOptions) (*xfs.ProjectTreeResult, error) {
+	return m.walkProject(ctx, "ClearProject", name, func(fs *Filesystem, p string, id uint32) (*xfs.ProjectTreeResult, error) {
+		return fs.setProjectTree(p, 0, opts, m.clock.Now())
+	})
+}
+
+// walkProject 对项目的每个目录执行操作并汇总结果
+func (m *FakeQuotaManager) walkProject(ctx context.Context, method, name string, fn func(fs *Filesystem, p string, id uint32) (*xfs.ProjectTreeResult, error)) (*xfs.ProjectTreeResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkFault(ctx, method, ""); err != nil {
+		return nil, err
+	}
+	project, ok := m.projects.Find(name)
+	if !ok {
+		return nil, fmt.Errorf("project %s not found", name)
+	}
+	paths := m.projects.Paths(project.ID)
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("project %s has no directories in %s", name, m.projects.Projects.Path)
+	}
+
+	total := &xfs.ProjectTreeResult{}
+	for _, p := range paths {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		fs, err := m.filesystem(p)
+		if err != nil {
+			return total, fmt.Errorf("project %s: %w", name, err)
+		}
+		result, err := fn(fs, p, project.ID)
+		if result != nil {
+			total.Processed += result.Processed
+			total.Skipped += result.Skipped
+		}
+		if err != nil {
+			return total, fmt.Errorf("project %s: %w", name, err)
+		}
+	}
+	return total, nil
+}
+
+// VerifyProject 按内存中的文件统计项目目录的用量并与dquot比较，规则与 xfs 包一致
+func (m *FakeQuotaManager) VerifyProject(ctx context.Context, name string, opts xfs.VerifyOptions) (*xfs.VerifyResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkFault(ctx, "VerifyProject", ""); err != nil {
+		return nil, err
+	}
+	project, ok := m.projects.Find(name)
+	if !ok {
+		return nil, fmt.Errorf("project %s not found", name)
+	}
+	paths := m.projects.Paths(project.ID)
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("project %s has no directories in %s", name, m.projects.Projects.Path)
+	}
+	maxFiles := opts.MaxFiles
+	if maxFiles == 0 {
+		maxFiles = xfs.DefaultVerifyMaxFiles
+	}
+
+	result := &xfs.VerifyResult{Project: project.Name, ProjectID: project.ID, Paths: paths}
+	for _, fs := range m.filesystems {
+		scanned := map[xfs.QuotaType]map[uint32]*xfs.Usage{
+			xfs.ProjectQuota: {project.ID: {}},
+			xfs.UserQuota:    {},
+			xfs.GroupQuota:   {},
+		}
+		complete, found := false, false
+		for _, root := range paths {
+			if other, err := m.filesystem(root); err != nil || other != fs {
+				continue
+			}
+			if _, ok := fs.files[root]; !ok {
+				return nil, fmt.Errorf("project %s: %w", name, &os.PathError{Op: "lstat", Path: root, Err: syscall.ENOENT})
+			}
+			found = true
+			complete = complete || root == fs.MountPoint
+
+			for _, p := range fs.tree(root) {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				f := fs.files[p]
+				for qt, id := range f.owners() {
+					usage, ok := scanned[qt][id]
+					if !ok {
+						usage = &xfs.Usage{}
+						scanned[qt][id] = usage
+					}
+					usage.Blocks += f.size
+					usage.Inodes++
+				}
+
+				if f.projid != project.ID || (f.dir && !f.inherit) {
+					if maxFiles > 0 && len(result.MismatchedFiles) >= maxFiles {
+						result.Truncated = true
+					} else {
+						result.MismatchedFiles = append(result.MismatchedFiles, xfs.MismatchedFile{
+							Path: p, ProjectID: f.projid, MissingInherit: f.dir && !f.inherit,
+						})
+					}
+				}
+
+				result.Scanned++
+				if opts.Progress != nil {
+					opts.Progress(p, result.Scanned)
+				}
+			}
+		}
+		if !found {
+			continue
+		}
+
+		for _, qt := range []xfs.QuotaType{xfs.ProjectQuota, xfs.UserQuota, xfs.GroupQuota} {
+			if !fs.options.Accounting(qt) {
+				if qt == xfs.ProjectQuota {
+					return nil, syscallError("verify", fs.MountPoint, syscall.ESRCH)
+				}
+				continue
+			}
+			for _, id := range sortedIDs(scanned[qt]) {
+				reported := xfs.Usage{}
+				if dq := fs.dquot(qt, id, false); dq != nil {
+					reported = xfs.Usage{Blocks: dq.blocks, Inodes: dq.inodes, RTBlocks: dq.rtblocks}
+				}
+				exact := complete || (qt == xfs.ProjectQuota && id == project.ID)
+				check := xfs.NewAccountingCheck(qt, id, *scanned[qt][id], reported, exact)
+				check.Device = fs.Device
+				check.Name = m.lookupName(qt, id)
+				result.Checks = append(result.Checks, check)
+			}
+		}
+	}
+	return result, nil
+}
+
+// sortedIDs 获取按升序排列的ID
+func sortedIDs(usage map[uint32]*xfs.Usage) []uint32 {
+	ids := make([]uint32, 0, len(usage))
+	for id := range usage {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
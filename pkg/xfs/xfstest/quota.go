@@ -0,0 +1,507 @@
+package xfstest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"syscall"
+	"time"
+
+	"github.com/xfs-quota-kit/pkg/utils"
+	"github.com/xfs-quota-kit/pkg/xfs"
+)
+
+// GetQuota 获取配额信息，ID没有dquot时返回 ENOENT
+func (m *FakeQuotaManager) GetQuota(ctx context.Context, quotaType xfs.QuotaType, id uint32, p string) (*xfs.QuotaInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fs, err := m.quotaFilesystem(ctx, "GetQuota", "get", quotaType, p)
+	if err != nil {
+		return nil, err
+	}
+
+	dq := fs.dquot(quotaType, id, false)
+	if dq == nil {
+		return nil, syscallError("get", p, syscall.ENOENT)
+	}
+	return m.quotaInfo(fs, quotaType, id, p, dq), nil
+}
+
+// SetQuota 设置配额限制，ID 0 的限制作为默认限制
+func (m *FakeQuotaManager) SetQuota(ctx context.Context, quotaType xfs.QuotaType, id uint32, p string, limits xfs.QuotaLimits) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.setQuota(ctx, "SetQuota", quotaType, id, p, limits)
+}
+
+func (m *FakeQuotaManager) setQuota(ctx context.Context, method string, quotaType xfs.QuotaType, id uint32, p string, limits xfs.QuotaLimits) error {
+	fs, err := m.quotaFilesystem(ctx, method, "set", quotaType, p)
+	if err != nil {
+		return err
+	}
+
+	dq := fs.dquot(quotaType, id, true)
+	dq.limits = limits.Merge(dq.limits)
+	fs.adjustTimers(quotaType, id, dq, m.clock.Now())
+	return nil
+}
+
+// RemoveQuota 删除配额限制
+func (m *FakeQuotaManager) RemoveQuota(ctx context.Context, quotaType xfs.QuotaType, id uint32, p string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.setQuota(ctx, "RemoveQuota", quotaType, id, p, xfs.QuotaLimits{})
+}
+
+// GetGraceTimes 获取默认宽限时间
+func (m *FakeQuotaManager) GetGraceTimes(ctx context.Context, quotaType xfs.QuotaType, p string) (*xfs.GraceTimes, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fs, err := m.quotaFilesystem(ctx, "GetGraceTimes", "grace", 0, p)
+	if err != nil {
+		return nil, err
+	}
+	grace, ok := fs.grace[quotaType]
+	if !ok {
+		return nil, &xfs.QuotaError{Op: "grace", Path: p, Err: fmt.Errorf("invalid quota type %d", quotaType)}
+	}
+	return &grace, nil
+}
+
+// SetGraceTimes 设置默认宽限时间，零值表示不修改
+func (m *FakeQuotaManager) SetGraceTimes(ctx context.Context, quotaType xfs.QuotaType, p string, grace xfs.GraceTimes) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fs, err := m.quotaFilesystem(ctx, "SetGraceTimes", "grace", quotaType, p)
+	if err != nil {
+		return err
+	}
+	if grace.Block <= 0 && grace.Inode <= 0 && grace.RTBlock <= 0 {
+		return &xfs.QuotaError{Op: "grace", Path: p, Err: fmt.Errorf("no grace time specified")}
+	}
+
+	current := fs.grace[quotaType]
+	for _, pair := range []struct{ dst, src *time.Duration }{
+		{&current.Block, &grace.Block},
+		{&current.Inode, &grace.Inode},
+		{&current.RTBlock, &grace.RTBlock},
+	} {
+		if *pair.src > 0 {
+			*pair.dst = (*pair.src / time.Second) * time.Second
+		}
+	}
+	fs.grace[quotaType] = current
+	return nil
+}
+
+// SetGraceTimers 设置单个ID的宽限到期时间，零值表示不修改
+func (m *FakeQuotaManager) SetGraceTimers(ctx context.Context, quotaType xfs.QuotaType, id uint32, p string, timers xfs.GraceTimers) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.setGraceTimers(ctx, "SetGraceTimers", quotaType, id, p, timers)
+}
+
+func (m *FakeQuotaManager) setGraceTimers(ctx context.Context, method string, quotaType xfs.QuotaType, id uint32, p string, timers xfs.GraceTimers) error {
+	if id == 0 {
+		return &xfs.QuotaError{Op: "grace", Path: p, Err: fmt.Errorf("ID 0 holds the default grace times, use SetGraceTimes")}
+	}
+	fs, err := m.quotaFilesystem(ctx, method, "grace", quotaType, p)
+	if err != nil {
+		return err
+	}
+	if timers.Block.IsZero() && timers.Inode.IsZero() && timers.RTBlock.IsZero() {
+		return &xfs.QuotaError{Op: "grace", Path: p, Err: fmt.Errorf("no grace timer specified")}
+	}
+
+	dq := fs.dquot(quotaType, id, true)
+	for _, pair := range []struct{ dst, src *time.Time }{
+		{&dq.timers.Block, &timers.Block},
+		{&dq.timers.Inode, &timers.Inode},
+		{&dq.timers.RTBlock, &timers.RTBlock},
+	} {
+		if !pair.src.IsZero() {
+			*pair.dst = time.Unix(pair.src.Unix(), 0)
+		}
+	}
+	return nil
+}
+
+// ResetGrace 将超过软限制的宽限期重新设置为从现在开始的默认宽限时间
+func (m *FakeQuotaManager) ResetGrace(ctx context.Context, quotaType xfs.QuotaType, id uint32, p string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fs, err := m.quotaFilesystem(ctx, "ResetGrace", "grace", quotaType, p)
+	if err != nil {
+		return err
+	}
+	dq := fs.dquot(quotaType, id, false)
+	if dq == nil {
+		return syscallError("get", p, syscall.ENOENT)
+	}
+
+	now := m.clock.Now()
+	grace := fs.grace[quotaType]
+	var timers xfs.GraceTimers
+	if dq.limits.BlockSoft > 0 && dq.blocks > dq.limits.BlockSoft {
+		timers.Block = now.Add(grace.Block)
+	}
+	if dq.limits.InodeSoft > 0 && dq.inodes > dq.limits.InodeSoft {
+		timers.Inode = now.Add(grace.Inode)
+	}
+	if dq.limits.RTBlockSoft > 0 && dq.rtblocks > dq.limits.RTBlockSoft {
+		timers.RTBlock = now.Add(grace.RTBlock)
+	}
+	if timers.Block.IsZero() && timers.Inode.IsZero() && timers.RTBlock.IsZero() {
+		return &xfs.QuotaError{Op: "grace", Path: p, Err: fmt.Errorf("%s ID %d is not over any soft limit", quotaType, id)}
+	}
+
+	return m.setGraceTimers(ctx, "ResetGrace", quotaType, id, p, timers)
+}
+
+// GetAllQuotas 获取所有配额
+func (m *FakeQuotaManager) GetAllQuotas(ctx context.Context, quotaType xfs.QuotaType, p string) ([]xfs.QuotaInfo, error) {
+	var quotas []xfs.QuotaInfo
+	err := m.WalkQuotas(ctx, quotaType, p, func(quota *xfs.QuotaInfo) error {
+		quotas = append(quotas, *quota)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return quotas, nil
+}
+
+// SetBatchQuotas 按ID升序批量设置配额，设置和回滚与真实实现一样使用 xfs.ExecuteBatch
+// 每个ID的设置和回滚都会检查 "SetQuota" 的故障注入规则，可以用 FailNth 模拟中途失败
+func (m *FakeQuotaManager) SetBatchQuotas(ctx context.Context, quotaType xfs.QuotaType, p string, quotas map[uint32]xfs.QuotaLimits, opts xfs.BatchOptions) (*xfs.BatchResult, error) {
+	m.mu.Lock()
+	fs, err := m.quotaFilesystem(ctx, "SetBatchQuotas", "batch", 0, p)
+	if err != nil {
+		m.mu.Unlock()
+		return nil, err
+	}
+
+	result := xfs.NewBatchResult(quotaType, p, quotas, opts)
+	for i := range result.Items {
+		item := &result.Items[i]
+		item.Name = m.lookupName(quotaType, item.ID)
+		previous := xfs.QuotaLimits{}
+		if dq := fs.dquot(quotaType, item.ID, false); dq != nil {
+			previous = dq.limits
+		}
+		item.Previous = &previous
+	}
+	m.mu.Unlock()
+
+	return result, xfs.ExecuteBatch(ctx, result, opts, func(ctx context.Context, id uint32, limits xfs.QuotaLimits) error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return m.setQuota(ctx, "SetQuota", quotaType, id, p, limits)
+	})
+}
+
+// WalkQuotas 按ID升序遍历所有配额
+// 遍历前复制一份快照，回调中可以再次调用管理器的方法
+func (m *FakeQuotaManager) WalkQuotas(ctx context.Context, quotaType xfs.QuotaType, p string, fn xfs.QuotaWalkFunc) error {
+	m.mu.Lock()
+	fs, err := m.quotaFilesystem(ctx, "WalkQuotas", "list", quotaType, p)
+	if err != nil {
+		m.mu.Unlock()
+		return err
+	}
+	var quotas []*xfs.QuotaInfo
+	for _, id := range fs.sortedIDs(quotaType) {
+		quotas = append(quotas, m.quotaInfo(fs, quotaType, id, p, fs.dquots[quotaType][id]))
+	}
+	m.mu.Unlock()
+
+	for _, quota := range quotas {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(quota); err != nil {
+			if err == xfs.StopWalk {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsureDefaultLimits 为没有显式限制的用户和组设置默认限制
+// 内存文件系统没有 passwd/group，只处理已有dquot（拥有文件或设置过用量）的ID
+func (m *FakeQuotaManager) EnsureDefaultLimits(ctx context.Context, p string, opts xfs.EnsureOptions) (*xfs.EnsureResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := &xfs.EnsureResult{Path: p, DryRun: opts.DryRun}
+	for _, quotaType := range []xfs.QuotaType{xfs.UserQuota, xfs.GroupQuota} {
+		limits := opts.Limits[quotaType]
+		if limits == (xfs.QuotaLimits{}) {
+			continue
+		}
+
+		fs, err := m.quotaFilesystem(ctx, "EnsureDefaultLimits", "ensure", quotaType, p)
+		if err != nil {
+			return result, err
+		}
+		for _, id := range fs.sortedIDs(quotaType) {
+			if !opts.Includes(id) {
+				continue
+			}
+			if fs.dquots[quotaType][id].limits != (xfs.QuotaLimits{}) {
+				result.Existing++
+				continue
+			}
+			if !opts.DryRun {
+				if err := m.setQuota(ctx, "EnsureDefaultLimits", quotaType, id, p, limits); err != nil {
+					return result, err
+				}
+			}
+			result.Applied = append(result.Applied, xfs.EnsureAction{
+				Type:   quotaType,
+				ID:     id,
+				Name:   m.lookupName(quotaType, id),
+				Limits: limits,
+			})
+		}
+	}
+	return result, nil
+}
+
+// GenerateReport 生成配额报告
+func (m *FakeQuotaManager) GenerateReport(ctx context.Context, p string) (*xfs.QuotaReport, error) {
+	allQuotas := []xfs.QuotaInfo{}
+	report, err := m.StreamReport(ctx, p, func(quota *xfs.QuotaInfo) error {
+		allQuotas = append(allQuotas, *quota)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Quotas = allQuotas
+	return report, nil
+}
+
+// StreamReport 流式生成配额报告
+func (m *FakeQuotaManager) StreamReport(ctx context.Context, p string, fn xfs.QuotaWalkFunc) (*xfs.QuotaReport, error) {
+	m.mu.Lock()
+	_, err := m.quotaFilesystem(ctx, "StreamReport", "report", 0, p)
+	now := m.clock.Now()
+	m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &xfs.QuotaReport{
+		Filesystem:  p,
+		GeneratedAt: now,
+	}
+	if state, err := m.GetQuotaState(ctx, p); err == nil {
+		report.State = state
+	}
+
+	for _, qType := range quotaTypes {
+		var fnErr error
+		err := m.WalkQuotas(ctx, qType, p, func(quota *xfs.QuotaInfo) error {
+			report.Add(quota)
+			if fn != nil {
+				fnErr = fn(quota)
+			}
+			return fnErr
+		})
+		if fnErr == xfs.StopWalk {
+			break
+		}
+		if fnErr != nil {
+			return nil, fnErr
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		if err != nil {
+			if errors.Is(err, xfs.ErrQuotaNotEnabled) {
+				continue
+			}
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+// CheckQuotaStatus 检查配额状态
+func (m *FakeQuotaManager) CheckQuotaStatus(ctx context.Context, p string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkFault(ctx, "CheckQuotaStatus", p); err != nil {
+		return err
+	}
+	fs, err := m.filesystem(path.Clean(p))
+	if err != nil {
+		return fmt.Errorf("path %s: %w", p, xfs.ErrNotXFS)
+	}
+	if !fs.options.Any() {
+		return fmt.Errorf("%w on %s (mount with uquota, gquota or pquota)", xfs.ErrQuotaNotEnabled, fs.MountPoint)
+	}
+	return nil
+}
+
+// GetQuotaState 获取配额子系统状态
+func (m *FakeQuotaManager) GetQuotaState(ctx context.Context, p string) (*xfs.QuotaState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fs, err := m.quotaFilesystem(ctx, "GetQuotaState", "state", 0, p)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &xfs.QuotaState{
+		Path:       p,
+		Device:     fs.Device,
+		MountPoint: fs.MountPoint,
+	}
+	for _, qt := range quotaTypes {
+		typeState := state.Type(qt)
+		typeState.Accounting = fs.options.Accounting(qt)
+		typeState.Enforced = fs.options.Enforced(qt)
+		typeState.BlockGrace = fs.grace[qt].Block
+		typeState.InodeGrace = fs.grace[qt].Inode
+		typeState.RTBlockGrace = fs.grace[qt].RTBlock
+		if typeState.Accounting {
+			state.IncoreDquots += uint32(len(fs.dquots[qt]))
+		}
+	}
+	return state, nil
+}
+
+// SetEnforcement 开启或关闭配额限制，要求已开启该类型的配额统计
+func (m *FakeQuotaManager) SetEnforcement(ctx context.Context, quotaType xfs.QuotaType, p string, enforce bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fs, err := m.quotaFilesystem(ctx, "SetEnforcement", "enforce", 0, p)
+	if err != nil {
+		return err
+	}
+	if !fs.options.Accounting(quotaType) {
+		return &xfs.QuotaError{Op: "enforce", Path: p, Err: fmt.Errorf("%s %w on %s", quotaType, xfs.ErrQuotaNotEnabled, fs.MountPoint)}
+	}
+
+	switch quotaType {
+	case xfs.UserQuota:
+		fs.options.UserEnforced = enforce
+	case xfs.GroupQuota:
+		fs.options.GroupEnforced = enforce
+	case xfs.ProjectQuota:
+		fs.options.ProjectEnforced = enforce
+	}
+	return nil
+}
+
+// PurgeQuota 删除指定类型的所有dquot，要求该类型的配额统计已关闭
+func (m *FakeQuotaManager) PurgeQuota(ctx context.Context, quotaType xfs.QuotaType, p string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fs, err := m.quotaFilesystem(ctx, "PurgeQuota", "purge", 0, p)
+	if err != nil {
+		return err
+	}
+	if fs.options.Accounting(quotaType) {
+		return &xfs.QuotaError{Op: "purge", Path: p, Err: fmt.Errorf("%s quota accounting is still on for %s", quotaType, fs.MountPoint)}
+	}
+
+	fs.dquots[quotaType] = map[uint32]*dquot{0: {}}
+	return nil
+}
+
+// IsXFSFilesystem 检查路径是否在内存文件系统上
+func (m *FakeQuotaManager) IsXFSFilesystem(ctx context.Context, p string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkFault(ctx, "IsXFSFilesystem", p); err != nil {
+		return false, err
+	}
+	_, err := m.filesystem(path.Clean(p))
+	return err == nil, nil
+}
+
+// GetFilesystemInfo 获取文件系统信息，容量为 DefaultFilesystemSize，几何信息与 mkfs.xfs 的默认值相近
+func (m *FakeQuotaManager) GetFilesystemInfo(ctx context.Context, p string) (*xfs.FilesystemInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkFault(ctx, "GetFilesystemInfo", p); err != nil {
+		return nil, err
+	}
+	fs, err := m.filesystem(path.Clean(p))
+	if err != nil {
+		return nil, err
+	}
+
+	var used utils.Size
+	for _, f := range fs.files {
+		used += f.size
+	}
+	const blockSize = 4096
+	free := DefaultFilesystemSize - used
+	return &xfs.FilesystemInfo{
+		Path:        p,
+		Type:        xfs.XFS_SUPER_MAGIC,
+		IsXFS:       true,
+		BlockSize:   blockSize,
+		TotalSize:   DefaultFilesystemSize,
+		FreeSize:    free,
+		AvailSize:   free,
+		TotalInodes: uint64(DefaultFilesystemSize / utils.KiB),
+		FreeInodes:  uint64(DefaultFilesystemSize/utils.KiB) - uint64(len(fs.files)),
+		Geometry: xfs.NewGeometry(&xfs.FsopGeom{
+			BlockSize:    blockSize,
+			RTExtSize:    1,
+			AGBlocks:     uint32(DefaultFilesystemSize / 4 / blockSize),
+			AGCount:      4,
+			LogBlocks:    uint32(64 * utils.MiB / blockSize),
+			SectSize:     512,
+			InodeSize:    512,
+			IMaxPct:      25,
+			DataBlocks:   uint64(DefaultFilesystemSize / blockSize),
+			LogStart:     uint64(DefaultFilesystemSize / 8 / blockSize),
+			Version:      5,
+			Flags:        defaultGeometryFlags,
+			LogSectSize:  512,
+			DirBlockSize: blockSize,
+		}),
+	}, nil
+}
+
+// DiscoverFilesystems 列出所有内存文件系统，按添加顺序排列
+func (m *FakeQuotaManager) DiscoverFilesystems(ctx context.Context) ([]xfs.DiscoveredFilesystem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkFault(ctx, "DiscoverFilesystems", ""); err != nil {
+		return nil, err
+	}
+
+	var filesystems []xfs.DiscoveredFilesystem
+	for _, fs := range m.filesystems {
+		filesystems = append(filesystems, xfs.DiscoveredFilesystem{
+			MountPoint:   fs.MountPoint,
+			Device:       fs.Device,
+			Options:      append([]string{}, fs.mountOptions...),
+			Quota:        fs.options,
+			ProjectQuota: true,
+		})
+	}
+	return filesystems, nil
+}